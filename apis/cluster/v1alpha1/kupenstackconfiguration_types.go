@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Name of the KupenstackConfiguration used by kupenstack. Configurations with
+// any other name are ignored.
+const KupenstackConfigurationName = "kupenstack"
+
+// Condition types of KupenstackConfiguration.
+const (
+	// Configuration is valid and in use.
+	ConfigurationReady = "Ready"
+)
+
+type NodeConfiguration struct {
+
+	// Name of kubernetes node.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Disabled nodes are put in maintenance and removed from OpenStack cloud.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Comma separated roles of node, e.g. `control,compute`.
+	// +kubebuilder:validation:Pattern=`^\s*(control|compute)?\s*(,\s*(control|compute)\s*)*$`
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Profile used by node instead of default profile. Nodes with different
+	// profiles are deployed as separate OpenStack clouds.
+	// +optional
+	Profile *OccpRef `json:"profile,omitempty"`
+}
+
+// NodeRule assigns roles and profile to all kubernetes nodes matching its selector.
+type NodeRule struct {
+
+	// Selects nodes by labels. An empty selector matches all nodes.
+	// +optional
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Comma separated roles of matching nodes, e.g. `control,compute`.
+	// +kubebuilder:validation:Pattern=`^\s*(control|compute)?\s*(,\s*(control|compute)\s*)*$`
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Profile used by matching nodes.
+	// +optional
+	Profile *OccpRef `json:"profile,omitempty"`
+}
+
+type KupenstackConfigurationSpec struct {
+
+	// Profile used by nodes which do not set any.
+	// +kubebuilder:validation:Required
+	DefaultProfile OccpRef `json:"defaultProfile"`
+
+	// Rules are evaluated in order, and first matching rule setting a
+	// field decides it. Entries in Nodes take precedence over rules.
+	// +optional
+	NodeRules []NodeRule `json:"nodeRules,omitempty"`
+
+	// Configuration of individual nodes.
+	// +optional
+	Nodes []NodeConfiguration `json:"nodes,omitempty"`
+}
+
+type KupenstackConfigurationStatus struct {
+
+	// Latest observations of configuration. `Ready` condition reports whether
+	// configuration is valid and all profiles it references exist.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of configuration last checked by kupenstack.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="DEFAULT-PROFILE",type="string",JSONPath=".spec.defaultProfile.name"
+//+kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName={kskcfg},scope=Cluster
+type KupenstackConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KupenstackConfigurationSpec   `json:"spec"`
+	Status KupenstackConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+type KupenstackConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KupenstackConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KupenstackConfiguration{}, &KupenstackConfigurationList{})
+}
@@ -17,11 +17,61 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ValuesFile struct{}
 
+// PodPlacement configures resources and scheduling of pods of a component.
+type PodPlacement struct {
+
+	// Resource requests and limits by pod type, as named in `pod.resources`
+	// of openstack-helm chart of component, e.g. `api` or `conductor`.
+	// +optional
+	Resources map[string]corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Tolerations of all pods of component.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity of pods of component.
+	// +optional
+	Affinity *Affinity `json:"affinity,omitempty"`
+}
+
+type Affinity struct {
+
+	// Anti-affinity between pods of same type, to spread them over nodes.
+	// +optional
+	Anti *PodAntiAffinity `json:"anti,omitempty"`
+}
+
+type PodAntiAffinity struct {
+
+	// Whether anti-affinity is preferred or required.
+	// +kubebuilder:validation:Enum=preferredDuringSchedulingIgnoredDuringExecution;requiredDuringSchedulingIgnoredDuringExecution
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Node label over whose values pods are spread, e.g. `kubernetes.io/hostname`.
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+
+	// Weight of preferred anti-affinity.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+}
+
+type SecretRef struct {
+
+	// Name of secret.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
 type KeystoneReplicas struct {
 
 	// Number of keystone-api pods.
@@ -30,15 +80,143 @@ type KeystoneReplicas struct {
 	Api int32 `json:"api,omitempty"`
 }
 
+// KeystoneDomain is a keystone domain with its own identity backend.
+type KeystoneDomain struct {
+
+	// Name of keystone domain.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// LDAP or Active Directory backend of users and groups of domain.
+	// +kubebuilder:validation:Required
+	LDAP LDAPBackend `json:"ldap"`
+}
+
+// LDAPBackend configures `[ldap]` section of a domain specific keystone
+// configuration.
+type LDAPBackend struct {
+
+	// URL of LDAP server, e.g. `ldaps://ldap.example.com`.
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Default suffix of the directory, e.g. `dc=example,dc=com`.
+	// +kubebuilder:validation:Required
+	Suffix string `json:"suffix"`
+
+	// Secret in namespace of profile with bind DN in key `user` and its
+	// password in key `password`. Anonymous bind is used when not set.
+	// +optional
+	BindCredentials *SecretRef `json:"bindCredentials,omitempty"`
+
+	// Search base for users, e.g. `ou=Users,dc=example,dc=com`.
+	// +optional
+	UserTreeDN string `json:"userTreeDN,omitempty"`
+
+	// Object class of users, e.g. `inetOrgPerson` or `person` for AD.
+	// +optional
+	UserObjectClass string `json:"userObjectClass,omitempty"`
+
+	// Attribute mapped to user id, e.g. `cn` or `sAMAccountName` for AD.
+	// +optional
+	UserIDAttribute string `json:"userIDAttribute,omitempty"`
+
+	// Attribute mapped to user name.
+	// +optional
+	UserNameAttribute string `json:"userNameAttribute,omitempty"`
+
+	// LDAP search filter for users.
+	// +optional
+	UserFilter string `json:"userFilter,omitempty"`
+
+	// Search base for groups, e.g. `ou=Groups,dc=example,dc=com`.
+	// +optional
+	GroupTreeDN string `json:"groupTreeDN,omitempty"`
+
+	// Object class of groups, e.g. `groupOfNames` or `group` for AD.
+	// +optional
+	GroupObjectClass string `json:"groupObjectClass,omitempty"`
+
+	// LDAP search filter for groups.
+	// +optional
+	GroupFilter string `json:"groupFilter,omitempty"`
+
+	// Whether to use StartTLS on `ldap://` connections.
+	// +optional
+	UseTLS bool `json:"useTLS,omitempty"`
+
+	// Other options of `[ldap]` section, by name.
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// KeystoneFederation configures keystone as service provider of SAML or
+// OpenID Connect identity providers.
+type KeystoneFederation struct {
+
+	// Dashboards trusted to receive tokens after federated login, e.g.
+	// `https://horizon.example.com/auth/websso/`.
+	// +optional
+	TrustedDashboards []string `json:"trustedDashboards,omitempty"`
+
+	// Identity providers users log in with.
+	// +optional
+	IdentityProviders []IdentityProvider `json:"identityProviders,omitempty"`
+}
+
+type IdentityProvider struct {
+
+	// Id of identity provider in keystone.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Federation protocol of identity provider.
+	// +kubebuilder:validation:Enum=saml2;openid
+	// +kubebuilder:validation:Required
+	Protocol string `json:"protocol"`
+
+	// Ids by which identity provider identifies itself, e.g. entity id of
+	// SAML provider or issuer of OpenID Connect provider.
+	// +kubebuilder:validation:MinItems=1
+	RemoteIDs []string `json:"remoteIDs"`
+
+	// Request attribute holding id of identity provider. Defaults to
+	// `Shib-Identity-Provider` for saml2 and `HTTP_OIDC_ISS` for openid.
+	// +optional
+	RemoteIDAttribute string `json:"remoteIDAttribute,omitempty"`
+
+	// Keystone mapping of federated users to local users, groups and
+	// projects, i.e. an object with `rules`.
+	// Reference: https://docs.openstack.org/keystone/latest/admin/federation/mapping_combinations.html
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Mapping ValuesFile `json:"mapping"`
+}
+
 type KeystoneConfiguration struct {
 
 	// Configures number of replicas for each pods.
 	// +optional
 	Replicas KeystoneReplicas `json:"replicas"`
 
+	// Keystone domains backed by LDAP or Active Directory.
+	// +optional
+	Domains []KeystoneDomain `json:"domains,omitempty"`
+
+	// Federated login through SAML or OpenID Connect identity providers.
+	// +optional
+	Federation *KeystoneFederation `json:"federation,omitempty"`
+
 	// Reference: Values.conf in openstack-helm keystone chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm keystone chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type HorizonReplicas struct {
@@ -62,6 +240,14 @@ type HorizonConfiguration struct {
 	// Reference: Values.conf in openstack-helm horizon chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm horizon chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type GlanceReplicas struct {
@@ -90,6 +276,14 @@ type GlanceConfiguration struct {
 	// Reference: Values.conf in openstack-helm glance chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm glance chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type NovaReplicas struct {
@@ -133,6 +327,14 @@ type NovaConfiguration struct {
 	// Reference: Values.conf in openstack-helm nova chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm nova chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type NeutronReplicas struct {
@@ -161,6 +363,14 @@ type NeutronConfiguration struct {
 	// Reference: Values.conf in openstack-helm neutron chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm neutron chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type PlacementReplicas struct {
@@ -184,13 +394,159 @@ type PlacementConfiguration struct {
 	// Reference: Values.conf in openstack-helm placement chart.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm placement chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
+}
+
+type IngressReplicas struct {
+
+	// Number of ingress controller pods.
+	// +optional
+	Ingress int32 `json:"ingress,omitempty"`
+
+	// Number of ingress-error-pages pods.
+	// +optional
+	ErrorPage int32 `json:"errorPage,omitempty"`
+}
+
+type IngressConfiguration struct {
+
+	// Configures number of replicas for each pods.
+	// +optional
+	Replicas IngressReplicas `json:"replicas,omitempty"`
+
+	// Reference: Values.conf in openstack-helm ingress chart.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm ingress chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
+}
+
+type MariadbReplicas struct {
+
+	// Number of mariadb-server pods.
+	// +optional
+	Server int32 `json:"server,omitempty"`
+
+	// Number of mariadb-ingress pods.
+	// +optional
+	Ingress int32 `json:"ingress,omitempty"`
+
+	// Number of mariadb-ingress-error-pages pods.
+	// +optional
+	ErrorPage int32 `json:"errorPage,omitempty"`
+}
+
+type MariadbConfiguration struct {
+
+	// Configures number of replicas for each pods.
+	// +optional
+	Replicas MariadbReplicas `json:"replicas,omitempty"`
+
+	// Reference: Values.conf in openstack-helm mariadb chart.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm mariadb chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
+}
+
+type RabbitmqReplicas struct {
+
+	// Number of rabbitmq server pods.
+	// +optional
+	Server int32 `json:"server,omitempty"`
+}
+
+type RabbitmqConfiguration struct {
+
+	// Configures number of replicas for each pods.
+	// +optional
+	Replicas RabbitmqReplicas `json:"replicas,omitempty"`
+
+	// Reference: Values.conf in openstack-helm rabbitmq chart.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm rabbitmq chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
+}
+
+type MemcachedReplicas struct {
+
+	// Number of memcached pods.
+	// +optional
+	Server int32 `json:"server,omitempty"`
+}
+
+type MemcachedConfiguration struct {
+
+	// Configures number of replicas for each pods.
+	// +optional
+	Replicas MemcachedReplicas `json:"replicas,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm memcached chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
+}
+
+type LibvirtConfiguration struct {
+
+	// Reference: Values.conf in openstack-helm libvirt chart. Libvirt runs
+	// on every compute node, so it has no replicas.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Conf ValuesFile `json:"conf,omitempty"`
+
+	// Resources, tolerations and affinity of pods.
+	PodPlacement `json:",inline"`
+
+	// Values of openstack-helm libvirt chart, merged over values generated
+	// by kupenstack. Takes precedence over all fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Values ValuesFile `json:"values,omitempty"`
 }
 
 type OpenStackCloudConfigurationProfileSpec struct {
 
-	// The parent profile to inherit and override in this definition.
+	// The parent profile to inherit and override in this definition. Either
+	// `<name>` or `<name>.<namespace>` of an OCCP, or a remote profile:
+	// `https://host/profile.yaml`, `git+https://host/repo.git//profile.yaml?ref=v1`
+	// or `oci://registry/repository:tag`. Remote profiles may be pinned by
+	// appending `#sha256=<hex digest of document>`.
 	From string `json:"from,omitempty"`
 
+	// Secret in namespace of this profile with credentials for remote `from`,
+	// in keys `username` and `password`, or `token`. Ignored in remote
+	// profile documents.
+	// +optional
+	FromCredentials *SecretRef `json:"fromCredentials,omitempty"`
+
 	// Keystone related confs
 	Keystone KeystoneConfiguration `json:"keystone,omitempty"`
 
@@ -208,15 +564,69 @@ type OpenStackCloudConfigurationProfileSpec struct {
 
 	// // Placement related confs
 	Placement PlacementConfiguration `json:"placement,omitempty"`
+
+	// Ingress of cloud
+	Ingress IngressConfiguration `json:"ingress,omitempty"`
+
+	// Mariadb database of cloud
+	Mariadb MariadbConfiguration `json:"mariadb,omitempty"`
+
+	// Rabbitmq message queue of cloud
+	Rabbitmq RabbitmqConfiguration `json:"rabbitmq,omitempty"`
+
+	// Memcached of cloud
+	Memcached MemcachedConfiguration `json:"memcached,omitempty"`
+
+	// Libvirt on compute nodes of cloud
+	Libvirt LibvirtConfiguration `json:"libvirt,omitempty"`
+}
+
+// Condition types of OpenStackCloudConfigurationProfile.
+const (
+	// Inheritance chain of profile is resolved.
+	ProfileResolved = "Resolved"
+)
+
+type OpenStackCloudConfigurationProfileStatus struct {
+
+	// Inheritance chain of profile, starting with the profile itself and
+	// followed by its parents through `from`.
+	// +optional
+	Chain []string `json:"chain,omitempty"`
+
+	// SHA-256 digest of effective configuration, i.e. this profile merged
+	// over all its parents. It changes whenever configuration deployed for
+	// nodes using this profile changes.
+	// +optional
+	EffectiveConfigurationDigest string `json:"effectiveConfigurationDigest,omitempty"`
+
+	// Names of osknodes using this profile.
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Latest observations of profile. `Resolved` condition reports whether
+	// inheritance chain could be resolved, e.g. it is false for a missing
+	// parent or a cycle.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of profile last resolved.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="RESOLVED",type="string",JSONPath=".status.conditions[?(@.type==\"Resolved\")].status"
+//+kubebuilder:printcolumn:name="NODES",type="string",JSONPath=".status.nodes",priority=1
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 //+kubebuilder:resource:shortName={occp},scope=Namespaced
 type OpenStackCloudConfigurationProfile struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec OpenStackCloudConfigurationProfileSpec `json:"spec,omitempty"`
+	Spec   OpenStackCloudConfigurationProfileSpec   `json:"spec,omitempty"`
+	Status OpenStackCloudConfigurationProfileStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
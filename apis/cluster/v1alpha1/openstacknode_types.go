@@ -20,6 +20,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Condition types of OpenstackNode.
+const (
+	// Node passed preflight checks for its roles and can be labelled for
+	// OpenStack components.
+	NodePreflightPassed = "PreflightPassed"
+)
+
 type OccpRef struct {
 
 	// +kubebuilder:validation:Required
@@ -34,6 +41,74 @@ type OpenstackNodeSpec struct {
 	// OpenStack Cloud Configuration Profile(OCCP) used by this node.
 	// +kubebuilder:validation:Required
 	Occp OccpRef `json:"openstackCloudConfigurationProfileRef"`
+
+	// Set to true to put this node in maintenance. nova-compute on this node
+	// is disabled, its instances are moved to other compute nodes, and
+	// OpenStack labels are removed from the node.
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+type MaintenanceStatus struct {
+
+	// One of Disabling, Migrating or Completed.
+	Phase string `json:"phase,omitempty"`
+
+	// Number of instances still running on this node.
+	RemainingInstances int32 `json:"remainingInstances,omitempty"`
+
+	// Details of current phase.
+	Message string `json:"message,omitempty"`
+}
+
+type ResourceCapacity struct {
+
+	// Total capacity of hypervisor.
+	Total int64 `json:"total"`
+
+	// Capacity used by instances.
+	Used int64 `json:"used"`
+
+	// Capacity left for new instances. May be negative when resource is
+	// overcommitted.
+	Free int64 `json:"free"`
+}
+
+type HypervisorStatus struct {
+
+	// Hostname of hypervisor in nova.
+	Hostname string `json:"hostname,omitempty"`
+
+	// Hypervisor type, e.g. QEMU.
+	Type string `json:"type,omitempty"`
+
+	// Whether nova-compute on node is up or down.
+	State string `json:"state,omitempty"`
+
+	// Whether nova-compute on node is enabled or disabled.
+	Status string `json:"status,omitempty"`
+
+	// Virtual CPUs of hypervisor.
+	VCPUs ResourceCapacity `json:"vcpus"`
+
+	// Memory of hypervisor in MiB.
+	MemoryMB ResourceCapacity `json:"memoryMB"`
+
+	// Local disk of hypervisor in GiB.
+	DiskGB ResourceCapacity `json:"diskGB"`
+
+	// Number of instances running on hypervisor.
+	RunningInstances int32 `json:"runningInstances"`
+}
+
+type PreflightStatus struct {
+
+	// Comma separated roles of node the checks were run for.
+	Roles string `json:"roles,omitempty"`
+
+	// Checks node failed. Empty when all checks passed.
+	// +optional
+	Failures []string `json:"failures,omitempty"`
 }
 
 type OpenstackNodeStatus struct {
@@ -47,6 +122,25 @@ type OpenstackNodeStatus struct {
 
 	// Status of OpenStack cluster components for this osknode.
 	Status string `json:"status,omitempty"`
+
+	// Progress of maintenance of this node. Not set when node is not in maintenance.
+	// +optional
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+
+	// Capacity and usage of nova hypervisor on this node. Not set when node
+	// is not a compute node.
+	// +optional
+	Hypervisor *HypervisorStatus `json:"hypervisor,omitempty"`
+
+	// Result of last preflight checks of node. Node is labelled for
+	// OpenStack components only after passing them.
+	// +optional
+	Preflight *PreflightStatus `json:"preflight,omitempty"`
+
+	// Latest observations of osknode. `PreflightPassed` condition reports
+	// whether node is ready to run its OpenStack roles.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // // +kubebuilder:printcolumn:name="STATUS",type="string",JSONPath=".status.status"
@@ -54,6 +148,13 @@ type OpenstackNodeStatus struct {
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="ROLES",type="string",JSONPath=".metadata.annotations.node-role"
 //+kubebuilder:printcolumn:name="PROFILE",type="string",JSONPath=".spec.openstackCloudConfigurationProfileRef.name"
+//+kubebuilder:printcolumn:name="PREFLIGHT",type="string",JSONPath=".status.conditions[?(@.type==\"PreflightPassed\")].status"
+//+kubebuilder:printcolumn:name="MAINTENANCE",type="string",JSONPath=".status.maintenance.phase"
+//+kubebuilder:printcolumn:name="HYPERVISOR",type="string",JSONPath=".status.hypervisor.state"
+//+kubebuilder:printcolumn:name="INSTANCES",type="integer",JSONPath=".status.hypervisor.runningInstances"
+//+kubebuilder:printcolumn:name="FREE-VCPUS",type="integer",JSONPath=".status.hypervisor.vcpus.free"
+//+kubebuilder:printcolumn:name="FREE-MEMORY-MB",type="integer",JSONPath=".status.hypervisor.memoryMB.free"
+//+kubebuilder:printcolumn:name="FREE-DISK-GB",type="integer",JSONPath=".status.hypervisor.diskGB.free",priority=1
 //+kubebuilder:resource:shortName={osknode,osknodes},scope=Cluster
 type OpenstackNode struct {
 	metav1.TypeMeta   `json:",inline"`
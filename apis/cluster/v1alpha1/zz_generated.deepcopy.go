@@ -22,14 +22,38 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Affinity) DeepCopyInto(out *Affinity) {
+	*out = *in
+	if in.Anti != nil {
+		in, out := &in.Anti, &out.Anti
+		*out = new(PodAntiAffinity)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Affinity.
+func (in *Affinity) DeepCopy() *Affinity {
+	if in == nil {
+		return nil
+	}
+	out := new(Affinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlanceConfiguration) DeepCopyInto(out *GlanceConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlanceConfiguration.
@@ -62,6 +86,8 @@ func (in *HorizonConfiguration) DeepCopyInto(out *HorizonConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizonConfiguration.
@@ -89,11 +115,98 @@ func (in *HorizonReplicas) DeepCopy() *HorizonReplicas {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HypervisorStatus) DeepCopyInto(out *HypervisorStatus) {
+	*out = *in
+	out.VCPUs = in.VCPUs
+	out.MemoryMB = in.MemoryMB
+	out.DiskGB = in.DiskGB
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HypervisorStatus.
+func (in *HypervisorStatus) DeepCopy() *HypervisorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HypervisorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityProvider) DeepCopyInto(out *IdentityProvider) {
+	*out = *in
+	if in.RemoteIDs != nil {
+		in, out := &in.RemoteIDs, &out.RemoteIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Mapping = in.Mapping
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityProvider.
+func (in *IdentityProvider) DeepCopy() *IdentityProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressConfiguration) DeepCopyInto(out *IngressConfiguration) {
+	*out = *in
+	out.Replicas = in.Replicas
+	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressConfiguration.
+func (in *IngressConfiguration) DeepCopy() *IngressConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressReplicas) DeepCopyInto(out *IngressReplicas) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressReplicas.
+func (in *IngressReplicas) DeepCopy() *IngressReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeystoneConfiguration) DeepCopyInto(out *KeystoneConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]KeystoneDomain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(KeystoneFederation)
+		(*in).DeepCopyInto(*out)
+	}
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneConfiguration.
@@ -106,6 +219,49 @@ func (in *KeystoneConfiguration) DeepCopy() *KeystoneConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneDomain) DeepCopyInto(out *KeystoneDomain) {
+	*out = *in
+	in.LDAP.DeepCopyInto(&out.LDAP)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneDomain.
+func (in *KeystoneDomain) DeepCopy() *KeystoneDomain {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneDomain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneFederation) DeepCopyInto(out *KeystoneFederation) {
+	*out = *in
+	if in.TrustedDashboards != nil {
+		in, out := &in.TrustedDashboards, &out.TrustedDashboards
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdentityProviders != nil {
+		in, out := &in.IdentityProviders, &out.IdentityProviders
+		*out = make([]IdentityProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneFederation.
+func (in *KeystoneFederation) DeepCopy() *KeystoneFederation {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneFederation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeystoneReplicas) DeepCopyInto(out *KeystoneReplicas) {
 	*out = *in
@@ -121,11 +277,251 @@ func (in *KeystoneReplicas) DeepCopy() *KeystoneReplicas {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KupenstackConfiguration) DeepCopyInto(out *KupenstackConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KupenstackConfiguration.
+func (in *KupenstackConfiguration) DeepCopy() *KupenstackConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(KupenstackConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KupenstackConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KupenstackConfigurationList) DeepCopyInto(out *KupenstackConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KupenstackConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KupenstackConfigurationList.
+func (in *KupenstackConfigurationList) DeepCopy() *KupenstackConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(KupenstackConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KupenstackConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KupenstackConfigurationSpec) DeepCopyInto(out *KupenstackConfigurationSpec) {
+	*out = *in
+	out.DefaultProfile = in.DefaultProfile
+	if in.NodeRules != nil {
+		in, out := &in.NodeRules, &out.NodeRules
+		*out = make([]NodeRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KupenstackConfigurationSpec.
+func (in *KupenstackConfigurationSpec) DeepCopy() *KupenstackConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KupenstackConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KupenstackConfigurationStatus) DeepCopyInto(out *KupenstackConfigurationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KupenstackConfigurationStatus.
+func (in *KupenstackConfigurationStatus) DeepCopy() *KupenstackConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KupenstackConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPBackend) DeepCopyInto(out *LDAPBackend) {
+	*out = *in
+	if in.BindCredentials != nil {
+		in, out := &in.BindCredentials, &out.BindCredentials
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPBackend.
+func (in *LDAPBackend) DeepCopy() *LDAPBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LibvirtConfiguration) DeepCopyInto(out *LibvirtConfiguration) {
+	*out = *in
+	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LibvirtConfiguration.
+func (in *LibvirtConfiguration) DeepCopy() *LibvirtConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(LibvirtConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceStatus) DeepCopyInto(out *MaintenanceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceStatus.
+func (in *MaintenanceStatus) DeepCopy() *MaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariadbConfiguration) DeepCopyInto(out *MariadbConfiguration) {
+	*out = *in
+	out.Replicas = in.Replicas
+	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariadbConfiguration.
+func (in *MariadbConfiguration) DeepCopy() *MariadbConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MariadbConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariadbReplicas) DeepCopyInto(out *MariadbReplicas) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariadbReplicas.
+func (in *MariadbReplicas) DeepCopy() *MariadbReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(MariadbReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedConfiguration) DeepCopyInto(out *MemcachedConfiguration) {
+	*out = *in
+	out.Replicas = in.Replicas
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedConfiguration.
+func (in *MemcachedConfiguration) DeepCopy() *MemcachedConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedReplicas) DeepCopyInto(out *MemcachedReplicas) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedReplicas.
+func (in *MemcachedReplicas) DeepCopy() *MemcachedReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NeutronConfiguration) DeepCopyInto(out *NeutronConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeutronConfiguration.
@@ -153,11 +549,54 @@ func (in *NeutronReplicas) DeepCopy() *NeutronReplicas {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfiguration) DeepCopyInto(out *NodeConfiguration) {
+	*out = *in
+	if in.Profile != nil {
+		in, out := &in.Profile, &out.Profile
+		*out = new(OccpRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfiguration.
+func (in *NodeConfiguration) DeepCopy() *NodeConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeRule) DeepCopyInto(out *NodeRule) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.Profile != nil {
+		in, out := &in.Profile, &out.Profile
+		*out = new(OccpRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeRule.
+func (in *NodeRule) DeepCopy() *NodeRule {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NovaConfiguration) DeepCopyInto(out *NovaConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NovaConfiguration.
@@ -205,7 +644,8 @@ func (in *OpenStackCloudConfigurationProfile) DeepCopyInto(out *OpenStackCloudCo
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCloudConfigurationProfile.
@@ -261,12 +701,22 @@ func (in *OpenStackCloudConfigurationProfileList) DeepCopyObject() runtime.Objec
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenStackCloudConfigurationProfileSpec) DeepCopyInto(out *OpenStackCloudConfigurationProfileSpec) {
 	*out = *in
-	out.Keystone = in.Keystone
-	out.Horizon = in.Horizon
-	out.Glance = in.Glance
-	out.Nova = in.Nova
-	out.Neutron = in.Neutron
-	out.Placement = in.Placement
+	if in.FromCredentials != nil {
+		in, out := &in.FromCredentials, &out.FromCredentials
+		*out = new(SecretRef)
+		**out = **in
+	}
+	in.Keystone.DeepCopyInto(&out.Keystone)
+	in.Horizon.DeepCopyInto(&out.Horizon)
+	in.Glance.DeepCopyInto(&out.Glance)
+	in.Nova.DeepCopyInto(&out.Nova)
+	in.Neutron.DeepCopyInto(&out.Neutron)
+	in.Placement.DeepCopyInto(&out.Placement)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	in.Mariadb.DeepCopyInto(&out.Mariadb)
+	in.Rabbitmq.DeepCopyInto(&out.Rabbitmq)
+	in.Memcached.DeepCopyInto(&out.Memcached)
+	in.Libvirt.DeepCopyInto(&out.Libvirt)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCloudConfigurationProfileSpec.
@@ -279,13 +729,45 @@ func (in *OpenStackCloudConfigurationProfileSpec) DeepCopy() *OpenStackCloudConf
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenStackCloudConfigurationProfileStatus) DeepCopyInto(out *OpenStackCloudConfigurationProfileStatus) {
+	*out = *in
+	if in.Chain != nil {
+		in, out := &in.Chain, &out.Chain
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCloudConfigurationProfileStatus.
+func (in *OpenStackCloudConfigurationProfileStatus) DeepCopy() *OpenStackCloudConfigurationProfileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenStackCloudConfigurationProfileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenstackNode) DeepCopyInto(out *OpenstackNode) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenstackNode.
@@ -358,6 +840,28 @@ func (in *OpenstackNodeSpec) DeepCopy() *OpenstackNodeSpec {
 func (in *OpenstackNodeStatus) DeepCopyInto(out *OpenstackNodeStatus) {
 	*out = *in
 	out.DesiredNodeConfiguration = in.DesiredNodeConfiguration
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceStatus)
+		**out = **in
+	}
+	if in.Hypervisor != nil {
+		in, out := &in.Hypervisor, &out.Hypervisor
+		*out = new(HypervisorStatus)
+		**out = **in
+	}
+	if in.Preflight != nil {
+		in, out := &in.Preflight, &out.Preflight
+		*out = new(PreflightStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenstackNodeStatus.
@@ -375,6 +879,8 @@ func (in *PlacementConfiguration) DeepCopyInto(out *PlacementConfiguration) {
 	*out = *in
 	out.Replicas = in.Replicas
 	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementConfiguration.
@@ -402,6 +908,139 @@ func (in *PlacementReplicas) DeepCopy() *PlacementReplicas {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodAntiAffinity) DeepCopyInto(out *PodAntiAffinity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodAntiAffinity.
+func (in *PodAntiAffinity) DeepCopy() *PodAntiAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(PodAntiAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodPlacement) DeepCopyInto(out *PodPlacement) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodPlacement.
+func (in *PodPlacement) DeepCopy() *PodPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(PodPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightStatus) DeepCopyInto(out *PreflightStatus) {
+	*out = *in
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightStatus.
+func (in *PreflightStatus) DeepCopy() *PreflightStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RabbitmqConfiguration) DeepCopyInto(out *RabbitmqConfiguration) {
+	*out = *in
+	out.Replicas = in.Replicas
+	out.Conf = in.Conf
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	out.Values = in.Values
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RabbitmqConfiguration.
+func (in *RabbitmqConfiguration) DeepCopy() *RabbitmqConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(RabbitmqConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RabbitmqReplicas) DeepCopyInto(out *RabbitmqReplicas) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RabbitmqReplicas.
+func (in *RabbitmqReplicas) DeepCopy() *RabbitmqReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(RabbitmqReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceCapacity) DeepCopyInto(out *ResourceCapacity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceCapacity.
+func (in *ResourceCapacity) DeepCopy() *ResourceCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRef) DeepCopyInto(out *SecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRef.
+func (in *SecretRef) DeepCopy() *SecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValuesFile) DeepCopyInto(out *ValuesFile) {
 	*out = *in
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of ProjectMember.
+const (
+	// Keystone user or group has all roles of spec on project of namespace.
+	ProjectMemberReady = "Ready"
+)
+
+type ProjectMemberSpec struct {
+
+	// Name of keystone user in Default domain. User is created when it
+	// does not exist, and its password stored in secret referenced in
+	// status. Exactly one of user or group must be set.
+	// +optional
+	// +immutable
+	User string `json:"user,omitempty"`
+
+	// Name of keystone group in Default domain. Group is created when it
+	// does not exist. Exactly one of user or group must be set.
+	// +optional
+	// +immutable
+	Group string `json:"group,omitempty"`
+
+	// Roles assigned on project of namespace, e.g. member or reader.
+	// Defaults to member.
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+}
+
+type ProjectMemberStatus struct {
+
+	// Unique Id of user or group at openstack.
+	ID string `json:"id,omitempty"`
+
+	// ID of project roles are assigned on.
+	ProjectID string `json:"projectID,omitempty"`
+
+	// Set to true when user or group was created by kupenstack.
+	Created bool `json:"created,omitempty"`
+
+	// Name of secret in same namespace holding `username` and `password` of
+	// user created by kupenstack.
+	PasswordSecret string `json:"passwordSecret,omitempty"`
+
+	// Roles currently assigned on project.
+	Roles []string `json:"roles,omitempty"`
+
+	// Latest observations of member. `Ready` condition reports whether all
+	// roles of spec are assigned.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="USER",type="string",JSONPath=".spec.user"
+//+kubebuilder:printcolumn:name="GROUP",type="string",JSONPath=".spec.group"
+//+kubebuilder:printcolumn:name="ROLES",type="string",JSONPath=".status.roles"
+//+kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:printcolumn:name="PASSWORD",type="string",JSONPath=".status.passwordSecret",priority=1
+type ProjectMember struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProjectMemberSpec   `json:"spec,omitempty"`
+	Status ProjectMemberStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+type ProjectMemberList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectMember `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectMember{}, &ProjectMemberList{})
+}
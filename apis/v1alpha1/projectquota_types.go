@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of ProjectQuota.
+const (
+	// Quotas of spec are applied on project of namespace.
+	ProjectQuotaReady = "Ready"
+)
+
+type ComputeQuota struct {
+
+	// Maximum number of instances.
+	// +optional
+	Instances *int32 `json:"instances,omitempty"`
+
+	// Maximum number of virtual CPUs of all instances.
+	// +optional
+	Cores *int32 `json:"cores,omitempty"`
+
+	// Maximum memory of all instances in MiB.
+	// +optional
+	RAMMB *int32 `json:"ramMB,omitempty"`
+}
+
+type NetworkQuota struct {
+
+	// +optional
+	Networks *int32 `json:"networks,omitempty"`
+
+	// +optional
+	Subnets *int32 `json:"subnets,omitempty"`
+
+	// +optional
+	Ports *int32 `json:"ports,omitempty"`
+
+	// +optional
+	Routers *int32 `json:"routers,omitempty"`
+
+	// +optional
+	FloatingIPs *int32 `json:"floatingIPs,omitempty"`
+
+	// +optional
+	SecurityGroups *int32 `json:"securityGroups,omitempty"`
+}
+
+type StorageQuota struct {
+
+	// +optional
+	Volumes *int32 `json:"volumes,omitempty"`
+
+	// +optional
+	Snapshots *int32 `json:"snapshots,omitempty"`
+
+	// Maximum size of all volumes and snapshots in GiB.
+	// +optional
+	Gigabytes *int32 `json:"gigabytes,omitempty"`
+}
+
+// Quotas never set are left as they are, and quotas removed from spec are
+// reset to defaults of cloud. Value -1 means unlimited.
+type ProjectQuotaSpec struct {
+
+	// Quotas of nova.
+	// +optional
+	Compute *ComputeQuota `json:"compute,omitempty"`
+
+	// Quotas of neutron.
+	// +optional
+	Network *NetworkQuota `json:"network,omitempty"`
+
+	// Quotas of cinder. Applied only when cloud has block storage service.
+	// +optional
+	Storage *StorageQuota `json:"storage,omitempty"`
+}
+
+type QuotaUsage struct {
+
+	// Quota of project, -1 when unlimited.
+	Limit int32 `json:"limit"`
+
+	// Amount used by project.
+	InUse int32 `json:"inUse"`
+}
+
+type ProjectQuotaStatus struct {
+
+	// ID of project quotas are applied on.
+	ProjectID string `json:"projectID,omitempty"`
+
+	// Generation of spec last applied.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Quotas last applied, so that quotas later removed from spec are reset.
+	// +optional
+	Applied *ProjectQuotaSpec `json:"applied,omitempty"`
+
+	// Limits and usage of nova resources, keyed by resource name.
+	// +optional
+	Compute map[string]QuotaUsage `json:"compute,omitempty"`
+
+	// Limits and usage of neutron resources, keyed by resource name.
+	// +optional
+	Network map[string]QuotaUsage `json:"network,omitempty"`
+
+	// Limits and usage of cinder resources, keyed by resource name.
+	// +optional
+	Storage map[string]QuotaUsage `json:"storage,omitempty"`
+
+	// Latest observations of quota. `Ready` condition reports whether
+	// quotas of spec are applied.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="INSTANCES",type="integer",JSONPath=".status.compute.instances.inUse"
+//+kubebuilder:printcolumn:name="MAX-INSTANCES",type="integer",JSONPath=".status.compute.instances.limit"
+//+kubebuilder:printcolumn:name="CORES",type="integer",JSONPath=".status.compute.cores.inUse"
+//+kubebuilder:printcolumn:name="RAM-MB",type="integer",JSONPath=".status.compute.ramMB.inUse"
+//+kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+type ProjectQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProjectQuotaSpec   `json:"spec,omitempty"`
+	Status ProjectQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+type ProjectQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectQuota{}, &ProjectQuotaList{})
+}
@@ -20,6 +20,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ConsoleRequestAnnotation requests a new console of virtual machine when
+// set to a value not yet in status.console.request.
+const ConsoleRequestAnnotation = "kupenstack.io/console-request"
+
 type VirtualMachineSpec struct {
 	Image string `json:"image,omitempty"`
 
@@ -30,6 +34,25 @@ type VirtualMachineSpec struct {
 
 	// +optional
 	Networks []string `json:"network,omitempty"`
+
+	// Whether virtual machine should be running. Setting it to false stops
+	// virtual machine and true starts it again. When not set, power state
+	// is left as it is.
+	// +optional
+	Running *bool `json:"running,omitempty"`
+}
+
+type ConsoleStatus struct {
+
+	// Value of ConsoleRequestAnnotation this console was created for.
+	Request string `json:"request"`
+
+	// Type of console, e.g. novnc.
+	Type string `json:"type,omitempty"`
+
+	// Name of secret in same namespace holding url of console in key
+	// `url`. Url is valid for limited time as set in nova.
+	SecretName string `json:"secret,omitempty"`
 }
 
 type VirtualMachineStatus struct {
@@ -41,6 +64,10 @@ type VirtualMachineStatus struct {
 
 	// hostname
 	Node string `json:"node,omitempty"`
+
+	// Console last requested through ConsoleRequestAnnotation.
+	// +optional
+	Console *ConsoleStatus `json:"console,omitempty"`
 }
 
 //+kubebuilder:object:root=true
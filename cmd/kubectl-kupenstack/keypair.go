@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
+)
+
+func newKeyPairCommand(o *options) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:     "keypair",
+		Aliases: []string{"kp"},
+		Short:   "Work with KeyPairs",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "private-key NAME",
+		Short: "Print private key of a KeyPair generated by kupenstack",
+		Long: "Print private key of a KeyPair, read from the Secret in its status. " +
+			"Only KeyPairs created without a public key have a private key.",
+		Example: "  kubectl kupenstack keypair private-key my-key > my-key.pem && chmod 600 my-key.pem",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			namespace, err := o.namespace()
+			if err != nil {
+				return err
+			}
+
+			var keypair kupenstackiov1alpha1.KeyPair
+			err = c.Get(cmd.Context(), types.NamespacedName{Name: args[0], Namespace: namespace}, &keypair)
+			if err != nil {
+				return err
+			}
+			if keypair.Status.PrivateKey.SecretName == "" {
+				return fmt.Errorf("KeyPair %s has no private key secret", keypair.Name)
+			}
+
+			var secret corev1.Secret
+			err = c.Get(cmd.Context(), types.NamespacedName{Name: keypair.Status.PrivateKey.SecretName, Namespace: namespace}, &secret)
+			if err != nil {
+				return err
+			}
+
+			// Private key is stored base64 encoded in secret data.
+			privateKey, err := base64.StdEncoding.DecodeString(string(secret.Data["privateKey"]))
+			if err != nil {
+				return fmt.Errorf("invalid private key in secret %s: %w", secret.Name, err)
+			}
+
+			_, err = cmd.OutOrStdout().Write(privateKey)
+			return err
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command kubectl-kupenstack is a kubectl plugin for day-to-day operations
+// on kupenstack resources. Install it anywhere in PATH and run it as
+// `kubectl kupenstack`.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kupenstackiov1alpha1.AddToScheme(scheme))
+	utilruntime.Must(clusterv1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// options are common to all commands.
+type options struct {
+	configFlags *genericclioptions.ConfigFlags
+}
+
+// client returns client to cluster selected by kubeconfig flags.
+func (o *options) client() (client.Client, error) {
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{Scheme: scheme})
+}
+
+// namespace returns namespace selected by flags or kubeconfig context.
+func (o *options) namespace() (string, error) {
+	namespace, _, err := o.configFlags.ToRawKubeConfigLoader().Namespace()
+	return namespace, err
+}
+
+func newRootCommand() *cobra.Command {
+
+	o := &options{
+		configFlags: genericclioptions.NewConfigFlags(true),
+	}
+
+	cmd := &cobra.Command{
+		Use:          "kubectl-kupenstack",
+		Short:        "Inspect and operate kupenstack clouds and resources",
+		SilenceUsage: true,
+	}
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(
+		newStatusCommand(o),
+		newOccpCommand(o),
+		newKeyPairCommand(o),
+		newVMCommand(o),
+	)
+
+	return cmd
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+// profileOptions select profiles from cluster, or from files when
+// filenames are given.
+type profileOptions struct {
+	*options
+	filenames []string
+}
+
+func (o *profileOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&o.filenames, "filename", "f", nil,
+		"Read profiles from these files instead of cluster. Profiles inherited through from "+
+			"are also looked up in these files, except remote and builtin profiles.")
+}
+
+// profiles returns reader of profiles, and profiles selected by `args`.
+// When reading from files without args, all profiles in files are selected.
+func (o *profileOptions) profiles(args []string) (client.Reader, []types.NamespacedName, error) {
+
+	var reader client.Reader
+	var namespace string
+	var profiles []types.NamespacedName
+
+	if len(o.filenames) > 0 {
+		namespace = "default"
+		if o.configFlags.Namespace != nil && *o.configFlags.Namespace != "" {
+			namespace = *o.configFlags.Namespace
+		}
+
+		files, err := occp.ReadFiles(namespace, o.filenames...)
+		if err != nil {
+			return nil, nil, err
+		}
+		reader = files
+		if len(args) == 0 {
+			profiles = files.Profiles()
+		}
+	} else {
+		if len(args) == 0 {
+			return nil, nil, fmt.Errorf("profile name or --filename is required")
+		}
+
+		c, err := o.client()
+		if err != nil {
+			return nil, nil, err
+		}
+		reader = c
+		namespace, err = o.namespace()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, name := range args {
+		profiles = append(profiles, types.NamespacedName{Name: name, Namespace: namespace})
+	}
+	return reader, profiles, nil
+}
+
+func newOccpCommand(o *options) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:     "occp",
+		Aliases: []string{"openstackcloudconfigurationprofile"},
+		Short:   "Work with OpenStackCloudConfigurationProfiles",
+	}
+
+	cmd.AddCommand(newOccpRenderCommand(o), newOccpLintCommand(o))
+	return cmd
+}
+
+func newOccpRenderCommand(o *options) *cobra.Command {
+
+	po := &profileOptions{options: o}
+	var chartValues bool
+
+	cmd := &cobra.Command{
+		Use:   "render [NAME...]",
+		Short: "Print configuration of profiles resolved with all their parents",
+		Long: "Print configuration of profiles resolved with all their parents, as used by " +
+			"osknodes. With --chart-values, configuration of each component is printed as " +
+			"values of its openstack-helm chart, using mapping of newest chart version.",
+		Example: "  # Helm values of every component of profiles in a pull request, without a cluster\n" +
+			"  kubectl kupenstack occp render --chart-values -f base.yaml -f production.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			reader, profiles, err := po.profiles(args)
+			if err != nil {
+				return err
+			}
+
+			resolver := occp.Resolver{Client: reader}
+			for i, profile := range profiles {
+				data, chain, err := resolver.Resolve(cmd.Context(), profile.Name, profile.Namespace)
+				if err != nil {
+					return fmt.Errorf("resolving %v: %w", chain, err)
+				}
+
+				if chartValues {
+					data, err = occp.ChartValues(data, newestChart)
+					if err != nil {
+						return fmt.Errorf("profile %s: %w", chain[0], err)
+					}
+				}
+
+				err = printProfile(cmd.OutOrStdout(), i, chain[0], data)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	po.addFlags(cmd)
+	cmd.Flags().BoolVar(&chartValues, "chart-values", false,
+		"Print configuration of components as values of openstack-helm charts.")
+
+	return cmd
+}
+
+func newOccpLintCommand(o *options) *cobra.Command {
+
+	po := &profileOptions{options: o}
+
+	cmd := &cobra.Command{
+		Use:   "lint [NAME...]",
+		Short: "Check profiles for errors",
+		Long: "Resolve profiles and check their configuration. Keys of components are checked " +
+			"against values of openstack-helm charts in local helm repository cache, and are " +
+			"skipped with a warning when charts are not available. Exits with non-zero status " +
+			"when any profile has problems.",
+		Example: "  kubectl kupenstack occp lint -f base.yaml -f production.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			reader, profiles, err := po.profiles(args)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, profile := range profiles {
+				ok := lintProfile(cmd.Context(), cmd.OutOrStdout(), reader, profile)
+				if !ok {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d profiles have problems", failed, len(profiles))
+			}
+			return nil
+		},
+	}
+	po.addFlags(cmd)
+
+	return cmd
+}
+
+// lintProfile prints problems and warnings of profile, and returns whether
+// profile has no problems.
+func lintProfile(ctx context.Context, out io.Writer, reader client.Reader, profile types.NamespacedName) bool {
+
+	resolver := occp.Resolver{Client: reader}
+	data, chain, err := resolver.Resolve(ctx, profile.Name, profile.Namespace)
+	id := chain[0]
+	if err != nil {
+		fmt.Fprintf(out, "%s: %s\n", id, err)
+		return false
+	}
+
+	problems, warnings := occp.Lint(ctx, data, true)
+	if _, err := occp.ChartValues(data, newestChart); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(out, "%s: warning: %s\n", id, warning)
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(out, "%s: %s\n", id, problem)
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(out, "%s: ok\n", id)
+	}
+	return len(problems) == 0
+}
+
+// printProfile prints data of profile `id` as `i`th YAML document.
+func printProfile(out io.Writer, i int, id string, data map[string]interface{}) error {
+
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if i > 0 {
+		fmt.Fprintln(out, "---")
+	}
+	fmt.Fprintf(out, "# %s\n", id)
+	_, err = out.Write(content)
+	return err
+}
+
+// newestChart selects mapping of newest chart version, as deployed chart
+// versions are not known outside cluster.
+func newestChart(string) string {
+	return ""
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+// Helm release of components whose release is not named after component.
+var releaseNames = map[string]string{
+	"ingress": "kupenstack-ingress",
+}
+
+type cloudStatus struct {
+	cloud       kupenstack.Cloud
+	nodes       int
+	maintenance int
+}
+
+func newStatusCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show OpenStack clouds and status of their components",
+		Long: "Show every OpenStack cloud with its nodes, and status of helm release " +
+			"of each of its components.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context(), o, cmd.OutOrStdout())
+		},
+	}
+}
+
+func runStatus(ctx context.Context, o *options, out io.Writer) error {
+
+	c, err := o.client()
+	if err != nil {
+		return err
+	}
+
+	clouds, err := listClouds(ctx, c)
+	if err != nil {
+		return err
+	}
+	if len(clouds) == 0 {
+		fmt.Fprintln(out, "No OpenStack clouds found.")
+		return nil
+	}
+
+	for i, status := range clouds {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Cloud:        %s\n", status.cloud.Name)
+		fmt.Fprintf(out, "Namespace:    %s\n", status.cloud.Namespace)
+		fmt.Fprintf(out, "Default:      %t\n", status.cloud.Default)
+		fmt.Fprintf(out, "Nodes:        %d (%d in maintenance)\n", status.nodes, status.maintenance)
+		fmt.Fprintln(out)
+
+		w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tRELEASE\tREVISION\tSTATUS")
+		for _, component := range occp.Components {
+			release := status.cloud.ReleaseName(component)
+			if name, ok := releaseNames[component]; ok {
+				release = status.cloud.ReleaseName(name)
+			}
+
+			revision, state, err := releaseStatus(ctx, c, release, status.cloud.Namespace)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", component, release, revision, state)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+// listClouds returns clouds of all osknodes, and the default cloud of
+// KupenstackConfiguration, sorted by name.
+func listClouds(ctx context.Context, c client.Client) ([]*cloudStatus, error) {
+
+	var oskNodeList clusterv1alpha1.OpenstackNodeList
+	err := c.List(ctx, &oskNodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultProfile, err := readDefaultProfile(ctx, c, oskNodeList.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	clouds := make(map[string]*cloudStatus)
+	if defaultProfile.Name != "" {
+		cloud := kupenstack.NewCloud(defaultProfile, defaultProfile)
+		clouds[cloud.Name] = &cloudStatus{cloud: cloud}
+	}
+	for _, osknode := range oskNodeList.Items {
+		cloud := kupenstack.NewCloud(osknode.Spec.Occp, defaultProfile)
+		if clouds[cloud.Name] == nil {
+			clouds[cloud.Name] = &cloudStatus{cloud: cloud}
+		}
+		clouds[cloud.Name].nodes++
+		if osknode.Status.Maintenance != nil {
+			clouds[cloud.Name].maintenance++
+		}
+	}
+
+	list := make([]*cloudStatus, 0, len(clouds))
+	for _, status := range clouds {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].cloud.Name < list[j].cloud.Name })
+	return list, nil
+}
+
+// readDefaultProfile returns default profile of KupenstackConfiguration.
+// Operator may be configured through a file instead, which is not readable
+// here. Then the profile is only known when all osknodes use one profile.
+func readDefaultProfile(ctx context.Context, c client.Client, osknodes []clusterv1alpha1.OpenstackNode) (clusterv1alpha1.OccpRef, error) {
+
+	var cfg clusterv1alpha1.KupenstackConfiguration
+	err := c.Get(ctx, types.NamespacedName{Name: clusterv1alpha1.KupenstackConfigurationName}, &cfg)
+	if err == nil {
+		return cfg.Spec.DefaultProfile, nil
+	}
+	if !errors.IsNotFound(err) {
+		return clusterv1alpha1.OccpRef{}, err
+	}
+
+	var profile clusterv1alpha1.OccpRef
+	for _, osknode := range osknodes {
+		if profile.Name != "" && osknode.Spec.Occp != profile {
+			return profile, fmt.Errorf("KupenstackConfiguration %s not found, default cloud is unknown",
+				clusterv1alpha1.KupenstackConfigurationName)
+		}
+		profile = osknode.Spec.Occp
+	}
+	return profile, nil
+}
+
+// releaseStatus returns latest revision and status of helm release, as
+// recorded in labels of its storage secrets.
+func releaseStatus(ctx context.Context, c client.Client, name, namespace string) (string, string, error) {
+
+	var secrets corev1.SecretList
+	err := c.List(ctx, &secrets, client.InNamespace(namespace),
+		client.MatchingLabels{"owner": "helm", "name": name})
+	if err != nil {
+		return "", "", err
+	}
+
+	latest := 0
+	status := "not-installed"
+	for _, secret := range secrets.Items {
+		revision, err := strconv.Atoi(secret.Labels["version"])
+		if err != nil || revision < latest {
+			continue
+		}
+		latest = revision
+		status = secret.Labels["status"]
+	}
+
+	if latest == 0 {
+		return "-", status, nil
+	}
+	return strconv.Itoa(latest), status, nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
+)
+
+func newVMCommand(o *options) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:     "vm",
+		Aliases: []string{"virtualmachine"},
+		Short:   "Work with VirtualMachines",
+	}
+
+	var timeout time.Duration
+	console := &cobra.Command{
+		Use:   "console NAME",
+		Short: "Print url of a noVNC console of a VirtualMachine",
+		Long: "Request a new noVNC console of a VirtualMachine and print its url. " +
+			"The url is valid for limited time, as set in nova.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url, err := requestConsole(cmd.Context(), o, args[0], timeout)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), url)
+			return nil
+		},
+	}
+	console.Flags().DurationVar(&timeout, "timeout", time.Minute, "Time to wait for console to be created.")
+
+	ips := &cobra.Command{
+		Use:   "ips NAME",
+		Short: "Print IP addresses of a VirtualMachine on each of its networks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			vm, err := getVM(cmd.Context(), o, args[0])
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 8, 3, ' ', 0)
+			fmt.Fprintln(w, "NETWORK\tADDRESSES")
+			for _, network := range parseAddresses(vm.Status.IP) {
+				fmt.Fprintf(w, "%s\t%s\n", network[0], network[1])
+			}
+			return w.Flush()
+		},
+	}
+
+	start := &cobra.Command{
+		Use:   "start NAME",
+		Short: "Start a stopped VirtualMachine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setRunning(cmd.Context(), o, args[0], true)
+		},
+	}
+
+	stop := &cobra.Command{
+		Use:   "stop NAME",
+		Short: "Stop a running VirtualMachine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setRunning(cmd.Context(), o, args[0], false)
+		},
+	}
+
+	cmd.AddCommand(console, ips, start, stop)
+	return cmd
+}
+
+func getVM(ctx context.Context, o *options, name string) (*kupenstackiov1alpha1.VirtualMachine, error) {
+
+	c, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := o.namespace()
+	if err != nil {
+		return nil, err
+	}
+
+	var vm kupenstackiov1alpha1.VirtualMachine
+	err = c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &vm)
+	return &vm, err
+}
+
+// patchVM applies merge `patch` to VirtualMachine `name`.
+func patchVM(ctx context.Context, o *options, name string, patch map[string]interface{}) error {
+
+	c, err := o.client()
+	if err != nil {
+		return err
+	}
+	namespace, err := o.namespace()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	vm := &kupenstackiov1alpha1.VirtualMachine{}
+	vm.Name = name
+	vm.Namespace = namespace
+	return c.Patch(ctx, vm, client.RawPatch(types.MergePatchType, data))
+}
+
+// setRunning sets spec.running of VirtualMachine, which kupenstack then
+// starts or stops.
+func setRunning(ctx context.Context, o *options, name string, running bool) error {
+	return patchVM(ctx, o, name, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"running": running,
+		},
+	})
+}
+
+// requestConsole sets ConsoleRequestAnnotation on VirtualMachine, waits for
+// kupenstack to publish the console in its status and reads url of console
+// from the secret referenced there.
+func requestConsole(ctx context.Context, o *options, name string, timeout time.Duration) (string, error) {
+
+	request := time.Now().UTC().Format(time.RFC3339Nano)
+	err := patchVM(ctx, o, name, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				kupenstackiov1alpha1.ConsoleRequestAnnotation: request,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var vm *kupenstackiov1alpha1.VirtualMachine
+	err = wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		vm, err = getVM(ctx, o, name)
+		if err != nil {
+			return false, err
+		}
+		return vm.Status.Console != nil && vm.Status.Console.Request == request, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return "", fmt.Errorf("console of VirtualMachine %s not created within %s, see its events", name, timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c, err := o.client()
+	if err != nil {
+		return "", err
+	}
+	var secret corev1.Secret
+	err = c.Get(ctx, types.NamespacedName{Name: vm.Status.Console.SecretName, Namespace: vm.Namespace}, &secret)
+	if err != nil {
+		return "", err
+	}
+	return string(secret.Data["url"]), nil
+}
+
+// Matches `network(ip1,ip2)` in status.ip of VirtualMachine.
+var networkAddresses = regexp.MustCompile(`([^\s,(][^(]*)\(([^)]*)\)`)
+
+// parseAddresses splits status.ip of VirtualMachine, formatted as
+// `net1(ip1,ip2) net2(ip3)`, into network names and their addresses.
+func parseAddresses(status string) [][2]string {
+
+	var networks [][2]string
+	for _, match := range networkAddresses.FindAllStringSubmatch(status, -1) {
+		networks = append(networks, [2]string{match[1], strings.ReplaceAll(match[2], ",", ", ")})
+	}
+	return networks
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddresses(t *testing.T) {
+
+	tests := []struct {
+		status string
+		want   [][2]string
+	}{
+		{status: ""},
+		{
+			status: "private(10.0.0.5) ",
+			want:   [][2]string{{"private", "10.0.0.5"}},
+		},
+		{
+			status: "net-db(10.10.120.21,fd00::5) net-front link(10.10.90.100) ",
+			want:   [][2]string{{"net-db", "10.10.120.21, fd00::5"}, {"net-front link", "10.10.90.100"}},
+		},
+		{
+			status: "empty() ",
+			want:   [][2]string{{"empty", ""}},
+		},
+	}
+
+	for _, test := range tests {
+		got := parseAddresses(test.status)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseAddresses(%q): expected %v, got %v", test.status, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/agents"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// isLastNodeOfCloud returns whether cr is the only osknode whose spec.occp
+// refers to its profile, counting osknodes being deleted too. Once cr is
+// gone no node uses the profile, so its cloud is no longer desired and
+// nothing is deregistered from it.
+func (r *Reconciler) isLastNodeOfCloud(ctx context.Context, cr clusterv1alpha1.OpenstackNode) (bool, error) {
+
+	var oskNodeList clusterv1alpha1.OpenstackNodeList
+	err := r.List(ctx, &oskNodeList)
+	if err != nil {
+		return false, err
+	}
+
+	for _, osknode := range oskNodeList.Items {
+		if osknode.Name != cr.Name && osknode.Spec.Occp == cr.Spec.Occp {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deregister removes nova-compute service and neutron agents of osknode
+// from OpenStack. It returns whether osknode is fully deregistered. Nova
+// refuses to delete compute service hosting instances, so instances are
+// first moved away as in maintenance.
+func (r *Reconciler) deregister(ctx context.Context, cr clusterv1alpha1.OpenstackNode) (bool, error) {
+
+	last, err := r.isLastNodeOfCloud(ctx, cr)
+	if err != nil || last {
+		return last, err
+	}
+
+	maintenance, err := r.maintain(cr)
+	if err != nil {
+		return false, err
+	}
+	if maintenance.Phase != MaintenanceCompleted {
+		return false, nil
+	}
+
+	err = r.deleteComputeService(cr)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.deleteNetworkAgents(cr)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *Reconciler) deleteComputeService(cr clusterv1alpha1.OpenstackNode) error {
+
+	osclient, err := r.computeClient(cr)
+	if err != nil {
+		return err
+	}
+
+	service, err := getComputeService(osclient, cr.Name)
+	if err != nil || service == nil {
+		return err
+	}
+
+	// gophercloud has no services.Delete yet.
+	_, err = osclient.Delete(osclient.ServiceURL("os-services", service.ID), nil)
+	return openstack.IgnoreNotFound(err)
+}
+
+func (r *Reconciler) deleteNetworkAgents(cr clusterv1alpha1.OpenstackNode) error {
+
+	osclient, err := r.OS.Cloud(kupenstack.CloudName(cr.Spec.Occp)).GetClient("network")
+	if err != nil {
+		return err
+	}
+
+	allPages, err := agents.List(osclient, agents.ListOpts{Host: cr.Name}).AllPages()
+	if err != nil {
+		return err
+	}
+	allAgents, err := agents.ExtractAgents(allPages)
+	if err != nil {
+		return err
+	}
+
+	for _, agent := range allAgents {
+		err = agents.Delete(osclient, agent.ID).ExtractErr()
+		if openstack.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/cluster"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+func oskNode(name string, deleted time.Duration, annotations map[string]string) *clusterv1alpha1.OpenstackNode {
+	cr := &clusterv1alpha1.OpenstackNode{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations, Finalizers: []string{cluster.Finalizer}},
+		Spec:       clusterv1alpha1.OpenstackNodeSpec{Occp: clusterv1alpha1.OccpRef{Name: "profile", Namespace: "default"}},
+	}
+	if deleted != 0 {
+		cr.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deleted)}
+	}
+	return cr
+}
+
+// OpenStack of clouds in these tests is never reachable.
+func TestDeregister(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		osknode   *clusterv1alpha1.OpenstackNode
+		others    bool
+		released  bool
+		expectErr bool
+	}{
+		{
+			name:     "last node of cloud",
+			osknode:  oskNode("node-1", time.Minute, nil),
+			released: true,
+		},
+		{
+			name:      "openstack unreachable",
+			osknode:   oskNode("node-1", time.Minute, nil),
+			others:    true,
+			expectErr: true,
+		},
+		{
+			name:     "openstack unreachable beyond timeout",
+			osknode:  oskNode("node-1", time.Hour, nil),
+			others:   true,
+			released: true,
+		},
+		{
+			name:     "skipped by annotation",
+			osknode:  oskNode("node-1", time.Minute, map[string]string{osknode.SkipDeregisterAnnotation: "true"}),
+			others:   true,
+			released: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			clientgoscheme.AddToScheme(scheme)
+			clusterv1alpha1.AddToScheme(scheme)
+
+			objs := []client.Object{test.osknode}
+			if test.others {
+				objs = append(objs, oskNode("node-2", 0, nil))
+			}
+			c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+			r := &cluster.Reconciler{Client: c, OS: openstack.NewClouds(), Log: ctrl.Log, Scheme: scheme,
+				EventRecorder: record.NewFakeRecorder(100)}
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "node-1"}})
+			if (err != nil) != test.expectErr {
+				t.Errorf("expected error %v, got %v", test.expectErr, err)
+			}
+
+			var cr clusterv1alpha1.OpenstackNode
+			err = c.Get(ctx, types.NamespacedName{Name: "node-1"}, &cr)
+			if client.IgnoreNotFound(err) != nil {
+				t.Fatal(err)
+			}
+			released := err != nil || !utils.ContainsString(cr.Finalizers, cluster.Finalizer)
+			if released != test.released {
+				t.Errorf("expected finalizer released %v, got %v", test.released, released)
+			}
+		})
+	}
+}
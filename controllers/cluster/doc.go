@@ -14,4 +14,29 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Package cluster implements openstacknode-reconciler for kupenstack controller.
+//
+// Working: generates desired configuration of each osknode from its OCCP,
+// runs preflight checks of node for its roles, labels kubernetes nodes
+// passing them for OpenStack components and takes nodes in and
+// out of maintenance. Osknodes being deleted are deregistered from OpenStack
+// (nova-compute service and neutron agents) before their finalizer is removed.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                  MESSAGE
+//
+//  OCCPNotFound            Required OpenstackCloudConfigurationProfiles not found for osknode %s.
+//  PreflightFailed         Osknode %s failed preflight checks: %s
+//  PreflightPassed         Osknode %s passed preflight checks.
+//  MaintenanceStarted      Osknode %s is going into maintenance.
+//  MaintenanceCompleted    All instances moved out of osknode %s.
+//  MaintenanceFailed       Maintenance of osknode %s failed. error: %s
+//  MaintenanceEnded        Osknode %s is out of maintenance.
+//  Deregistering           Moving instances out of osknode %s before deregistering it.
+//  DeregisterFailed        Deregistering osknode %s from OpenStack failed. error: %s
+//  Deregistered            Osknode %s deregistered from OpenStack.
+//  DeregisterSkipped       Osknode %s deleted without deregistering it from OpenStack, as annotated.
+//  DeregisterSkipped       Osknode %s deleted without deregistering it from OpenStack, which failed for %s. error: %s
 package cluster
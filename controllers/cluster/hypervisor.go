@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"net/url"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/hypervisors"
+	"github.com/gophercloud/gophercloud/pagination"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// listHypervisors returns pager of hypervisors whose hostname contains
+// pattern. hypervisors package lists all hypervisors only, which is too
+// costly to do for each osknode.
+func listHypervisors(client *gophercloud.ServiceClient, pattern string) pagination.Pager {
+
+	query := url.Values{"hypervisor_hostname_pattern": {pattern}}
+	u := client.ServiceURL("os-hypervisors", "detail") + "?" + query.Encode()
+	return pagination.NewPager(client, u, func(r pagination.PageResult) pagination.Page {
+		return hypervisors.HypervisorPage{SinglePageBase: pagination.SinglePageBase(r)}
+	})
+}
+
+// hypervisorStatus returns capacity and usage of nova hypervisor running
+// on osknode, or nil if node is not a compute node. Hypervisor hostname
+// may be the fully qualified name of node, so hypervisors are searched by
+// name of node and then matched by host of their nova-compute service.
+func (r *Reconciler) hypervisorStatus(cr clusterv1alpha1.OpenstackNode) (*clusterv1alpha1.HypervisorStatus, error) {
+
+	client, err := r.computeClient(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	// nova finds no hypervisor matching pattern
+	allPages, err := listHypervisors(client, cr.Name).AllPages()
+	if openstack.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	allHypervisors, err := hypervisors.ExtractHypervisors(allPages)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hypervisor := range allHypervisors {
+		if hypervisor.Service.Host != cr.Name {
+			continue
+		}
+
+		return &clusterv1alpha1.HypervisorStatus{
+			Hostname: hypervisor.HypervisorHostname,
+			Type:     hypervisor.HypervisorType,
+			State:    hypervisor.State,
+			Status:   hypervisor.Status,
+			VCPUs: clusterv1alpha1.ResourceCapacity{
+				Total: int64(hypervisor.VCPUs),
+				Used:  int64(hypervisor.VCPUsUsed),
+				Free:  int64(hypervisor.VCPUs - hypervisor.VCPUsUsed),
+			},
+			MemoryMB: clusterv1alpha1.ResourceCapacity{
+				Total: int64(hypervisor.MemoryMB),
+				Used:  int64(hypervisor.MemoryMBUsed),
+				Free:  int64(hypervisor.FreeRamMB),
+			},
+			DiskGB: clusterv1alpha1.ResourceCapacity{
+				Total: int64(hypervisor.LocalGB),
+				Used:  int64(hypervisor.LocalGBUsed),
+				Free:  int64(hypervisor.FreeDiskGB),
+			},
+			RunningInstances: int32(hypervisor.RunningVMs),
+		}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func TestHypervisorStatus(t *testing.T) {
+
+	_, r, cr := newComputeReconciler(t, fake.ComputeHost)
+	compute, image := clients(t, r, cr)
+	// each uses 1 vcpu, 512MB memory and 1GB disk
+	createServers(t, compute, image, "server-1", "server-2")
+
+	tests := []struct {
+		name     string
+		expected *clusterv1alpha1.HypervisorStatus
+	}{
+		{
+			name: fake.ComputeHost,
+			expected: &clusterv1alpha1.HypervisorStatus{
+				Hostname:         fake.ComputeHost + ".fake",
+				Type:             "QEMU",
+				State:            "up",
+				Status:           "enabled",
+				VCPUs:            clusterv1alpha1.ResourceCapacity{Total: 16, Used: 2, Free: 14},
+				MemoryMB:         clusterv1alpha1.ResourceCapacity{Total: 32768, Used: 1024, Free: 31744},
+				DiskGB:           clusterv1alpha1.ResourceCapacity{Total: 500, Used: 2, Free: 498},
+				RunningInstances: 2,
+			},
+		},
+		{
+			name: fake.OtherComputeHost,
+			expected: &clusterv1alpha1.HypervisorStatus{
+				Hostname: fake.OtherComputeHost + ".fake",
+				Type:     "QEMU",
+				State:    "up",
+				Status:   "enabled",
+				VCPUs:    clusterv1alpha1.ResourceCapacity{Total: 16, Free: 16},
+				MemoryMB: clusterv1alpha1.ResourceCapacity{Total: 32768, Free: 32768},
+				DiskGB:   clusterv1alpha1.ResourceCapacity{Total: 500, Free: 500},
+			},
+		},
+		{
+			// not found by nova
+			name: "control-0",
+		},
+		{
+			// hostname of all hypervisors match, host of none
+			name: "fake-compute",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := cr
+			node.Name = test.name
+			status, err := r.hypervisorStatus(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(status, test.expected) {
+				t.Errorf("expected hypervisor %+v, got %+v", test.expected, status)
+			}
+		})
+	}
+}
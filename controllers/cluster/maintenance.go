@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/evacuate"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/extendedstatus"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/migrate"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/services"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// Phases of node maintenance.
+const (
+	MaintenanceDisabling = "Disabling"
+	MaintenanceMigrating = "Migrating"
+	MaintenanceCompleted = "Completed"
+)
+
+const (
+	// Reason set on nova-compute service disabled by kupenstack. Only
+	// services with this reason are enabled again on ending maintenance.
+	disabledReason = "Disabled by kupenstack for node maintenance."
+
+	// Compute api microversion supporting service update by id.
+	computeMicroversion = "2.53"
+)
+
+// Whether osknode is disabled in KupenstackConfiguration or set to maintenance.
+func inMaintenance(cr clusterv1alpha1.OpenstackNode) bool {
+	return cr.Spec.Maintenance || cr.Annotations[osknode.DisabledAnnotation] == "true"
+}
+
+// draining reports whether instances of osknode are being moved away.
+func draining(cr clusterv1alpha1.OpenstackNode) bool {
+	return cr.Status.Maintenance != nil && cr.Status.Maintenance.Phase != MaintenanceCompleted
+}
+
+// unreachable reports whether err means OpenStack could not be reached at
+// all, e.g. as it is not deployed yet, rather than a failed request.
+func unreachable(err error) bool {
+	return err != nil && openstack.StatusCode(err) == 0
+}
+
+func (r *Reconciler) computeClient(cr clusterv1alpha1.OpenstackNode) (*gophercloud.ServiceClient, error) {
+
+	osclient, err := r.OS.Cloud(kupenstack.CloudName(cr.Spec.Occp)).GetClient("compute")
+	if err != nil {
+		return nil, err
+	}
+
+	client := *osclient
+	client.Microversion = computeMicroversion
+	return &client, nil
+}
+
+// getComputeService returns nova-compute service running on host, or nil
+// if host is not a compute node.
+func getComputeService(client *gophercloud.ServiceClient, host string) (*services.Service, error) {
+
+	allPages, err := services.List(client, services.ListOpts{Binary: "nova-compute", Host: host}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	allServices, err := services.ExtractServices(allPages)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allServices) == 0 {
+		return nil, nil
+	}
+	return &allServices[0], nil
+}
+
+// serverWithTask is a nova server with its task state.
+type serverWithTask struct {
+	servers.Server
+	extendedstatus.ServerExtendedStatusExt
+}
+
+// maintain takes osknode one step further into maintenance and returns its
+// progress. nova-compute is first disabled so that no new instance is
+// scheduled on node, then all instances are moved away. Running instances
+// are live-migrated and stopped instances are cold-migrated, or evacuated
+// when nova-compute on node is down. Cold migrations are confirmed, so that
+// instances release their resources on node. Instances with a task in
+// progress are left alone until it is done.
+func (r *Reconciler) maintain(cr clusterv1alpha1.OpenstackNode) (*clusterv1alpha1.MaintenanceStatus, error) {
+
+	client, err := r.computeClient(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := getComputeService(client, cr.Name)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return &clusterv1alpha1.MaintenanceStatus{
+			Phase:   MaintenanceCompleted,
+			Message: "No nova-compute service on node.",
+		}, nil
+	}
+
+	if service.Status != string(services.ServiceDisabled) {
+		updateOpts := services.UpdateOpts{
+			Status:         services.ServiceDisabled,
+			DisabledReason: disabledReason,
+		}
+		_, err = services.Update(client, service.ID, updateOpts).Extract()
+		if err != nil {
+			return nil, err
+		}
+		return &clusterv1alpha1.MaintenanceStatus{
+			Phase:   MaintenanceDisabling,
+			Message: "Disabled nova-compute service.",
+		}, nil
+	}
+
+	allPages, err := servers.List(client, servers.ListOpts{Host: cr.Name, AllTenants: true}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	var allServers []serverWithTask
+	err = servers.ExtractServersInto(allPages, &allServers)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allServers) == 0 {
+		return &clusterv1alpha1.MaintenanceStatus{
+			Phase:   MaintenanceCompleted,
+			Message: "All instances moved to other nodes.",
+		}, nil
+	}
+
+	computeDown := service.State == "down"
+	blockMigration := true
+	var failures []string
+
+	for _, server := range allServers {
+
+		switch {
+		case server.TaskState != "":
+			// Instance is already being moved, or busy with other task.
+			err = nil
+		case server.Status == "VERIFY_RESIZE":
+			err = servers.ConfirmResize(client, server.ID).ExtractErr()
+		case computeDown && (server.Status == "ACTIVE" || server.Status == "SHUTOFF" || server.Status == "ERROR"):
+			_, err = evacuate.Evacuate(client, server.ID, evacuate.EvacuateOpts{}).ExtractAdminPass()
+		case server.Status == "ACTIVE":
+			err = migrate.LiveMigrate(client, server.ID, migrate.LiveMigrateOpts{BlockMigration: &blockMigration}).ExtractErr()
+		case server.Status == "SHUTOFF":
+			err = migrate.Migrate(client, server.ID).ExtractErr()
+		default:
+			// Instance is already being moved, or needs manual action.
+			err = nil
+		}
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("instance %s: %s", server.ID, err))
+		}
+	}
+
+	message := fmt.Sprintf("Moving %d instances to other nodes.", len(allServers))
+	if len(failures) != 0 {
+		message = fmt.Sprintf("Failed to move %d instances: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return &clusterv1alpha1.MaintenanceStatus{
+		Phase:              MaintenanceMigrating,
+		RemainingInstances: int32(len(allServers)),
+		Message:            message,
+	}, nil
+}
+
+// endMaintenance enables nova-compute service on node again, if it was
+// disabled by kupenstack.
+func (r *Reconciler) endMaintenance(cr clusterv1alpha1.OpenstackNode) error {
+
+	client, err := r.computeClient(cr)
+	if err != nil {
+		return err
+	}
+
+	service, err := getComputeService(client, cr.Name)
+	if err != nil || service == nil {
+		return err
+	}
+
+	if service.Status != string(services.ServiceDisabled) || service.DisabledReason != disabledReason {
+		return nil
+	}
+
+	_, err = services.Update(client, service.ID, services.UpdateOpts{Status: services.ServiceEnabled}).Extract()
+	return err
+}
+
+// withoutOpenstackLabels returns labels with all OpenStack node labels
+// unset, so that no OpenStack pods are scheduled on node.
+func withoutOpenstackLabels(labels map[string]string) map[string]string {
+	for key := range labels {
+		if key == "kupenstack-occp" {
+			continue
+		}
+		labels[key] = ""
+	}
+	return labels
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/services"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+// newComputeReconciler returns fake cloud, reconciler of osknodes using it
+// and osknode `name`. Name of osknode is its compute host.
+func newComputeReconciler(t *testing.T, name string) (*fake.Server, *Reconciler, clusterv1alpha1.OpenstackNode) {
+
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr := clusterv1alpha1.OpenstackNode{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       clusterv1alpha1.OpenstackNodeSpec{Occp: clusterv1alpha1.OccpRef{Name: "profile", Namespace: "default"}},
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set(kupenstack.CloudName(cr.Spec.Occp), admin)
+
+	return server, &Reconciler{OS: clouds}, cr
+}
+
+// createServers creates running servers with names on compute host of fake
+// cloud, and returns their IDs.
+func createServers(t *testing.T, compute, image *gophercloud.ServiceClient, names ...string) []string {
+
+	img, err := images.Create(image, images.CreateOpts{Name: "cirros"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk := 1
+	flavor, err := flavors.Create(compute, flavors.CreateOpts{Name: "small", RAM: 512, VCPUs: 1, Disk: &disk}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, name := range names {
+		created, err := servers.Create(compute, servers.CreateOpts{Name: name, ImageRef: img.ID, FlavorRef: flavor.ID}).Extract()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.ID)
+	}
+	return ids
+}
+
+// clients returns compute and image clients of cloud of osknode.
+func clients(t *testing.T, r *Reconciler, cr clusterv1alpha1.OpenstackNode) (*gophercloud.ServiceClient, *gophercloud.ServiceClient) {
+	compute, err := r.computeClient(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := r.OS.Cloud(kupenstack.CloudName(cr.Spec.Occp)).GetClient("image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compute, image
+}
+
+// serverOn returns status and host of server.
+func serverOn(t *testing.T, compute *gophercloud.ServiceClient, id string) (string, string) {
+	var s struct {
+		Status string `json:"status"`
+		Host   string `json:"OS-EXT-SRV-ATTR:host"`
+	}
+	err := servers.Get(compute, id).ExtractInto(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Status, s.Host
+}
+
+// maintainStep runs one step of maintenance and checks its phase and
+// remaining instances.
+func maintainStep(t *testing.T, r *Reconciler, cr clusterv1alpha1.OpenstackNode, phase string, remaining int32) {
+	t.Helper()
+	status, err := r.maintain(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != phase || status.RemainingInstances != remaining {
+		t.Fatalf("expected phase %s with %d instances, got %+v", phase, remaining, status)
+	}
+}
+
+func TestMaintainMigrate(t *testing.T) {
+
+	_, r, cr := newComputeReconciler(t, fake.ComputeHost)
+	compute, image := clients(t, r, cr)
+	ids := createServers(t, compute, image, "running", "stopped")
+	err := startstop.Stop(compute, ids[1]).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maintainStep(t, r, cr, MaintenanceDisabling, 0)
+	service, err := getComputeService(compute, cr.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.Status != "disabled" || service.DisabledReason != disabledReason {
+		t.Errorf("expected nova-compute disabled by kupenstack, got %+v", service)
+	}
+
+	// Running instance is live-migrated, stopped one cold-migrated.
+	maintainStep(t, r, cr, MaintenanceMigrating, 2)
+	if status, host := serverOn(t, compute, ids[0]); status != "ACTIVE" || host != fake.OtherComputeHost {
+		t.Errorf("expected running instance live-migrated, got %s on %s", status, host)
+	}
+	if status, host := serverOn(t, compute, ids[1]); status != "VERIFY_RESIZE" || host != fake.ComputeHost {
+		t.Errorf("expected stopped instance migrated awaiting confirmation, got %s on %s", status, host)
+	}
+
+	// Cold migration is confirmed.
+	maintainStep(t, r, cr, MaintenanceMigrating, 1)
+	if status, host := serverOn(t, compute, ids[1]); status != "SHUTOFF" || host != fake.OtherComputeHost {
+		t.Errorf("expected stopped instance moved, got %s on %s", status, host)
+	}
+
+	maintainStep(t, r, cr, MaintenanceCompleted, 0)
+}
+
+func TestMaintainEvacuate(t *testing.T) {
+
+	server, r, cr := newComputeReconciler(t, fake.ComputeHost)
+	compute, image := clients(t, r, cr)
+	ids := createServers(t, compute, image, "running", "stopped")
+	err := startstop.Stop(compute, ids[1]).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetComputeState(fake.ComputeHost, "down")
+
+	maintainStep(t, r, cr, MaintenanceDisabling, 0)
+	maintainStep(t, r, cr, MaintenanceMigrating, 2)
+	for i, expected := range []string{"ACTIVE", "SHUTOFF"} {
+		if status, host := serverOn(t, compute, ids[i]); status != expected || host != fake.OtherComputeHost {
+			t.Errorf("expected instance evacuated in status %s, got %s on %s", expected, status, host)
+		}
+	}
+	maintainStep(t, r, cr, MaintenanceCompleted, 0)
+}
+
+func TestMaintainWithoutCompute(t *testing.T) {
+
+	_, r, cr := newComputeReconciler(t, "control-0")
+
+	status, err := r.maintain(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != MaintenanceCompleted {
+		t.Errorf("expected maintenance of node without nova-compute completed, got %+v", status)
+	}
+	if err := r.endMaintenance(cr); err != nil {
+		t.Errorf("expected nothing to enable on node without nova-compute, got %s", err)
+	}
+}
+
+func TestEndMaintenance(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		reason  string
+		enabled bool
+	}{
+		{
+			name:    "disabled by kupenstack",
+			reason:  disabledReason,
+			enabled: true,
+		},
+		{
+			name:   "disabled by operator",
+			reason: "Broken disk.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, r, cr := newComputeReconciler(t, fake.ComputeHost)
+			compute, _ := clients(t, r, cr)
+
+			service, err := getComputeService(compute, cr.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = services.Update(compute, service.ID, services.UpdateOpts{
+				Status: services.ServiceDisabled, DisabledReason: test.reason}).Extract()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.endMaintenance(cr); err != nil {
+				t.Fatal(err)
+			}
+			service, err = getComputeService(compute, cr.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enabled := service.Status == "enabled"; enabled != test.enabled {
+				t.Errorf("expected nova-compute enabled %v, got %+v", test.enabled, service)
+			}
+		})
+	}
+}
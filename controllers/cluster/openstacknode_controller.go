@@ -19,12 +19,14 @@ package cluster
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -33,17 +35,51 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+	"github.com/kupenstack/kupenstack/pkg/helm"
 	"github.com/kupenstack/kupenstack/pkg/k8s"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
+const (
+	// Finalizer keeps osknode until its node is deregistered from OpenStack.
+	Finalizer = "kupenstack.io/finalizer"
+
+	// Osknodes are reconciled periodically, e.g. to follow hypervisors and
+	// maintenance.
+	requeuePeriod = 20 * time.Second
+
+	// Deregistering osknode from OpenStack failing for longer than this,
+	// e.g. as OpenStack is down, is given up and osknode deleted anyway.
+	deregisterTimeout = 30 * time.Minute
+)
+
 // OpenstackNodeReconciler reconciles a OpenstackNode object
 type Reconciler struct {
 	client.Client
+
+	KupenstackConfiguration *oskops.Configuration
+
+	OS *openstack.Clouds
+
+	// Reads preflight pods and StorageClasses bypassing cache, e.g.
+	// manager.GetAPIReader(). Client is used when not set.
+	APIReader client.Reader
+
+	// Image of preflight pods, DefaultPreflightImage when empty.
+	PreflightImage string
+
 	Scheme        *runtime.Scheme
 	Log           logr.Logger
 	EventRecorder record.EventRecorder
@@ -52,13 +88,27 @@ type Reconciler struct {
 //+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;create;delete
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = r.Log.WithValues("osknode", req.NamespacedName)
+	log := r.Log.WithValues("osknode", req.NamespacedName)
 
 	var cr clusterv1alpha1.OpenstackNode
 	err := r.Get(ctx, req.NamespacedName, &cr)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: 20000000000}, client.IgnoreNotFound(err)
+		return ctrl.Result{RequeueAfter: requeuePeriod}, client.IgnoreNotFound(err)
+	}
+
+	if !cr.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, cr)
+	}
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		controllerutil.AddFinalizer(&cr, Finalizer)
+		err = r.Update(ctx, &cr)
+		if err != nil {
+			return openstack.Result(err, requeuePeriod)
+		}
 	}
 
 	generatedCfg, err := r.generateDesiredNodeConfiguration(ctx, cr.Spec.Occp.Name, cr.Spec.Occp.Namespace)
@@ -67,7 +117,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, corev1.EventTypeWarning, "OCCPNotFound",
 				"Required OpenstackCloudConfigurationProfiles not found for osknode %s.", cr.Name)
 		}
-		return ctrl.Result{RequeueAfter: 20000000000}, err
+		return openstack.Result(err, requeuePeriod)
 	}
 
 	osknode := &unstructured.Unstructured{}
@@ -78,31 +128,201 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	})
 	err = r.Get(ctx, req.NamespacedName, osknode)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: 20000000000}, client.IgnoreNotFound(err)
+		return ctrl.Result{RequeueAfter: requeuePeriod}, client.IgnoreNotFound(err)
 	}
 
+	// A failing maintenance is retried only after status is written, so
+	// that generated configuration never waits for OpenStack.
+	maintenance, maintenanceErr := r.reconcileMaintenance(cr)
+	if maintenanceErr != nil {
+		r.Eventf(&cr, corev1.EventTypeWarning, "MaintenanceFailed",
+			"Maintenance of osknode %s failed. error: %s", cr.Name, maintenanceErr)
+	}
+
+	// OpenStack may not be deployed yet, so failing to read hypervisor only
+	// keeps its last known status.
+	hypervisor, err := r.hypervisorStatus(cr)
+	if err != nil {
+		log.V(1).Info("Failed to read hypervisor.", "error", err.Error())
+		hypervisor = cr.Status.Hypervisor
+	}
+
+	preflight, condition, err := r.preflight(ctx, cr, generatedCfg)
+	if err != nil {
+		return openstack.Result(err, requeuePeriod)
+	}
+	if preflight != nil && !reflect.DeepEqual(preflight, cr.Status.Preflight) {
+		if len(preflight.Failures) != 0 {
+			r.Eventf(&cr, corev1.EventTypeWarning, "PreflightFailed",
+				"Osknode %s failed preflight checks: %s", cr.Name, condition.Message)
+		} else {
+			r.Eventf(&cr, corev1.EventTypeNormal, "PreflightPassed",
+				"Osknode %s passed preflight checks.", cr.Name)
+		}
+	}
+	if preflight == nil {
+		preflight = cr.Status.Preflight
+	}
+	condition.ObservedGeneration = cr.Generation
+	conditions := append([]metav1.Condition{}, cr.Status.Conditions...)
+	meta.SetStatusCondition(&conditions, condition)
+
 	status := make(map[string]interface{})
 	if osknode.Object["status"] != nil {
 		status = osknode.Object["status"].(map[string]interface{})
 	}
 	status["desiredNodeConfiguration"] = generatedCfg
 	status["generated"] = true
+	if maintenance != nil {
+		status["maintenance"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(maintenance)
+		if err != nil {
+			return openstack.Result(err, requeuePeriod)
+		}
+	} else {
+		delete(status, "maintenance")
+	}
+	if hypervisor != nil {
+		status["hypervisor"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(hypervisor)
+		if err != nil {
+			return openstack.Result(err, requeuePeriod)
+		}
+	} else {
+		delete(status, "hypervisor")
+	}
+	if preflight != nil {
+		status["preflight"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(preflight)
+		if err != nil {
+			return openstack.Result(err, requeuePeriod)
+		}
+	}
+	status["conditions"], err = conditionsToUnstructured(conditions)
+	if err != nil {
+		return openstack.Result(err, requeuePeriod)
+	}
 	osknode.Object["status"] = status
 
 	err = r.Status().Update(ctx, osknode)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: 20000000000}, err
+		return openstack.Result(err, requeuePeriod)
 	}
 
+	cfg, err := r.KupenstackConfiguration.Read(ctx)
+	if err != nil {
+		return openstack.Result(err, requeuePeriod)
+	}
+	cloud := kupenstack.NewCloud(cr.Spec.Occp, cfg.Spec.DefaultProfile)
+
 	// get list of desired nodelabels from this osknodes
 	// and add them to k8snodes.
-	labels := getRequiredLables(osknode)
+	labels := getRequiredLables(osknode, cloud)
+	switch {
+	case inMaintenance(cr):
+		if maintenance == nil || maintenance.Phase != MaintenanceCompleted {
+			return openstack.Result(maintenanceErr, requeuePeriod)
+		}
+		labels = withoutOpenstackLabels(labels)
+	case condition.Status != metav1.ConditionTrue:
+		// node is labelled for its roles only after passing preflight checks
+		return openstack.Result(maintenanceErr, requeuePeriod)
+	}
 	err = r.addLabelsToK8sNode(ctx, req.NamespacedName, labels)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: 20000000000}, err
+		return openstack.Result(err, requeuePeriod)
+	}
+
+	return openstack.Result(maintenanceErr, requeuePeriod)
+}
+
+// reconcileDelete deregisters osknode from OpenStack before letting it go.
+func (r *Reconciler) reconcileDelete(ctx context.Context, cr clusterv1alpha1.OpenstackNode) (ctrl.Result, error) {
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// reason of event recorded once finalizer is removed
+	reason, message := "Deregistered", fmt.Sprintf("Osknode %s deregistered from OpenStack.", cr.Name)
+
+	if cr.Annotations[osknode.SkipDeregisterAnnotation] == "true" {
+		reason, message = "DeregisterSkipped", fmt.Sprintf(
+			"Osknode %s deleted without deregistering it from OpenStack, as annotated.", cr.Name)
+	} else {
+		done, err := r.deregister(ctx, cr)
+		switch {
+		case err != nil && time.Since(cr.DeletionTimestamp.Time) < deregisterTimeout:
+			r.Eventf(&cr, corev1.EventTypeWarning, "DeregisterFailed",
+				"Deregistering osknode %s from OpenStack failed. error: %s", cr.Name, err)
+			return openstack.Result(err, requeuePeriod)
+		case err != nil:
+			reason, message = "DeregisterSkipped", fmt.Sprintf(
+				"Osknode %s deleted without deregistering it from OpenStack, which failed for %s. error: %s",
+				cr.Name, deregisterTimeout, err)
+		case !done:
+			r.Eventf(&cr, corev1.EventTypeNormal, "Deregistering",
+				"Moving instances out of osknode %s before deregistering it.", cr.Name)
+			return ctrl.Result{RequeueAfter: requeuePeriod}, nil
+		}
+	}
+
+	controllerutil.RemoveFinalizer(&cr, Finalizer)
+	err := r.Update(ctx, &cr)
+	if err != nil {
+		return openstack.Result(err, requeuePeriod)
 	}
 
-	return ctrl.Result{RequeueAfter: 20000000000}, nil
+	eventtype := corev1.EventTypeNormal
+	if reason != "Deregistered" {
+		eventtype = corev1.EventTypeWarning
+	}
+	r.Eventf(&cr, eventtype, reason, "%s", message)
+	return ctrl.Result{}, nil
+}
+
+// reconcileMaintenance returns progress of maintenance of osknode, or nil
+// when osknode is not in maintenance. Ending maintenance enables its
+// nova-compute service again. On error, last known progress is returned.
+func (r *Reconciler) reconcileMaintenance(cr clusterv1alpha1.OpenstackNode) (*clusterv1alpha1.MaintenanceStatus, error) {
+
+	if !inMaintenance(cr) {
+		if cr.Status.Maintenance != nil {
+			err := r.endMaintenance(cr)
+			if err != nil {
+				return cr.Status.Maintenance, err
+			}
+			r.Eventf(&cr, corev1.EventTypeNormal, "MaintenanceEnded",
+				"Osknode %s is out of maintenance.", cr.Name)
+		}
+		return nil, nil
+	}
+
+	if cr.Status.Maintenance == nil {
+		r.Eventf(&cr, corev1.EventTypeNormal, "MaintenanceStarted",
+			"Osknode %s is going into maintenance.", cr.Name)
+	}
+
+	maintenance, err := r.maintain(cr)
+	if unreachable(err) && !draining(cr) {
+		// OpenStack is not deployed yet or down, so node has no instances
+		// to move, or they were moved already.
+		maintenance, err = cr.Status.Maintenance, nil
+		if maintenance == nil {
+			maintenance = &clusterv1alpha1.MaintenanceStatus{
+				Phase:   MaintenanceCompleted,
+				Message: "OpenStack is not reachable, nothing to drain.",
+			}
+		}
+	}
+	if err != nil {
+		return cr.Status.Maintenance, err
+	}
+
+	if maintenance.Phase == MaintenanceCompleted &&
+		(cr.Status.Maintenance == nil || cr.Status.Maintenance.Phase != MaintenanceCompleted) {
+		r.Eventf(&cr, corev1.EventTypeNormal, "MaintenanceCompleted",
+			"All instances moved out of osknode %s.", cr.Name)
+	}
+
+	return maintenance, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -110,9 +330,45 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&clusterv1alpha1.OpenstackNode{}).
 		Watches(&source.Kind{Type: &clusterv1alpha1.OpenStackCloudConfigurationProfile{}}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &clusterv1alpha1.KupenstackConfiguration{}},
+			handler.EnqueueRequestsFromMapFunc(r.allOskNodes)).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
 		Complete(r)
 }
 
+// allOskNodes returns requests for all osknodes, as default profile in
+// KupenstackConfiguration decides labels of each of them.
+func (r *Reconciler) allOskNodes(_ client.Object) []reconcile.Request {
+
+	var oskNodeList clusterv1alpha1.OpenstackNodeList
+	err := r.List(context.Background(), &oskNodeList)
+	if err != nil {
+		r.Log.Error(err, "Failed to list osknodes.")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(oskNodeList.Items))
+	for _, osknode := range oskNodeList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: osknode.Name},
+		})
+	}
+	return requests
+}
+
+func conditionsToUnstructured(conditions []metav1.Condition) ([]interface{}, error) {
+
+	list := make([]interface{}, 0, len(conditions))
+	for i := range conditions {
+		condition, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conditions[i])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, condition)
+	}
+	return list, nil
+}
+
 // Records kubernetes event for passed custom resources.
 func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
 	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
@@ -134,7 +390,7 @@ func (r *Reconciler) addLabelsToK8sNode(ctx context.Context, name types.Namespac
 	return r.Update(ctx, &cr)
 }
 
-func getRequiredLables(osknode *unstructured.Unstructured) map[string]string {
+func getRequiredLables(osknode *unstructured.Unstructured, cloud kupenstack.Cloud) map[string]string {
 
 	labels := make(map[string]string)
 	labels["openstack-control-plane"] = ""
@@ -149,10 +405,10 @@ func getRequiredLables(osknode *unstructured.Unstructured) map[string]string {
 		for _, role := range roles {
 			role = strings.TrimSpace(role)
 			if role == "control" {
-				labels["openstack-control-plane"] = "enabled"
+				labels["openstack-control-plane"] = cloud.NodeSelectorValue()
 			}
 			if role == "compute" {
-				labels["openstack-compute-node"] = "enabled"
+				labels["openstack-compute-node"] = cloud.NodeSelectorValue()
 			}
 		}
 	}
@@ -185,7 +441,7 @@ func getRequiredLables(osknode *unstructured.Unstructured) map[string]string {
 
 	// TODO: manage labels for linux-bridge, openvswitch
 	// temporary fix:
-	labels["linuxbridge"] = "enabled"
+	labels["linuxbridge"] = cloud.NodeSelectorValue()
 
 	return labels
 }
@@ -207,186 +463,28 @@ func isEnabledLabel(cfg map[string]interface{}, componentName string) (string, s
 	return labelKey, labelValue
 }
 
-func (r *Reconciler) getProfileData(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
-
-	occp := &unstructured.Unstructured{}
-	occp.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "cluster.kupenstack.io",
-		Kind:    "OpenStackCloudConfigurationProfile",
-		Version: "v1alpha1",
-	})
-	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, occp)
-	if err != nil {
-		return nil, err
-	}
-	if occp.Object["spec"] == nil {
-		return nil, nil
-	}
-	data := occp.Object["spec"].(map[string]interface{})
-	return data, err
-}
-
-func fetchProfileData(urlPath string) (map[string]interface{}, error) {
-
-	occp, err := utils.ReadYamlFromUrl(urlPath)
-	if err != nil {
-		return nil, err
-	}
-
-	data := occp["spec"].(map[string]interface{})
-	return data, nil
-}
-
-// generateProfileData() is a recurring function. It reads profile data, and
-// if the profile has any parent then it recurs. The resulting data is drived by
-// overriding parent data values.
-func (r *Reconciler) generateProfileData(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
-
-	var data map[string]interface{}
-	var err error
-
-	if isUrl(name) {
-		data, err = fetchProfileData(name)
-	} else {
-		data, err = r.getProfileData(ctx, name, namespace)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	// if no parent then return data
-	if data["from"] == nil || data["from"] == "" {
-		return data, nil
-	}
-
-	// else get parent and merge into profile-data
-	parent := data["from"].(string)
-
-	if isUrl(parent) {
-		name = parent
-		// have not changed namespace to preserve it as default namespace for next function recussion.
-	} else {
-		profileName, profileNamespace := r.predictNameNamespace(parent)
-		name = profileName
-		if profileNamespace != "" {
-			namespace = profileNamespace
-		}
-	}
-
-	parentData, err := r.generateProfileData(ctx, name, namespace)
-	if err != nil {
-		return nil, err
-	}
-	return utils.PatchJson(parentData, data), err
-}
-
-// Predict Name/Namespace of profile based on FQDN name provided.
-func (r *Reconciler) predictNameNamespace(profile string) (string, string) {
-
-	profileName := profile
-	profileNamespace := ""
-
-	// if dot separated name, then split to get namespace.
-	profileFQDN := strings.Split(profile, ".")
-	if len(profileFQDN) > 1 {
-		profileName = strings.Join(profileFQDN[:len(profileFQDN)-1], ".")
-		profileNamespace = profileFQDN[len(profileFQDN)-1]
-	}
-
-	// check if namespace exists
-	if profileNamespace != "" {
-		err := r.Get(context.Background(), types.NamespacedName{Name: profileNamespace}, &corev1.Namespace{})
-		if err != nil && errors.IsNotFound(err) {
-			// Maybe profile is in same namespace
-			profileNamespace = ""
-			profileName = profile
-		}
-	}
-
-	return profileName, profileNamespace
-}
-
 func (r *Reconciler) generateDesiredNodeConfiguration(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
 
-	data, err := r.generateProfileData(ctx, name, namespace)
-	if err != nil {
-		return nil, err
-	}
-	if data == nil {
-		return nil, nil
-	}
-
-	data["keystone"], err = transformKeys(data["keystone"])
-	if err != nil {
-		return nil, err
-	}
-
-	data["glance"], err = transformKeys(data["glance"])
-	if err != nil {
-		return nil, err
-	}
-
-	data["horizon"], err = transformKeys(data["horizon"])
-	if err != nil {
-		return nil, err
-	}
-
-	data["nova"], err = transformKeys(data["nova"], "metadata", "api_metadata", "ironic", "compute_ironic")
-	if err != nil {
-		return nil, err
-	}
-
-	data["neutron"], err = transformKeys(data["neutron"], "ironicAgent", "ironic_agent")
+	resolver := occp.Resolver{Client: r}
+	data, _, err := resolver.Resolve(ctx, name, namespace)
 	if err != nil {
 		return nil, err
 	}
-
-	data["placement"], err = transformKeys(data["placement"])
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
-}
-
-func transformKeys(data interface{}, args ...string) (map[string]interface{}, error) {
-
-	if len(args)%2 == 1 {
-		return nil, fmt.Errorf("transformKeys() must have even number of keys.")
-	}
-
 	if data == nil {
 		return nil, nil
 	}
-	conf := data.(map[string]interface{})
-
-	if conf["replicas"] == nil {
-		return data.(map[string]interface{}), nil
-	}
-	replicas := conf["replicas"].(map[string]interface{})
-
-	for i := range args {
-		if i%2 == 1 {
-			continue
-		}
-		// replace keyname of i with i+1
-		if replicas[args[i]] != nil {
-			replicas[args[i+1]] = replicas[args[i]]
-			delete(replicas, args[i])
-		}
-	}
 
-	conf["pod"] = map[string]interface{}{
-		"replicas": replicas,
-	}
-	delete(conf, "replicas")
-	return conf, nil
+	return occp.ChartValues(data, r.chartVersion)
 }
 
-func isUrl(testUrl string) bool {
-	_, err := url.ParseRequestURI(testUrl)
-	if err == nil {
-		return true
+// chartVersion returns version of openstack-helm chart which would be
+// deployed, or empty string when helm repository is not available yet, in
+// which case mapping of newest chart version is used.
+func (r *Reconciler) chartVersion(chart string) string {
+	version, err := helm.ChartVersion("osh", chart)
+	if err != nil {
+		r.Log.V(1).Info("Chart not available, using newest mapping.", "chart", chart, "error", err.Error())
+		return ""
 	}
-	return false
+	return version
 }
@@ -0,0 +1,360 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/chartmap"
+)
+
+const (
+	// Namespace preflight pods run in.
+	preflightNamespace = "kube-system"
+
+	// DefaultPreflightImage is image of preflight pods unless
+	// Reconciler.PreflightImage is set. Only a shell is needed.
+	DefaultPreflightImage = "busybox:1.34"
+
+	// Annotation of preflight pod with roles it checks node for.
+	preflightRolesAnnotation = "kupenstack.io/preflight-roles"
+
+	// Time after which unfinished preflight pod is reported as failed.
+	preflightTimeout = 5 * time.Minute
+
+	// Time after which checks of a node that failed them are run again.
+	// Until then failures are reported from the finished pod.
+	preflightRetryPeriod = 10 * time.Minute
+
+	// StorageClass requested by charts when volume class is not configured.
+	defaultStorageClass = "general"
+)
+
+// Kernel modules required by linuxbridge backend of neutron.
+var bridgeModules = []string{"bridge", "br_netfilter"}
+
+// preflightScript prints name of each failing node check to termination
+// log of preflight pod. Modules are available when loaded, built in, or
+// can be loaded from /lib/modules of node.
+var preflightScript = `{
+[ -c /host/dev/kvm ] || echo kvm
+for m in ` + strings.Join(bridgeModules, " ") + `; do
+  [ -d /sys/module/$m ] && continue
+  grep -qs "/$m.ko" /host/lib/modules/$(uname -r)/modules.dep /host/lib/modules/$(uname -r)/modules.builtin && continue
+  echo module:$m
+done
+} > /dev/termination-log`
+
+// preflight runs preflight checks of osknode for its roles in pod pinned to
+// node, and returns their result and condition. Result is nil while checks
+// are running. Checks are run again when roles of node change, and every
+// preflightRetryPeriod until node passes them. Finished pod of failed
+// checks is kept until then, so that failures follow changes of
+// configuration without running checks again.
+func (r *Reconciler) preflight(ctx context.Context, cr clusterv1alpha1.OpenstackNode,
+	cfg map[string]interface{}) (*clusterv1alpha1.PreflightStatus, metav1.Condition, error) {
+
+	roles := nodeRoles(cr)
+
+	last := cr.Status.Preflight
+	passed := meta.IsStatusConditionTrue(cr.Status.Conditions, clusterv1alpha1.NodePreflightPassed)
+	if passed && last != nil && last.Roles == roles {
+		return last, *meta.FindStatusCondition(cr.Status.Conditions, clusterv1alpha1.NodePreflightPassed), nil
+	}
+
+	pending := metav1.Condition{
+		Type:    clusterv1alpha1.NodePreflightPassed,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "Running",
+		Message: "Preflight checks are running.",
+	}
+	if roles == "" {
+		return &clusterv1alpha1.PreflightStatus{}, preflightCondition(nil), nil
+	}
+
+	var pod corev1.Pod
+	name := types.NamespacedName{Name: preflightPodName(cr.Name), Namespace: preflightNamespace}
+	err := r.reader().Get(ctx, name, &pod)
+	if errors.IsNotFound(err) {
+		pod = preflightPod(cr, roles, r.preflightImage())
+		err = controllerutil.SetOwnerReference(&cr, &pod, r.Scheme)
+		if err != nil {
+			return nil, pending, err
+		}
+		return nil, pending, r.Create(ctx, &pod)
+	}
+	if err != nil {
+		return nil, pending, err
+	}
+
+	// Pod checked node for other roles, it is run again.
+	if pod.Annotations[preflightRolesAnnotation] != roles {
+		return nil, pending, client.IgnoreNotFound(r.Delete(ctx, &pod))
+	}
+
+	var checks []string
+	switch {
+	case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+		checks = nodeFailures(pod)
+	case time.Since(pod.CreationTimestamp.Time) > preflightTimeout:
+		checks = []string{fmt.Sprintf("preflight pod did not complete in %s", preflightTimeout)}
+	default:
+		return nil, pending, nil
+	}
+
+	failures := requiredFailures(checks, roles, cfg)
+	storage, err := r.storageFailures(ctx, roles, cfg)
+	if err != nil {
+		return nil, pending, err
+	}
+	failures = append(failures, storage...)
+
+	if len(failures) == 0 || time.Since(pod.CreationTimestamp.Time) > preflightRetryPeriod {
+		err = r.Delete(ctx, &pod)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, pending, err
+		}
+	}
+
+	return &clusterv1alpha1.PreflightStatus{Roles: roles, Failures: failures}, preflightCondition(failures), nil
+}
+
+func preflightCondition(failures []string) metav1.Condition {
+	if len(failures) != 0 {
+		return metav1.Condition{
+			Type:    clusterv1alpha1.NodePreflightPassed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Failed",
+			Message: strings.Join(failures, "; "),
+		}
+	}
+	return metav1.Condition{
+		Type:    clusterv1alpha1.NodePreflightPassed,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Passed",
+		Message: "Node passed preflight checks for its roles.",
+	}
+}
+
+// nodeFailures returns checks failed on node as reported by preflight pod.
+func nodeFailures(pod corev1.Pod) []string {
+
+	var failures []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodFailed {
+			failures = append(failures, fmt.Sprintf("preflight pod failed: %s", status.State.Terminated.Reason))
+			continue
+		}
+		for _, line := range strings.Split(status.State.Terminated.Message, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				failures = append(failures, line)
+			}
+		}
+	}
+	if len(failures) == 0 && pod.Status.Phase == corev1.PodFailed {
+		failures = append(failures, "preflight pod failed: "+pod.Status.Reason)
+	}
+	return failures
+}
+
+// requiredFailures converts checks failed on node to messages, dropping
+// checks not required by roles and configuration of node.
+func requiredFailures(checks []string, roles string, cfg map[string]interface{}) []string {
+
+	var failures []string
+	for _, check := range checks {
+		switch {
+		case check == "kvm":
+			virtType, _ := componentValue(cfg, "nova", "conf.nova.libvirt.virt_type").(string)
+			if !strings.Contains(roles, "compute") || virtType == "qemu" {
+				continue
+			}
+			failures = append(failures, "/dev/kvm not found, enable hardware virtualization "+
+				"or set nova conf.nova.libvirt.virt_type to qemu")
+		case strings.HasPrefix(check, "module:"):
+			if !linuxbridge(cfg) {
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("kernel module %s required by linuxbridge is not available",
+				strings.TrimPrefix(check, "module:")))
+		default:
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+// linuxbridge reports whether neutron configuration of node selects the
+// linuxbridge backend, by its interface driver or by backend in values.
+func linuxbridge(cfg map[string]interface{}) bool {
+
+	driver, _ := componentValue(cfg, "neutron", "conf.neutron.DEFAULT.interface_driver").(string)
+	if driver == "linuxbridge" {
+		return true
+	}
+	backends, _ := componentValue(cfg, "neutron", "network.backend").([]interface{})
+	for _, backend := range backends {
+		if backend == "linuxbridge" {
+			return true
+		}
+	}
+	return false
+}
+
+// storageFailures checks StorageClasses requested by persistent volumes of
+// mariadb and rabbitmq, which run on control nodes.
+func (r *Reconciler) storageFailures(ctx context.Context, roles string, cfg map[string]interface{}) ([]string, error) {
+
+	if !strings.Contains(roles, "control") {
+		return nil, nil
+	}
+
+	var failures []string
+	for _, component := range []string{"mariadb", "rabbitmq"} {
+		enabled, _ := componentValue(cfg, component, "volume.enabled").(bool)
+		if !enabled {
+			continue
+		}
+		class, _ := componentValue(cfg, component, "volume.class_name").(string)
+		if class == "" {
+			class = defaultStorageClass
+		}
+
+		var sc storagev1.StorageClass
+		err := r.reader().Get(ctx, types.NamespacedName{Name: class}, &sc)
+		if errors.IsNotFound(err) {
+			failures = append(failures, fmt.Sprintf("StorageClass %s requested by %s volume not found", class, component))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return failures, nil
+}
+
+// componentValue returns value at dot separated `path` of component in
+// desired node configuration, with `values` of component taking priority.
+func componentValue(cfg map[string]interface{}, component, path string) interface{} {
+
+	section, _ := cfg[component].(map[string]interface{})
+	if section == nil {
+		return nil
+	}
+	if values, ok := section["values"].(map[string]interface{}); ok {
+		if val, ok := chartmap.GetPath(values, path); ok {
+			return val
+		}
+	}
+	val, _ := chartmap.GetPath(section, path)
+	return val
+}
+
+// nodeRoles returns sorted, comma separated roles of osknode.
+func nodeRoles(cr clusterv1alpha1.OpenstackNode) string {
+
+	var roles []string
+	for _, role := range strings.Split(cr.Annotations["node-role"], ",") {
+		role = strings.TrimSpace(role)
+		if role == "control" || role == "compute" {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return strings.Join(roles, ",")
+}
+
+// reader returns reader of preflight pods and StorageClasses. They are
+// read from API server, as caching them would watch all pods and
+// StorageClasses of cluster.
+func (r *Reconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+func (r *Reconciler) preflightImage() string {
+	if r.PreflightImage != "" {
+		return r.PreflightImage
+	}
+	return DefaultPreflightImage
+}
+
+func preflightPodName(node string) string {
+	return "kupenstack-preflight-" + node
+}
+
+// preflightPod returns pod running preflight checks on node for `roles`
+// with `image`. Host paths are mounted read-only, the pod needs no
+// privileges.
+func preflightPod(cr clusterv1alpha1.OpenstackNode, roles, image string) corev1.Pod {
+
+	hostPathDirectory := corev1.HostPathDirectory
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      preflightPodName(cr.Name),
+			Namespace: preflightNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "kupenstack-preflight",
+				"app.kubernetes.io/managed-by": "kupenstack",
+			},
+			Annotations: map[string]string{
+				preflightRolesAnnotation: roles,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      cr.Name,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:    "preflight",
+				Image:   image,
+				Command: []string{"sh", "-c", preflightScript},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "dev", MountPath: "/host/dev", ReadOnly: true},
+					{Name: "modules", MountPath: "/host/lib/modules", ReadOnly: true},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{Name: "dev", VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/dev", Type: &hostPathDirectory},
+				}},
+				{Name: "modules", VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/lib/modules", Type: &hostPathDirectory},
+				}},
+			},
+		},
+	}
+}
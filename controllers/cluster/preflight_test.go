@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+)
+
+func newPreflightReconciler(objs ...client.Object) *Reconciler {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	clusterv1alpha1.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &Reconciler{Client: c, Scheme: scheme}
+}
+
+func TestRequiredFailures(t *testing.T) {
+
+	kvm := "/dev/kvm not found, enable hardware virtualization or set nova conf.nova.libvirt.virt_type to qemu"
+	qemuConf := map[string]interface{}{"nova": map[string]interface{}{
+		"conf": map[string]interface{}{"nova": map[string]interface{}{"libvirt": map[string]interface{}{"virt_type": "qemu"}}},
+	}}
+	qemuValues := map[string]interface{}{"nova": map[string]interface{}{
+		"values": map[string]interface{}{"conf": map[string]interface{}{"nova": map[string]interface{}{
+			"libvirt": map[string]interface{}{"virt_type": "qemu"}}}},
+	}}
+	linuxbridgeConf := map[string]interface{}{"neutron": map[string]interface{}{
+		"conf": map[string]interface{}{"neutron": map[string]interface{}{
+			"DEFAULT": map[string]interface{}{"interface_driver": "linuxbridge"}}},
+	}}
+	linuxbridgeValues := map[string]interface{}{"neutron": map[string]interface{}{
+		"values": map[string]interface{}{"network": map[string]interface{}{"backend": []interface{}{"linuxbridge"}}},
+	}}
+
+	tests := []struct {
+		name   string
+		checks []string
+		roles  string
+		cfg    map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "kvm on compute node",
+			checks: []string{"kvm"},
+			roles:  "compute,control",
+			want:   []string{kvm},
+		},
+		{
+			name:   "kvm on control node",
+			checks: []string{"kvm"},
+			roles:  "control",
+		},
+		{
+			name:   "kvm with qemu in conf",
+			checks: []string{"kvm"},
+			roles:  "compute",
+			cfg:    qemuConf,
+		},
+		{
+			name:   "kvm with qemu in values",
+			checks: []string{"kvm"},
+			roles:  "compute",
+			cfg:    qemuValues,
+		},
+		{
+			name:   "modules and other failures",
+			checks: []string{"module:br_netfilter", "preflight pod failed: Error"},
+			roles:  "control",
+			cfg:    linuxbridgeConf,
+			want: []string{"kernel module br_netfilter required by linuxbridge is not available",
+				"preflight pod failed: Error"},
+		},
+		{
+			name:   "modules with linuxbridge in values",
+			checks: []string{"module:bridge"},
+			roles:  "compute",
+			cfg:    linuxbridgeValues,
+			want:   []string{"kernel module bridge required by linuxbridge is not available"},
+		},
+		{
+			name:   "modules without linuxbridge",
+			checks: []string{"module:bridge", "module:br_netfilter"},
+			roles:  "compute",
+			cfg:    qemuConf,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := requiredFailures(test.checks, test.roles, test.cfg)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected failures %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestStorageFailures(t *testing.T) {
+
+	r := newPreflightReconciler(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+	volume := func(enabled bool, class string) map[string]interface{} {
+		return map[string]interface{}{"volume": map[string]interface{}{"enabled": enabled, "class_name": class}}
+	}
+
+	tests := []struct {
+		name  string
+		roles string
+		cfg   map[string]interface{}
+		want  []string
+	}{
+		{
+			name:  "compute node",
+			roles: "compute",
+			cfg:   map[string]interface{}{"mariadb": volume(true, "")},
+		},
+		{
+			name:  "default class missing",
+			roles: "control",
+			cfg:   map[string]interface{}{"mariadb": volume(true, ""), "rabbitmq": volume(false, "")},
+			want:  []string{"StorageClass general requested by mariadb volume not found"},
+		},
+		{
+			name:  "configured classes",
+			roles: "compute,control",
+			cfg:   map[string]interface{}{"mariadb": volume(true, "fast"), "rabbitmq": volume(true, "slow")},
+			want:  []string{"StorageClass slow requested by rabbitmq volume not found"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := r.storageFailures(context.Background(), test.roles, test.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected failures %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+// Pod of failed checks is kept until preflightRetryPeriod has passed or
+// roles of node change.
+func TestPreflightRetry(t *testing.T) {
+
+	cr := clusterv1alpha1.OpenstackNode{ObjectMeta: metav1.ObjectMeta{Name: "node-1",
+		Annotations: map[string]string{"node-role": "compute"}}}
+	finishedPod := func(age time.Duration, roles string) *corev1.Pod {
+		pod := preflightPod(cr, roles, DefaultPreflightImage)
+		pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+		pod.Status = corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Message: "kvm\n"},
+			}}},
+		}
+		return &pod
+	}
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		failed bool
+		kept   bool
+	}{
+		{
+			name:   "within retry period",
+			pod:    finishedPod(time.Minute, "compute"),
+			failed: true,
+			kept:   true,
+		},
+		{
+			name:   "after retry period",
+			pod:    finishedPod(time.Hour, "compute"),
+			failed: true,
+		},
+		{
+			name: "roles changed",
+			pod:  finishedPod(time.Minute, "control"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newPreflightReconciler(test.pod)
+
+			result, _, err := r.preflight(context.Background(), cr, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if failed := result != nil && len(result.Failures) != 0; failed != test.failed {
+				t.Errorf("expected failed %v, got result %+v", test.failed, result)
+			}
+
+			var pod corev1.Pod
+			err = r.Get(context.Background(), types.NamespacedName{Name: test.pod.Name, Namespace: test.pod.Namespace}, &pod)
+			if client.IgnoreNotFound(err) != nil {
+				t.Fatal(err)
+			}
+			if kept := !errors.IsNotFound(err); kept != test.kept {
+				t.Errorf("expected pod kept %v, got %v", test.kept, kept)
+			}
+		})
+	}
+}
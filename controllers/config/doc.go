@@ -0,0 +1,33 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config implements kupenstackconfiguration-reconciler for kupenstack
+// controller.
+//
+// Working: validates KupenstackConfiguration and reports result in its
+// `Ready` condition, then notifies oskops and osknode sync so that changes
+// take effect immediately. Only the configuration named `kupenstack` is used;
+// without it kupenstack falls back to the configuration file.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                  MESSAGE
+//
+//  InvalidConfiguration    %s
+//  ProfileNotFound         %s
+//  Ignored                 Only KupenstackConfiguration named %s is used.
+package config
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+	"github.com/kupenstack/kupenstack/pkg/k8s"
+)
+
+// Reconciler reconciles a KupenstackConfiguration object
+type Reconciler struct {
+	client.Client
+
+	KupenstackConfiguration *oskops.Configuration
+
+	Scheme        *runtime.Scheme
+	Log           logr.Logger
+	EventRecorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=kupenstackconfigurations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=kupenstackconfigurations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstackcloudconfigurationprofiles,verbs=get;list;watch
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("kupenstackconfiguration", req.Name)
+
+	var cr clusterv1alpha1.KupenstackConfiguration
+	err := r.Get(ctx, req.NamespacedName, &cr)
+	if err != nil {
+		if errors.IsNotFound(err) && req.Name == clusterv1alpha1.KupenstackConfigurationName {
+			// Fall back to configuration file.
+			r.KupenstackConfiguration.Notify()
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	condition := r.check(ctx, cr)
+	if condition.Status != metav1.ConditionTrue {
+		r.Eventf(&cr, corev1.EventTypeWarning, condition.Reason, "%s", condition.Message)
+	}
+
+	meta.SetStatusCondition(&cr.Status.Conditions, condition)
+	cr.Status.ObservedGeneration = cr.Generation
+	err = r.Status().Update(ctx, &cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if cr.Name == clusterv1alpha1.KupenstackConfigurationName {
+		r.KupenstackConfiguration.Notify()
+	}
+	return ctrl.Result{}, nil
+}
+
+// check returns `Ready` condition of configuration.
+func (r *Reconciler) check(ctx context.Context, cr clusterv1alpha1.KupenstackConfiguration) metav1.Condition {
+
+	condition := metav1.Condition{
+		Type:               clusterv1alpha1.ConfigurationReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: cr.Generation,
+	}
+
+	if cr.Name != clusterv1alpha1.KupenstackConfigurationName {
+		condition.Reason = "Ignored"
+		condition.Message = fmt.Sprintf("Only KupenstackConfiguration named %s is used.",
+			clusterv1alpha1.KupenstackConfigurationName)
+		return condition
+	}
+
+	err := oskops.ValidateKupenstackConfiguration(cr)
+	if err != nil {
+		condition.Reason = "InvalidConfiguration"
+		condition.Message = err.Error()
+		return condition
+	}
+
+	err = r.checkProfiles(ctx, cr)
+	if err != nil {
+		condition.Reason = "ProfileNotFound"
+		condition.Message = err.Error()
+		return condition
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Valid"
+	condition.Message = "Configuration is in use."
+	return condition
+}
+
+// checkProfiles returns error if any OCCP referenced by configuration does not exist.
+func (r *Reconciler) checkProfiles(ctx context.Context, cr clusterv1alpha1.KupenstackConfiguration) error {
+
+	refs := []clusterv1alpha1.OccpRef{cr.Spec.DefaultProfile}
+	for _, rule := range cr.Spec.NodeRules {
+		if rule.Profile != nil {
+			refs = append(refs, *rule.Profile)
+		}
+	}
+	for _, node := range cr.Spec.Nodes {
+		if node.Profile != nil {
+			refs = append(refs, *node.Profile)
+		}
+	}
+
+	for _, ref := range refs {
+		var occp clusterv1alpha1.OpenStackCloudConfigurationProfile
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, &occp)
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("OpenStackCloudConfigurationProfile %s/%s not found.", ref.Namespace, ref.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Configuration is
+// checked again whenever any OCCP is created or deleted.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&clusterv1alpha1.KupenstackConfiguration{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &clusterv1alpha1.OpenStackCloudConfigurationProfile{}},
+			handler.EnqueueRequestsFromMapFunc(func(_ client.Object) []reconcile.Request {
+				return []reconcile.Request{{
+					NamespacedName: types.NamespacedName{Name: clusterv1alpha1.KupenstackConfigurationName},
+				}}
+			}),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(event.UpdateEvent) bool { return false },
+			})).
+		Complete(r)
+}
+
+// Records kubernetes event for passed custom resources.
+func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
+	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
+		eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
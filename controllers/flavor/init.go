@@ -30,7 +30,7 @@ import (
 func (r *Reconciler) init(ctx context.Context, cr kstypes.Flavor) error {
 	log := r.Log.WithValues("flavor", cr.Name)
 
-	osclient, err := r.OS.GetClient("compute")
+	osclient, err := r.OS.For(&cr).GetClient("compute")
 	if err != nil {
 		return err
 	}
@@ -63,6 +63,9 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.Flavor) error {
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
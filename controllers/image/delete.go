@@ -24,19 +24,20 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) delete(ctx context.Context, cr kstypes.Image) error {
 	log := r.Log.WithValues("image", cr.Name)
 
-	osclient, err := r.OS.GetClient("image")
+	osclient, err := r.OS.For(&cr).GetClient("image")
 	if err != nil {
 		return err
 	}
 
 	err = images.Delete(osclient, cr.Status.ID).ExtractErr()
-	if ignoreNotFoundError(err) != nil {
+	if openstack.IgnoreNotFound(err) != nil {
 		log.Error(err, msgDeleteFailed)
 		return err
 	}
@@ -55,15 +56,3 @@ func (r *Reconciler) delete(ctx context.Context, cr kstypes.Image) error {
 	r.Eventf(&cr, coreV1.EventTypeNormal, "Deleted", "Image deleted.")
 	return nil
 }
-
-func ignoreNotFoundError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	if err.Error() == "Resource not found" {
-		return nil
-	} else {
-		return err
-	}
-}
@@ -19,6 +19,7 @@ package image
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
@@ -28,6 +29,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
 	"github.com/kupenstack/kupenstack/pkg/k8s"
@@ -51,10 +53,19 @@ const (
 	msgFinalizerRemoveFailed = "Failed to remove image finalizer at kubernetes."
 )
 
+// Requeue periods
+const (
+	// resource is checked again soon after it is created at openstack
+	createRequeuePeriod = time.Second
+
+	// status of resource is refreshed periodically
+	requeuePeriod = 2 * time.Second
+)
+
 // Reconciler reconciles a Image object
 type Reconciler struct {
 	client.Client
-	OS            *openstack.Client
+	OS            *openstack.Clouds
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
@@ -80,7 +91,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "CreateFailed",
 				"Image create failed. error: %s", err)
 		}
-		return ctrl.Result{RequeueAfter: 1000000000}, err
+		return openstack.Result(err, createRequeuePeriod)
 
 	}
 
@@ -91,24 +102,24 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "DeleteFailed",
 				"Image deletion failed. error: %s", err)
 		}
-		return ctrl.Result{}, err
+		return openstack.Result(err, 0)
 	}
 
-	osclient, err := r.OS.GetClient("image")
+	osclient, err := r.OS.For(&cr).GetClient("image")
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	img, err := images.Get(osclient, cr.Status.ID).Extract()
-	if ignoreNotFoundError(err) != nil {
-		return ctrl.Result{}, err
+	if openstack.IgnoreNotFound(err) != nil {
+		return openstack.Result(err, requeuePeriod)
 	}
 
-	if notFoundErr(err) {
+	if openstack.IsNotFound(err) {
+		// image is created again at openstack
 		cr.Status.ID = ""
-	}
-
-	if string(img.Status) == "active" {
+		cr.Status.Ready = false
+	} else if img.Status == images.ImageStatusActive {
 		cr.Status.Ready = true
 	} else {
 		cr.Status.Ready = false
@@ -126,13 +137,14 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	log.Info("reconciled")
-	return ctrl.Result{RequeueAfter: 2000000000}, err
+	return openstack.Result(err, requeuePeriod)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kstypes.Image{}).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
 		Complete(r)
 }
 
@@ -141,15 +153,3 @@ func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt stri
 	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
 		eventtype, reason, fmt.Sprintf(messageFmt, args...))
 }
-
-func notFoundErr(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	if err.Error() == "Resource not found" {
-		return true
-	} else {
-		return false
-	}
-}
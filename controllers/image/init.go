@@ -31,7 +31,7 @@ import (
 func (r *Reconciler) init(ctx context.Context, cr kstypes.Image) error {
 	log := r.Log.WithValues("image", cr.Name)
 
-	osclient, err := r.OS.GetClient("image")
+	osclient, err := r.OS.For(&cr).GetClient("image")
 	if err != nil {
 		return err
 	}
@@ -73,6 +73,9 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.Image) error {
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
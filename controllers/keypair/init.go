@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 
+	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
 	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,19 +29,25 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) init(ctx context.Context, cr kstypes.KeyPair) error {
 	log := r.Log.WithValues("keypair", cr.Namespace+"/"+cr.Name)
 
-	osclient, err := r.OS.GetClient("compute")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, false)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("compute")
 	if err != nil {
 		return err
 	}
 
 	createOpts := keypairs.CreateOpts{
-		Name:      r.generateName(cr.Name),
+		Name:      r.generateName(osclient, cr.Name),
 		PublicKey: cr.Spec.PublicKey,
 	}
 
@@ -96,7 +103,11 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.KeyPair) error {
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
+	cr.Annotations[project.ProjectIDAnnotation] = cloud.ProjectID()
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
 	}
@@ -112,11 +123,10 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.KeyPair) error {
 }
 
 // Appends passed string with a random string suffix.
-func (r *Reconciler) generateName(name string) string {
+func (r *Reconciler) generateName(osclient *gophercloud.ServiceClient, name string) string {
 
 	generatedName := utilname.SimpleNameGenerator.GenerateName(name + "-")
 
-	osclient, _ := r.OS.GetClient("compute")
 	allPages, _ := keypairs.List(osclient).AllPages()
 	allKeyPairs, _ := keypairs.ExtractKeyPairs(allPages)
 
@@ -131,7 +141,7 @@ func (r *Reconciler) generateName(name string) string {
 		return generatedName
 	} else {
 		// try again with func recursion
-		return r.generateName(name)
+		return r.generateName(osclient, name)
 	}
 
 }
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keypair_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/keypair"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func TestKeyPairLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	server := fake.NewServer()
+	defer server.Close()
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	clouds.SetDefault("fake")
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	kstypes.AddToScheme(scheme)
+
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}},
+		&kstypes.KeyPair{ObjectMeta: metav1.ObjectMeta{Name: "key", Namespace: "demo"}},
+	).Build()
+
+	projectReconciler := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+	keypairReconciler := &keypair.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+
+	_, err = projectReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}})
+	if err != nil {
+		t.Fatalf("project not created: %s", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "key"}}
+	_, err = keypairReconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("keypair not created: %s", err)
+	}
+
+	var cr kstypes.KeyPair
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.Status.ID == "" || cr.Spec.PublicKey == "" {
+		t.Errorf("expected created keypair, got status %+v", cr.Status)
+	}
+	if cr.Status.PrivateKey.SecretName == "" {
+		t.Error("expected secret with generated private key")
+	}
+
+	name := cr.Annotations[keypair.ExternalNameAnnotation]
+	cloud, err := admin.Project(cr.Annotations[project.ProjectIDAnnotation])
+	if err != nil {
+		t.Fatalf("keypair not created in project of namespace: %s", err)
+	}
+	compute, _ := cloud.GetClient("compute")
+	if err := keypairs.Get(compute, name).Err; err != nil {
+		t.Errorf("keypair %s not found at openstack: %s", name, err)
+	}
+
+	if err := c.Delete(ctx, &cr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = keypairReconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("keypair not deleted: %s", err)
+	}
+
+	if err := c.Get(ctx, req.NamespacedName, &cr); client.IgnoreNotFound(err) != nil || err == nil {
+		t.Errorf("expected keypair resource to be gone, got %v", err)
+	}
+	if err := keypairs.Get(compute, name).Err; err == nil {
+		t.Errorf("keypair %s still exists at openstack", name)
+	}
+}
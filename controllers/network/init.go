@@ -31,7 +31,7 @@ import (
 func (r *Reconciler) init(ctx context.Context, cr kstypes.Network) error {
 	log := r.Log.WithValues("network", cr.Name)
 
-	osclient, err := r.OS.GetClient("network")
+	osclient, err := r.OS.For(&cr).GetClient("network")
 	if err != nil {
 		return err
 	}
@@ -77,6 +77,9 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.Network) error {
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
@@ -19,6 +19,7 @@ package network
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	coreV1 "k8s.io/api/core/v1"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
 	"github.com/kupenstack/kupenstack/pkg/k8s"
@@ -51,10 +53,19 @@ const (
 	msgFinalizerRemoveFailed  = "Failed to remove network finalizer at kubernetes."
 )
 
+// Requeue periods
+const (
+	// resource is checked again soon after it is created at openstack
+	createRequeuePeriod = time.Second
+
+	// status of resource is refreshed periodically
+	requeuePeriod = 3 * time.Second
+)
+
 // Reconciler reconciles a Network object
 type Reconciler struct {
 	client.Client
-	OS            *openstack.Client
+	OS            *openstack.Clouds
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
@@ -80,7 +91,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "CreateFailed",
 				"Network create failed. error: %s", err)
 		}
-		return ctrl.Result{RequeueAfter: 1000000000}, err
+		return openstack.Result(err, createRequeuePeriod)
 	}
 
 	// delete
@@ -90,7 +101,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "DeleteFailed",
 				"Network deletion failed. error: %s", err)
 		}
-		return ctrl.Result{}, err
+		return openstack.Result(err, 0)
 	}
 
 	if len(cr.Status.Usage.InstanceList) > 0 {
@@ -105,13 +116,14 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	log.Info("reconciled")
-	return ctrl.Result{RequeueAfter: 3000000000}, err
+	return openstack.Result(err, requeuePeriod)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kstypes.Network{}).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
 		Complete(r)
 }
 
@@ -0,0 +1,30 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package node implements node-reconciler for kupenstack controller.
+//
+// Working: keeps one osknode for every kubernetes node, with role, profile
+// and disabled state as set in KupenstackConfiguration. Osknodes are owned
+// by their kubernetes node, so removing a node garbage collects its osknode.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                MESSAGE
+//
+//  OskNodeCreated        Osknode %s created for node.
+//  OskNodeUpdated        Osknode %s updated as per KupenstackConfiguration.
+package node
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+	"github.com/kupenstack/kupenstack/pkg/k8s"
+)
+
+const (
+	// Nodes are resynced periodically, see Reconcile.
+	resyncPeriod = 60 * time.Second
+
+	// Nodes are checked again after failures, or while old osknode of a
+	// node is being deleted.
+	retryPeriod = 20 * time.Second
+)
+
+// Reconciler reconciles kubernetes Node objects into OpenstackNodes.
+type Reconciler struct {
+	client.Client
+
+	KupenstackConfiguration *oskops.Configuration
+
+	Scheme        *runtime.Scheme
+	Log           logr.Logger
+	EventRecorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=kupenstackconfigurations,verbs=get;list;watch
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("node", req.Name)
+
+	var node corev1.Node
+	err := r.Get(ctx, req.NamespacedName, &node)
+	if err != nil {
+		// Osknode of removed node is garbage collected through its owner reference.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !node.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// KupenstackConfiguration may come from a file which is not watched, so
+	// nodes are also resynced periodically to pick up its changes.
+	cfg, err := r.KupenstackConfiguration.Read(ctx)
+	if err != nil {
+		log.Error(err, "Failed to read KupenstackConfiguration.")
+		return ctrl.Result{RequeueAfter: retryPeriod}, err
+	}
+
+	var osknode clusterv1alpha1.OpenstackNode
+	err = r.Get(ctx, types.NamespacedName{Name: node.Name}, &osknode)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: retryPeriod}, err
+		}
+
+		osknode = oskops.NewOskNode(node, cfg)
+		err = ctrl.SetControllerReference(&node, &osknode, r.Scheme)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		err = r.Create(ctx, &osknode)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: retryPeriod}, err
+		}
+		r.Eventf(&node, corev1.EventTypeNormal, "OskNodeCreated", "Osknode %s created for node.", osknode.Name)
+		return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+	}
+
+	if !osknode.ObjectMeta.DeletionTimestamp.IsZero() {
+		// Osknode of a previous node with same name is still being
+		// deregistered from OpenStack. Create new one after it is gone.
+		return ctrl.Result{RequeueAfter: retryPeriod}, nil
+	}
+
+	changed := oskops.SyncOskNode(&osknode, node, cfg)
+
+	// Osknodes created before nodes owned them are adopted.
+	if !metav1.IsControlledBy(&osknode, &node) {
+		err = ctrl.SetControllerReference(&node, &osknode, r.Scheme)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		changed = true
+	}
+
+	if changed {
+		err = r.Update(ctx, &osknode)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: retryPeriod}, err
+		}
+		r.Eventf(&node, corev1.EventTypeNormal, "OskNodeUpdated",
+			"Osknode %s updated as per KupenstackConfiguration.", osknode.Name)
+	}
+
+	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only changes of
+// node labels are of interest, as node status is updated very frequently.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("node").
+		For(&corev1.Node{}, builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Owns(&clusterv1alpha1.OpenstackNode{}).
+		Watches(&source.Kind{Type: &clusterv1alpha1.KupenstackConfiguration{}},
+			handler.EnqueueRequestsFromMapFunc(r.allNodes)).
+		Complete(r)
+}
+
+// allNodes returns requests for all kubernetes nodes, as any of them may
+// be affected by a change in KupenstackConfiguration.
+func (r *Reconciler) allNodes(_ client.Object) []reconcile.Request {
+
+	var nodeList corev1.NodeList
+	err := r.List(context.Background(), &nodeList)
+	if err != nil {
+		r.Log.Error(err, "Failed to list nodes.")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: node.Name},
+		})
+	}
+	return requests
+}
+
+// Records kubernetes event for passed custom resources.
+func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
+	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
+		eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/node"
+	"github.com/kupenstack/kupenstack/oskops"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+)
+
+func TestNodeSyncsOskNode(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	clusterv1alpha1.AddToScheme(scheme)
+
+	cfg := &clusterv1alpha1.KupenstackConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterv1alpha1.KupenstackConfigurationName},
+		Spec: clusterv1alpha1.KupenstackConfigurationSpec{
+			DefaultProfile: clusterv1alpha1.OccpRef{Name: "default", Namespace: "kupenstack"},
+			NodeRules: []clusterv1alpha1.NodeRule{{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+				Type:     "compute",
+				Profile:  &clusterv1alpha1.OccpRef{Name: "gpu", Namespace: "kupenstack"},
+			}},
+		},
+	}
+	gpuNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1", Labels: map[string]string{
+		"pool": "gpu", "node-role.kubernetes.io/control-plane": ""}}}
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(cfg, gpuNode).Build()
+
+	r := &node.Reconciler{Client: c, KupenstackConfiguration: oskops.NewConfiguration(c, ""),
+		Log: ctrl.Log, Scheme: scheme, EventRecorder: record.NewFakeRecorder(100)}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "gpu-1"}}
+
+	_, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("osknode not created: %s", err)
+	}
+
+	var cr clusterv1alpha1.OpenstackNode
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.Annotations["node-role"] != "compute" || cr.Spec.Occp.Name != "gpu" {
+		t.Errorf("expected role and profile of matching rule, got role %q profile %v",
+			cr.Annotations["node-role"], cr.Spec.Occp)
+	}
+	if !metav1.IsControlledBy(&cr, gpuNode) {
+		t.Error("expected osknode owned by its node")
+	}
+
+	// node disabled by name and rule removed
+	if err := c.Get(ctx, types.NamespacedName{Name: cfg.Name}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Spec.NodeRules = nil
+	cfg.Spec.Nodes = []clusterv1alpha1.NodeConfiguration{{Name: "gpu-1", Disabled: true}}
+	if err := c.Update(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("osknode not updated: %s", err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.Annotations["node-role"] != "control" || cr.Spec.Occp.Name != "default" {
+		t.Errorf("expected role of control-plane node and default profile, got role %q profile %v",
+			cr.Annotations["node-role"], cr.Spec.Occp)
+	}
+	if cr.Annotations[osknode.DisabledAnnotation] != "true" {
+		t.Error("expected osknode to be disabled")
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package occp implements openstackcloudconfigurationprofile-reconciler for
+// kupenstack controller.
+//
+// Working: resolves inheritance chain of each OCCP and reports it in status,
+// along with digest of effective configuration and osknodes using the
+// profile. Profiles are resolved again whenever any profile or osknode
+// changes.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                MESSAGE
+//
+//  CycleDetected         cycle in profile inheritance: %s
+//  ParentNotFound        Parent profile %s not found.
+//  DigestMismatch        Failed to fetch profile %s: sha256 digest mismatch: %s
+//  RemoteSourceFailed    Failed to fetch profile %s: %s
+//  MergeConflict         cannot merge %s over %s: %s
+//  ResolveFailed         %s
+package occp
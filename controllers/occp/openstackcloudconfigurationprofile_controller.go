@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/k8s"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// Profiles with remote parents are resolved again with this period.
+const remoteProfileResyncPeriod = 5 * time.Minute
+
+// Reconciler reconciles a OpenStackCloudConfigurationProfile object
+type Reconciler struct {
+	client.Client
+
+	Scheme        *runtime.Scheme
+	Log           logr.Logger
+	EventRecorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstackcloudconfigurationprofiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstackcloudconfigurationprofiles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("occp", req.NamespacedName)
+
+	var cr clusterv1alpha1.OpenStackCloudConfigurationProfile
+	err := r.Get(ctx, req.NamespacedName, &cr)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	resolver := occp.Resolver{Client: r}
+	data, chain, err := resolver.Resolve(ctx, cr.Name, cr.Namespace)
+
+	condition := metav1.Condition{
+		Type:               clusterv1alpha1.ProfileResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            "Inheritance chain resolved.",
+		ObservedGeneration: cr.Generation,
+	}
+	digest := ""
+	var sourceErr *occp.SourceError
+	var mergeErr *utils.MergeError
+
+	switch {
+	case err == nil:
+		digest, err = configurationDigest(data)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	case errors.Is(err, occp.ErrDigestMismatch):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "DigestMismatch"
+		condition.Message = err.Error()
+	case errors.As(err, &sourceErr):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RemoteSourceFailed"
+		condition.Message = err.Error()
+	case errors.As(err, &mergeErr):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "MergeConflict"
+		condition.Message = err.Error()
+	case errors.Is(err, occp.ErrCycle):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CycleDetected"
+		condition.Message = err.Error()
+	case apierrors.IsNotFound(err):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ParentNotFound"
+		condition.Message = fmt.Sprintf("Parent profile %s not found.", chain[len(chain)-1])
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ResolveFailed"
+		condition.Message = err.Error()
+	}
+
+	if condition.Status != metav1.ConditionTrue {
+		r.Eventf(&cr, corev1.EventTypeWarning, condition.Reason, "%s", condition.Message)
+	}
+
+	nodes, err := r.nodesUsing(ctx, cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cr.Status.Chain = chain
+	cr.Status.EffectiveConfigurationDigest = digest
+	cr.Status.Nodes = nodes
+	cr.Status.ObservedGeneration = cr.Generation
+	meta.SetStatusCondition(&cr.Status.Conditions, condition)
+
+	err = r.Status().Update(ctx, &cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Remote parents are not watched, so profiles using them are resolved
+	// again periodically. Builtin profiles never change at runtime.
+	for _, profile := range chain {
+		if occp.IsUrl(profile) && !occp.IsBuiltin(profile) {
+			return ctrl.Result{RequeueAfter: remoteProfileResyncPeriod}, nil
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// configurationDigest returns SHA-256 digest of effective configuration.
+// Keys of maps are sorted on marshalling, so digest is stable.
+func configurationDigest(data map[string]interface{}) (string, error) {
+
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(buf)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// nodesUsing returns sorted names of osknodes using profile cr.
+func (r *Reconciler) nodesUsing(ctx context.Context, cr clusterv1alpha1.OpenStackCloudConfigurationProfile) ([]string, error) {
+
+	var oskNodeList clusterv1alpha1.OpenstackNodeList
+	err := r.List(ctx, &oskNodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	for _, osknode := range oskNodeList.Items {
+		if osknode.Spec.Occp.Name == cr.Name && osknode.Spec.Occp.Namespace == cr.Namespace {
+			nodes = append(nodes, osknode.Name)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. A change in any
+// profile may change chain of other profiles inheriting it, so all profiles
+// are resolved again.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&clusterv1alpha1.OpenStackCloudConfigurationProfile{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &clusterv1alpha1.OpenStackCloudConfigurationProfile{}},
+			handler.EnqueueRequestsFromMapFunc(r.allProfiles),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &clusterv1alpha1.OpenstackNode{}},
+			handler.EnqueueRequestsFromMapFunc(profileOfNode)).
+		Complete(r)
+}
+
+func (r *Reconciler) allProfiles(_ client.Object) []reconcile.Request {
+
+	var profileList clusterv1alpha1.OpenStackCloudConfigurationProfileList
+	err := r.List(context.Background(), &profileList)
+	if err != nil {
+		r.Log.Error(err, "Failed to list profiles.")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(profileList.Items))
+	for _, profile := range profileList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: profile.Name, Namespace: profile.Namespace},
+		})
+	}
+	return requests
+}
+
+func profileOfNode(obj client.Object) []reconcile.Request {
+
+	osknode, ok := obj.(*clusterv1alpha1.OpenstackNode)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      osknode.Spec.Occp.Name,
+			Namespace: osknode.Spec.Occp.Namespace,
+		},
+	}}
+}
+
+// Records kubernetes event for passed custom resources.
+func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
+	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
+		eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	occpcontroller "github.com/kupenstack/kupenstack/controllers/occp"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+func profile(name, from string) *clusterv1alpha1.OpenStackCloudConfigurationProfile {
+	return &clusterv1alpha1.OpenStackCloudConfigurationProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       clusterv1alpha1.OpenStackCloudConfigurationProfileSpec{From: from},
+	}
+}
+
+// profileChain returns n profiles p0..p(n-1), each inheriting the next one.
+func profileChain(n int) []client.Object {
+	var objs []client.Object
+	for i := 0; i < n; i++ {
+		from := ""
+		if i < n-1 {
+			from = fmt.Sprintf("p%d", i+1)
+		}
+		objs = append(objs, profile(fmt.Sprintf("p%d", i), from))
+	}
+	return objs
+}
+
+// reconcile reconciles profile name among objs and returns its
+// ProfileResolved condition and status chain.
+func reconcile(t *testing.T, name string, objs ...client.Object) (*metav1.Condition, []string) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	clusterv1alpha1.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r := &occpcontroller.Reconciler{Client: c, Scheme: scheme, Log: ctrl.Log,
+		EventRecorder: record.NewFakeRecorder(100)}
+
+	key := types.NamespacedName{Name: name, Namespace: "default"}
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cr clusterv1alpha1.OpenStackCloudConfigurationProfile
+	if err := c.Get(ctx, key, &cr); err != nil {
+		t.Fatal(err)
+	}
+	return meta.FindStatusCondition(cr.Status.Conditions, clusterv1alpha1.ProfileResolved), cr.Status.Chain
+}
+
+func TestProfileCycle(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		objs  []client.Object
+		chain string
+	}{
+		{
+			name:  "self",
+			objs:  []client.Object{profile("a", "a")},
+			chain: "a.default -> a.default",
+		},
+		{
+			name:  "two profiles",
+			objs:  []client.Object{profile("a", "b"), profile("b", "a")},
+			chain: "a.default -> b.default -> a.default",
+		},
+		{
+			name:  "cycle in parents",
+			objs:  []client.Object{profile("a", "b"), profile("b", "c"), profile("c", "b")},
+			chain: "a.default -> b.default -> c.default -> b.default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition, chain := reconcile(t, "a", test.objs...)
+			if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "CycleDetected" {
+				t.Fatalf("expected cycle detected, got %+v", condition)
+			}
+			if !strings.Contains(condition.Message, test.chain) {
+				t.Errorf("expected cycle %s in message, got %q", test.chain, condition.Message)
+			}
+			if got := strings.Join(chain, " -> "); got != test.chain {
+				t.Errorf("expected status chain %s, got %s", test.chain, got)
+			}
+		})
+	}
+}
+
+func TestProfileMaxDepth(t *testing.T) {
+
+	condition, chain := reconcile(t, "p0", profileChain(occp.MaxDepth)...)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("expected chain of %d profiles resolved, got %+v", occp.MaxDepth, condition)
+	}
+	if len(chain) != occp.MaxDepth {
+		t.Errorf("expected chain of %d profiles, got %v", occp.MaxDepth, chain)
+	}
+
+	condition, _ = reconcile(t, "p0", profileChain(occp.MaxDepth+1)...)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Message != occp.ErrTooDeep.Error() {
+		t.Errorf("expected chain of %d profiles to fail with %q, got %+v", occp.MaxDepth+1, occp.ErrTooDeep, condition)
+	}
+}
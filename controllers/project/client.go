@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"context"
+	"fmt"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// ProjectIDAnnotation is set on namespaced resources to id of project they
+// are created in at openstack.
+const ProjectIDAnnotation = "kupenstack.io/project-id"
+
+// ClientFor returns client of cloud of namespaced resource obj, scoped to
+// project of its namespace, so that quotas and usage of resources follow
+// namespaces. Resources `created` before projects were used, i.e. without
+// ProjectIDAnnotation, keep using admin project of cloud.
+func ClientFor(ctx context.Context, c client.Reader, clouds *openstack.Clouds, obj client.Object, created bool) (*openstack.Client, error) {
+
+	if id := obj.GetAnnotations()[ProjectIDAnnotation]; id != "" {
+		return clouds.For(obj).Project(id)
+	}
+	if created {
+		return clouds.For(obj), nil
+	}
+
+	var ns coreV1.Namespace
+	err := c.Get(ctx, types.NamespacedName{Name: obj.GetNamespace()}, &ns)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ns.Annotations[ExternalIDAnnotation]
+	if id == "" {
+		return nil, fmt.Errorf("project of namespace %s is not created yet", ns.Name)
+	}
+
+	cloud := obj.GetAnnotations()[openstack.CloudAnnotation]
+	if cloud != "" && cloud != ns.Annotations[openstack.CloudAnnotation] {
+		return nil, fmt.Errorf("cloud %s differs from cloud of project of namespace %s", cloud, ns.Name)
+	}
+
+	return clouds.For(&ns).Project(id)
+}
@@ -23,13 +23,14 @@ import (
 	coreV1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/kupenstack/kupenstack/pkg/openstack"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) delete(ctx context.Context, cr coreV1.Namespace) (bool, error) {
 	log := r.Log.WithValues("project", cr.Name)
 
-	osclient, err := r.OS.GetClient("identity")
+	osclient, err := r.OS.For(&cr).GetClient("identity")
 	if err != nil {
 		return false, err
 	}
@@ -39,7 +40,7 @@ func (r *Reconciler) delete(ctx context.Context, cr coreV1.Namespace) (bool, err
 	}
 
 	err = projects.Delete(osclient, cr.Annotations[ExternalIDAnnotation]).ExtractErr()
-	if ignoreNotFoundError(err) != nil {
+	if openstack.IgnoreNotFound(err) != nil {
 		log.Error(err, msgDeleteFailed)
 		return false, err
 	}
@@ -68,15 +69,3 @@ func getFinalizers(cr coreV1.Namespace) []string {
 	}
 	return finalizers
 }
-
-func ignoreNotFoundError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	if err.Error() == "Resource not found" {
-		return nil
-	} else {
-		return err
-	}
-}
@@ -16,7 +16,11 @@ limitations under the License.
 
 // Package project implements project-reconciler for kupenstack controller.
 //
-// Working: maps projects in openstack to namespaces in kubernetes.
+// Working: maps projects in openstack to namespaces in kubernetes. Admin
+// user is given member role on each project, so that namespaced resources
+// are created in project of their namespace with project-scoped tokens
+// (see ClientFor). Role is assigned once per project, which is recorded in
+// AccessAnnotation.
 //
 // Events
 //
@@ -25,6 +29,7 @@ limitations under the License.
 //
 //  KupenstackCreateFailed          Openstack project create failed. error: %s
 //  KupenstackCreated               Openstack project mapping created.
+//  KupenstackAccessFailed          Granting access to openstack project failed. error: %s
 //  KupenstackDeleteFailed          Openstack project deletion failed. error: %s
 //  KupenstackDeleted               Openstack project mapping deleted.
 package project
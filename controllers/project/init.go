@@ -18,8 +18,11 @@ package project
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
 	coreV1 "k8s.io/api/core/v1"
 	utilname "k8s.io/apiserver/pkg/storage/names"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -30,13 +33,13 @@ import (
 func (r *Reconciler) init(ctx context.Context, cr coreV1.Namespace) error {
 	log := r.Log.WithValues("project", cr.Name)
 
-	osclient, err := r.OS.GetClient("identity")
+	osclient, err := r.OS.For(&cr).GetClient("identity")
 	if err != nil {
 		return err
 	}
 
 	createOpts := projects.CreateOpts{
-		Name:        r.generateName(cr.Name),
+		Name:        r.generateName(osclient, cr.Name),
 		Description: "kubernetes-namespace=" + cr.Name,
 		Tags:        []string{"kupenstack"},
 	}
@@ -52,8 +55,14 @@ func (r *Reconciler) init(ctx context.Context, cr coreV1.Namespace) error {
 		cr.Annotations = make(map[string]string)
 	}
 
+	err = r.grantAccess(cr, createResult.ID)
+	if err != nil {
+		return err
+	}
+
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
 	cr.Annotations[ExternalIDAnnotation] = createResult.ID
+	cr.Annotations[AccessAnnotation] = createResult.ID
 
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
@@ -68,16 +77,45 @@ func (r *Reconciler) init(ctx context.Context, cr coreV1.Namespace) error {
 	return nil
 }
 
-// Appends passed string with a random string suffix.
-func (r *Reconciler) generateName(name string) string {
+// grantAccess assigns MemberRole on project to user of admin client, so
+// that namespaced resources can be created in project with project-scoped
+// tokens. Assigning role again has no effect. Granted project is recorded in
+// AccessAnnotation, so role is assigned once per project.
+func (r *Reconciler) grantAccess(cr coreV1.Namespace, projectID string) error {
 
-	generatedName := utilname.SimpleNameGenerator.GenerateName(name + "-")
+	osclient, err := r.OS.For(&cr).GetClient("identity")
+	if err != nil {
+		return err
+	}
 
-	osclient, err := r.OS.GetClient("identity")
+	userID, err := r.OS.For(&cr).UserID()
 	if err != nil {
-		return ""
+		return err
 	}
 
+	allPages, err := roles.List(osclient, roles.ListOpts{Name: MemberRole}).AllPages()
+	if err != nil {
+		return err
+	}
+	allRoles, err := roles.ExtractRoles(allPages)
+	if err != nil {
+		return err
+	}
+	if len(allRoles) == 0 {
+		return fmt.Errorf("role %s not found", MemberRole)
+	}
+
+	return roles.Assign(osclient, allRoles[0].ID, roles.AssignOpts{
+		UserID:    userID,
+		ProjectID: projectID,
+	}).ExtractErr()
+}
+
+// Appends passed string with a random string suffix.
+func (r *Reconciler) generateName(osclient *gophercloud.ServiceClient, name string) string {
+
+	generatedName := utilname.SimpleNameGenerator.GenerateName(name + "-")
+
 	allPages, err := projects.ListAvailable(osclient).AllPages()
 	if err != nil {
 		return ""
@@ -98,7 +136,7 @@ func (r *Reconciler) generateName(name string) string {
 		return generatedName
 	} else {
 		// try again with func recursion
-		return r.generateName(name)
+		return r.generateName(osclient, name)
 	}
 
 }
@@ -19,6 +19,7 @@ package project
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/kupenstack/kupenstack/pkg/k8s"
@@ -42,8 +43,15 @@ const (
 	// contains id of project/tenant at openstack.
 	ExternalIDAnnotation = "kupenstack.io/external-project-id"
 
+	// contains id of project admin user is given MemberRole on. Removing
+	// it grants the role again.
+	AccessAnnotation = "kupenstack.io/project-access"
+
 	Finalizer = "kupenstack.io/finalizer"
 
+	// role of admin user on every project, used by project-scoped clients.
+	MemberRole = "member"
+
 	kubernetesFinalizer = "kubernetes"
 )
 
@@ -56,10 +64,14 @@ const (
 	msgFinalizerRemoveFailed = "Failed to remove kupenstack finalizer at kubernetes namespace."
 )
 
+// Deletion of project waits for other finalizers of namespace with this
+// period.
+const deleteRequeuePeriod = 500 * time.Millisecond
+
 // Reconciler reconciles a KeyPair object
 type Reconciler struct {
 	client.Client
-	OS            *openstack.Client
+	OS            *openstack.Clouds
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
@@ -83,7 +95,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "KupenstackCreateFailed",
 				"Openstack project create failed. error: %s", err)
 		}
-		return ctrl.Result{}, err
+		return openstack.Result(err, 0)
 	}
 
 	if !cr.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -93,11 +105,25 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 				"Openstack project deletion failed. error: %s", err)
 		}
 		if !ok {
-			return ctrl.Result{RequeueAfter: 500000000}, err
+			return openstack.Result(err, deleteRequeuePeriod)
 		}
 		return ctrl.Result{}, err
 	}
 
+	// projects created before project-scoped clients were used
+	if cr.Annotations[AccessAnnotation] != cr.Annotations[ExternalIDAnnotation] {
+		err = r.grantAccess(cr, cr.Annotations[ExternalIDAnnotation])
+		if err == nil {
+			cr.Annotations[AccessAnnotation] = cr.Annotations[ExternalIDAnnotation]
+			err = r.Update(ctx, &cr)
+		}
+		if err != nil {
+			r.Eventf(&cr, coreV1.EventTypeWarning, "KupenstackAccessFailed",
+				"Granting access to openstack project failed. error: %s", err)
+			return openstack.Result(err, 0)
+		}
+	}
+
 	log.Info("reconciled")
 	return ctrl.Result{}, nil
 }
@@ -106,7 +132,8 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	c, err := controller.New("kupenstack-controller", mgr, controller.Options{
-		Reconciler: r,
+		Reconciler:  r,
+		RateLimiter: openstack.RateLimiter(),
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+// Member role is assigned to admin user when project is created, and only
+// again once AccessAnnotation is removed.
+func TestProjectAccess(t *testing.T) {
+	ctx := context.Background()
+
+	server := fake.NewServer()
+	defer server.Close()
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	clouds.SetDefault("fake")
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}},
+	).Build()
+	r := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+
+	identity, _ := admin.GetClient("identity")
+	userID, err := admin.UserID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// memberRoles returns member role assignments of admin user on project
+	// of namespace demo.
+	memberRoles := func() (string, []roles.RoleAssignment) {
+		var ns coreV1.Namespace
+		if err := c.Get(ctx, types.NamespacedName{Name: "demo"}, &ns); err != nil {
+			t.Fatal(err)
+		}
+		id := ns.Annotations[project.ExternalIDAnnotation]
+		allPages, err := roles.ListAssignments(identity, roles.ListAssignmentsOpts{
+			UserID: userID, ScopeProjectID: id}).AllPages()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignments, err := roles.ExtractRoleAssignments(allPages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id, assignments
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("project not created: %s", err)
+	}
+	id, assignments := memberRoles()
+	if id == "" || len(assignments) != 1 {
+		t.Fatalf("expected member role on project %q, got %+v", id, assignments)
+	}
+
+	err = roles.Unassign(identity, assignments[0].Role.ID, roles.UnassignOpts{UserID: userID, ProjectID: id}).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, assignments := memberRoles(); len(assignments) != 0 {
+		t.Errorf("expected role not assigned again for granted project, got %+v", assignments)
+	}
+
+	var ns coreV1.Namespace
+	if err := c.Get(ctx, req.NamespacedName, &ns); err != nil {
+		t.Fatal(err)
+	}
+	delete(ns.Annotations, project.AccessAnnotation)
+	if err := c.Update(ctx, &ns); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, assignments := memberRoles(); len(assignments) != 1 {
+		t.Errorf("expected member role assigned again, got %+v", assignments)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectmember
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/groups"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/users"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// delete unassigns roles of cr on project. User or group created by
+// kupenstack is deleted too, unless it has roles on other projects.
+func (r *Reconciler) delete(ctx context.Context, cr kstypes.ProjectMember) error {
+	log := r.Log.WithValues("projectmember", cr.Namespace+"/"+cr.Name)
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		return nil
+	}
+
+	// roles are on project of namespace, which is kept until its resources
+	// are gone
+	var ns coreV1.Namespace
+	err := r.Get(ctx, types.NamespacedName{Name: cr.Namespace}, &ns)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := r.OS.For(&ns).GetClient("identity")
+	if err != nil {
+		return err
+	}
+
+	for _, role := range cr.Status.Roles {
+		err = unassignRole(osclient, cr, cr.Status.ProjectID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cr.Status.Created {
+		opts := roles.ListAssignmentsOpts{}
+		if cr.Spec.User != "" {
+			opts.UserID = cr.Status.ID
+		} else {
+			opts.GroupID = cr.Status.ID
+		}
+		allPages, err := roles.ListAssignments(osclient, opts).AllPages()
+		if err != nil {
+			return err
+		}
+		assignments, err := roles.ExtractRoleAssignments(allPages)
+		if err != nil {
+			return err
+		}
+
+		if len(assignments) == 0 {
+			if cr.Spec.User != "" {
+				err = users.Delete(osclient, cr.Status.ID).ExtractErr()
+			} else {
+				err = groups.Delete(osclient, cr.Status.ID).ExtractErr()
+			}
+			if openstack.IgnoreNotFound(err) != nil {
+				log.Error(err, msgDeleteFailed)
+				return err
+			}
+			log.Info(msgDeleteSuccessful)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(&cr, Finalizer)
+	err = r.Update(ctx, &cr)
+	if err != nil {
+		log.Error(err, msgFinalizerRemoveFailed)
+		return err
+	}
+
+	r.Eventf(&cr, coreV1.EventTypeNormal, "Deleted", "Project member deleted.")
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package projectmember implements projectmember-reconciler for kupenstack controller.
+//
+// Working: links keystone user or group of each ProjectMember, creating it
+// when it does not exist, and assigns its roles on project of namespace of
+// ProjectMember. Only users and groups created for the same namespace are
+// linked, and only roles allowed by operator are assigned. Password of
+// created users is stored in a secret. Deleting
+// ProjectMember unassigns its roles, and deletes user or group created for
+// it once it has no roles left on any project.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                MESSAGE
+//
+//  InvalidSpec           Exactly one of user or group must be set.
+//  InvalidSpec           Role %s is not allowed, allowed roles are %s.
+//  LinkRefused           linking refused: %s.
+//  PasswordCreated       Password of user %s stored in secret %s.
+//  Created               Keystone %s %s created.
+//  Linked                Existing keystone %s %s linked.
+//  CreateFailed          Project member create failed. error: %s
+//  RolesAssigned         Roles %s of %s %s assigned on project.
+//  RolesFailed           Assigning roles on project failed. error: %s
+//  DeleteFailed          Project member deletion failed. error: %s
+//  Deleted               Project member deleted.
+package projectmember
@@ -0,0 +1,284 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectmember
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/groups"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/users"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// init links keystone user or group of spec to cr, creating it when it
+// does not exist. Existing users and groups are linked only when they were
+// created for namespace of cr, and never the user kupenstack itself is
+// authenticated as.
+func (r *Reconciler) init(ctx context.Context, cr *kstypes.ProjectMember, ns coreV1.Namespace) error {
+	log := r.Log.WithValues("projectmember", cr.Namespace+"/"+cr.Name)
+
+	cloud := r.OS.For(&ns)
+	osclient, err := cloud.GetClient("identity")
+	if err != nil {
+		return err
+	}
+
+	var id, description string
+	if cr.Spec.User != "" {
+		id, description, err = findUser(osclient, cr.Spec.User)
+	} else {
+		id, description, err = findGroup(osclient, cr.Spec.Group)
+	}
+	if err != nil {
+		return err
+	}
+
+	created := false
+	if id != "" {
+		err = linkable(cloud, *cr, id, description)
+		if err != nil {
+			return err
+		}
+		// created by cr in an earlier pass whose status update failed
+		created = description == descriptionOf(*cr)
+	} else if cr.Spec.User != "" {
+		password, err := r.password(ctx, cr, ns)
+		if err != nil {
+			return err
+		}
+		user, err := users.Create(osclient, users.CreateOpts{
+			Name:        cr.Spec.User,
+			DomainID:    domainID,
+			Password:    password,
+			Description: descriptionOf(*cr),
+		}).Extract()
+		if err != nil {
+			log.Error(err, msgCreateFailed)
+			return err
+		}
+		id, created = user.ID, true
+	} else {
+		group, err := groups.Create(osclient, groups.CreateOpts{
+			Name:        cr.Spec.Group,
+			DomainID:    domainID,
+			Description: descriptionOf(*cr),
+		}).Extract()
+		if err != nil {
+			log.Error(err, msgCreateFailed)
+			return err
+		}
+		id, created = group.ID, true
+	}
+	if created {
+		log.Info(msgCreateSuccessful)
+	}
+
+	// update status
+	cr.Status.ID = id
+	cr.Status.Created = created
+	err = r.Status().Update(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		controllerutil.AddFinalizer(cr, Finalizer)
+		err = r.Update(ctx, cr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if created {
+		r.Eventf(cr, coreV1.EventTypeNormal, "Created", "Keystone %s %s created.", kind(*cr), name(*cr))
+	} else {
+		r.Eventf(cr, coreV1.EventTypeNormal, "Linked", "Existing keystone %s %s linked.", kind(*cr), name(*cr))
+	}
+	return nil
+}
+
+// password returns password for user of cr. A new password is stored in a
+// secret, whose name is persisted in status before the user is created, so
+// that it is not lost when creating the user or a later status update fails.
+func (r *Reconciler) password(ctx context.Context, cr *kstypes.ProjectMember, ns coreV1.Namespace) (string, error) {
+	log := r.Log.WithValues("projectmember", cr.Namespace+"/"+cr.Name)
+
+	if cr.Status.PasswordSecret != "" {
+		var secret coreV1.Secret
+		err := r.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Status.PasswordSecret}, &secret)
+		if err == nil {
+			return string(secret.Data["password"]), nil
+		}
+		if !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return "", err
+	}
+
+	immutable := true
+	secret := coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    cr.Namespace,
+			GenerateName: cr.Name + "-",
+		},
+		Immutable: &immutable,
+		Data: map[string][]byte{
+			"username":    []byte(cr.Spec.User),
+			"password":    []byte(password),
+			"domain":      []byte("Default"),
+			"projectName": []byte(ns.Annotations[project.ExternalNameAnnotation]),
+		},
+	}
+
+	err = ctrl.SetControllerReference(cr, &secret, r.Scheme)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.Create(ctx, &secret)
+	if err != nil {
+		log.Error(err, msgCreateSecretFailed)
+		return "", err
+	}
+	r.Eventf(cr, coreV1.EventTypeNormal, "PasswordCreated",
+		"Password of user %s stored in secret %s.", cr.Spec.User, secret.Name)
+
+	cr.Status.PasswordSecret = secret.Name
+	err = r.Status().Update(ctx, cr)
+	if err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// linkable returns errLinkRefused unless existing user or group with `id`
+// and `description` may be linked to cr.
+func linkable(cloud *openstack.Client, cr kstypes.ProjectMember, id, description string) error {
+
+	if cr.Spec.User != "" {
+		operator, err := cloud.UserID()
+		if err != nil {
+			return err
+		}
+		if id == operator {
+			return fmt.Errorf("%w: user %s is used by kupenstack", errLinkRefused, cr.Spec.User)
+		}
+	}
+
+	if namespaceOf(description) != cr.Namespace {
+		return fmt.Errorf("%w: %s %s was not created for namespace %s",
+			errLinkRefused, kind(cr), name(cr), cr.Namespace)
+	}
+	return nil
+}
+
+// descriptionOf returns description of keystone user or group created for
+// cr, e.g. "kubernetes-namespace=team-a kubernetes-projectmember=alice".
+func descriptionOf(cr kstypes.ProjectMember) string {
+	return namespaceMarker + cr.Namespace + " " + memberMarker + cr.Name
+}
+
+// namespaceOf returns namespace in description of keystone user or group
+// created by kupenstack, or empty string for others.
+func namespaceOf(description string) string {
+	for _, field := range strings.Fields(description) {
+		if strings.HasPrefix(field, namespaceMarker) {
+			return strings.TrimPrefix(field, namespaceMarker)
+		}
+	}
+	return ""
+}
+
+// findUser returns ID and description of user `name`, or empty strings
+// when there is none.
+func findUser(osclient *gophercloud.ServiceClient, name string) (string, string, error) {
+
+	allPages, err := users.List(osclient, users.ListOpts{Name: name, DomainID: domainID}).AllPages()
+	if err != nil {
+		return "", "", err
+	}
+	allUsers, err := users.ExtractUsers(allPages)
+	if err != nil {
+		return "", "", err
+	}
+	if len(allUsers) == 0 {
+		return "", "", nil
+	}
+	return allUsers[0].ID, allUsers[0].Description, nil
+}
+
+// findGroup returns ID and description of group `name`, or empty strings
+// when there is none.
+func findGroup(osclient *gophercloud.ServiceClient, name string) (string, string, error) {
+
+	allPages, err := groups.List(osclient, groups.ListOpts{Name: name, DomainID: domainID}).AllPages()
+	if err != nil {
+		return "", "", err
+	}
+	allGroups, err := groups.ExtractGroups(allPages)
+	if err != nil {
+		return "", "", err
+	}
+	if len(allGroups) == 0 {
+		return "", "", nil
+	}
+	return allGroups[0].ID, allGroups[0].Description, nil
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// kind returns "user" or "group" as per spec of cr.
+func kind(cr kstypes.ProjectMember) string {
+	if cr.Spec.User != "" {
+		return "user"
+	}
+	return "group"
+}
+
+// name returns name of user or group in spec of cr.
+func name(cr kstypes.ProjectMember) string {
+	if cr.Spec.User != "" {
+		return cr.Spec.User
+	}
+	return cr.Spec.Group
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectmember
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/k8s"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+const (
+	Finalizer = "kupenstack.io/finalizer"
+
+	// Keystone domain users and groups are created in.
+	domainID = "default"
+
+	// Role assigned when spec has none.
+	defaultRole = "member"
+
+	// Description of keystone users and groups created by kupenstack
+	// holds namespace and name of their ProjectMember with these prefixes.
+	namespaceMarker = "kubernetes-namespace="
+	memberMarker    = "kubernetes-projectmember="
+)
+
+// DefaultAllowedRoles are roles ProjectMembers may assign when Reconciler
+// does not set AllowedRoles.
+var DefaultAllowedRoles = []string{"member", "reader"}
+
+// errLinkRefused is returned when existing keystone user or group of spec
+// may not be linked to a ProjectMember.
+var errLinkRefused = errors.New("linking refused")
+
+// Log messages
+const (
+	msgCreateFailed          = "Failed to create user or group at openstack."
+	msgCreateSuccessful      = "Successfully created user or group at openstack."
+	msgCreateSecretFailed    = "Failed to create k8s-secret for user password."
+	msgDeleteFailed          = "Failed to delete user or group at openstack."
+	msgDeleteSuccessful      = "Successfully deleted user or group at openstack."
+	msgFinalizerRemoveFailed = "Failed to remove projectmember finalizer at kubernetes."
+)
+
+// Project of namespace is waited for with this period.
+const projectRequeuePeriod = 5 * time.Second
+
+// Reconciler reconciles a ProjectMember object
+type Reconciler struct {
+	client.Client
+	OS            *openstack.Clouds
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	EventRecorder record.EventRecorder
+
+	// Roles ProjectMembers may assign on project of their namespace.
+	// Defaults to DefaultAllowedRoles.
+	AllowedRoles []string
+}
+
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectmembers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectmembers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectmembers/finalizers,verbs=update
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("projectmember", req.NamespacedName)
+
+	var cr kstypes.ProjectMember
+	err := r.Get(ctx, req.NamespacedName, &cr)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// delete
+	if !cr.ObjectMeta.DeletionTimestamp.IsZero() {
+		err = r.delete(ctx, cr)
+		if err != nil {
+			r.Eventf(&cr, coreV1.EventTypeWarning, "DeleteFailed",
+				"Project member deletion failed. error: %s", err)
+		}
+		return openstack.Result(err, 0)
+	}
+
+	if (cr.Spec.User == "") == (cr.Spec.Group == "") {
+		return ctrl.Result{}, r.invalid(ctx, &cr, "InvalidSpec",
+			"Exactly one of user or group must be set.")
+	}
+
+	allowed := r.allowedRoles()
+	for _, role := range desiredRoles(cr) {
+		if !utils.ContainsString(allowed, role) {
+			return ctrl.Result{}, r.invalid(ctx, &cr, "InvalidSpec",
+				"Role %s is not allowed, allowed roles are %s.", role, strings.Join(allowed, ","))
+		}
+	}
+
+	var ns coreV1.Namespace
+	err = r.Get(ctx, types.NamespacedName{Name: cr.Namespace}, &ns)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// wait for project of namespace
+	projectID := ns.Annotations[project.ExternalIDAnnotation]
+	if projectID == "" {
+		return ctrl.Result{RequeueAfter: projectRequeuePeriod}, nil
+	}
+
+	// create
+	if cr.Status.ID == "" {
+		err = r.init(ctx, &cr, ns)
+		if errors.Is(err, errLinkRefused) {
+			return ctrl.Result{}, r.invalid(ctx, &cr, "LinkRefused", "%s.", err)
+		}
+		if err != nil {
+			r.Eventf(&cr, coreV1.EventTypeWarning, "CreateFailed",
+				"Project member create failed. error: %s", err)
+			return openstack.Result(err, 0)
+		}
+	}
+
+	err = r.syncRoles(ctx, &cr, ns, projectID)
+	if err != nil {
+		r.Eventf(&cr, coreV1.EventTypeWarning, "RolesFailed",
+			"Assigning roles on project failed. error: %s", err)
+		return openstack.Result(err, 0)
+	}
+
+	log.Info("reconciled")
+	return ctrl.Result{}, nil
+}
+
+// invalid records a warning event with `reason` and marks cr not ready, as
+// it cannot be reconciled until its spec changes.
+func (r *Reconciler) invalid(ctx context.Context, cr *kstypes.ProjectMember, reason, messageFmt string, args ...interface{}) error {
+	message := fmt.Sprintf(messageFmt, args...)
+	r.Eventf(cr, coreV1.EventTypeWarning, reason, message)
+
+	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+		Type:    kstypes.ProjectMemberReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	return r.Status().Update(ctx, cr)
+}
+
+func (r *Reconciler) allowedRoles() []string {
+	if len(r.AllowedRoles) == 0 {
+		return DefaultAllowedRoles
+	}
+	return r.AllowedRoles
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kstypes.ProjectMember{}).
+		Owns(&coreV1.Secret{}).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
+		Complete(r)
+}
+
+// Records kubernetes event for passed custom resources.
+func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
+	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
+		eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectmember_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/users"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/controllers/projectmember"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+type env struct {
+	server  *fake.Server
+	admin   *openstack.Client
+	client  client.Client
+	members *projectmember.Reconciler
+}
+
+// setup returns reconciler of ProjectMembers with project of namespace
+// demo created, and objs added to kubernetes.
+func setup(t *testing.T, objs ...client.Object) env {
+	ctx := context.Background()
+
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	clouds.SetDefault("fake")
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	kstypes.AddToScheme(scheme)
+
+	objs = append(objs, &coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}})
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	projectReconciler := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+	_, err = projectReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}})
+	if err != nil {
+		t.Fatalf("project not created: %s", err)
+	}
+
+	return env{
+		server: server,
+		admin:  admin,
+		client: c,
+		members: &projectmember.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+			EventRecorder: record.NewFakeRecorder(100)},
+	}
+}
+
+// reconcile reconciles ProjectMember demo/name and returns it.
+func (e env) reconcile(t *testing.T, name string) kstypes.ProjectMember {
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: name}}
+
+	_, err := e.members.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("projectmember not reconciled: %s", err)
+	}
+
+	var cr kstypes.ProjectMember
+	if err := e.client.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	return cr
+}
+
+func (e env) createUser(t *testing.T, name, description string) string {
+	identity, _ := e.admin.GetClient("identity")
+	user, err := users.Create(identity, users.CreateOpts{Name: name, DomainID: fake.DomainID,
+		Password: "secret", Description: description}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func member(name string, spec kstypes.ProjectMemberSpec) *kstypes.ProjectMember {
+	return &kstypes.ProjectMember{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "demo"}, Spec: spec}
+}
+
+func TestProjectMemberLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e := setup(t, member("alice", kstypes.ProjectMemberSpec{User: "alice", Roles: []string{"reader"}}))
+
+	cr := e.reconcile(t, "alice")
+	if !cr.Status.Created || cr.Status.ID == "" || cr.Status.PasswordSecret == "" {
+		t.Fatalf("expected created user with password secret, got status %+v", cr.Status)
+	}
+	if !meta.IsStatusConditionTrue(cr.Status.Conditions, kstypes.ProjectMemberReady) {
+		t.Errorf("expected ready projectmember, got conditions %+v", cr.Status.Conditions)
+	}
+
+	// user logs in to project of namespace with password of secret
+	var secret coreV1.Secret
+	err := e.client.Get(ctx, types.NamespacedName{Namespace: "demo", Name: cr.Status.PasswordSecret}, &secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = openstack.New(&gophercloud.AuthOptions{
+		IdentityEndpoint: e.server.IdentityEndpoint(),
+		Username:         string(secret.Data["username"]),
+		Password:         string(secret.Data["password"]),
+		DomainName:       string(secret.Data["domain"]),
+		TenantID:         cr.Status.ProjectID,
+	})
+	if err != nil {
+		t.Errorf("user cannot log in to project: %s", err)
+	}
+
+	if err := e.client.Delete(ctx, &cr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.members.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "alice"}})
+	if err != nil {
+		t.Fatalf("projectmember not deleted: %s", err)
+	}
+	identity, _ := e.admin.GetClient("identity")
+	if err := users.Get(identity, cr.Status.ID).Err; err == nil {
+		t.Error("created user still exists at openstack")
+	}
+}
+
+func TestProjectMemberRefused(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		spec   kstypes.ProjectMemberSpec
+		user   string
+		reason string
+	}{
+		{
+			name:   "role not allowed",
+			spec:   kstypes.ProjectMemberSpec{User: "mallory", Roles: []string{"member", "admin"}},
+			reason: "InvalidSpec",
+		},
+		{
+			name:   "operator user",
+			spec:   kstypes.ProjectMemberSpec{User: fake.AdminUser},
+			reason: "LinkRefused",
+		},
+		{
+			name:   "user of other namespace",
+			spec:   kstypes.ProjectMemberSpec{User: "bob"},
+			user:   "kubernetes-namespace=other kubernetes-projectmember=bob",
+			reason: "LinkRefused",
+		},
+		{
+			name:   "user not created by kupenstack",
+			spec:   kstypes.ProjectMemberSpec{User: "bob"},
+			reason: "LinkRefused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := setup(t, member("refused", test.spec))
+			if test.spec.User == "bob" {
+				e.createUser(t, "bob", test.user)
+			}
+
+			cr := e.reconcile(t, "refused")
+			if cr.Status.ID != "" || len(cr.Status.Roles) != 0 {
+				t.Errorf("expected nothing linked or assigned, got status %+v", cr.Status)
+			}
+			ready := meta.FindStatusCondition(cr.Status.Conditions, kstypes.ProjectMemberReady)
+			if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != test.reason {
+				t.Errorf("expected Ready=False with reason %s, got %+v", test.reason, ready)
+			}
+		})
+	}
+}
+
+func TestProjectMemberAllowedRoles(t *testing.T) {
+	e := setup(t, member("ops", kstypes.ProjectMemberSpec{Group: "ops", Roles: []string{"admin"}}))
+	e.members.AllowedRoles = []string{"admin"}
+
+	cr := e.reconcile(t, "ops")
+	if !meta.IsStatusConditionTrue(cr.Status.Conditions, kstypes.ProjectMemberReady) {
+		t.Errorf("expected role allowed by operator to be assigned, got conditions %+v", cr.Status.Conditions)
+	}
+}
+
+// A user created in an earlier pass whose status update failed is linked
+// as created, with password of the secret recorded before it was created.
+func TestProjectMemberRecoversCreatedUser(t *testing.T) {
+	ctx := context.Background()
+
+	cr := member("carol", kstypes.ProjectMemberSpec{User: "carol"})
+	cr.Status.PasswordSecret = "carol-abcde"
+	secret := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "carol-abcde", Namespace: "demo"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	e := setup(t, cr, secret)
+	id := e.createUser(t, "carol", "kubernetes-namespace=demo kubernetes-projectmember=carol")
+
+	got := e.reconcile(t, "carol")
+	if got.Status.ID != id || !got.Status.Created {
+		t.Errorf("expected user %s linked as created, got status %+v", id, got.Status)
+	}
+	if got.Status.PasswordSecret != "carol-abcde" {
+		t.Errorf("expected password secret to be kept, got %s", got.Status.PasswordSecret)
+	}
+
+	var secrets coreV1.SecretList
+	if err := e.client.List(ctx, &secrets, client.InNamespace("demo")); err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets.Items) != 1 {
+		t.Errorf("expected no new password secret, got %d secrets", len(secrets.Items))
+	}
+}
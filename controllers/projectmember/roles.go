@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectmember
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// syncRoles assigns roles of spec on project of namespace to user or group
+// of cr, and unassigns roles removed from spec.
+func (r *Reconciler) syncRoles(ctx context.Context, cr *kstypes.ProjectMember, ns coreV1.Namespace, projectID string) error {
+
+	desired := desiredRoles(*cr)
+
+	// roles on a previous project went away with it
+	assigned := cr.Status.Roles
+	if cr.Status.ProjectID != projectID {
+		assigned = nil
+	}
+
+	if sameRoles(assigned, desired) && meta.IsStatusConditionTrue(cr.Status.Conditions, kstypes.ProjectMemberReady) {
+		return nil
+	}
+
+	osclient, err := r.OS.For(&ns).GetClient("identity")
+	if err != nil {
+		return err
+	}
+
+	err = r.assignRoles(osclient, *cr, projectID, assigned, desired)
+	if err != nil {
+		meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+			Type:    kstypes.ProjectMemberReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "AssignFailed",
+			Message: err.Error(),
+		})
+		r.Status().Update(ctx, cr)
+		return err
+	}
+
+	cr.Status.ProjectID = projectID
+	cr.Status.Roles = desired
+	meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+		Type:    kstypes.ProjectMemberReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RolesAssigned",
+		Message: "All roles are assigned on project.",
+	})
+	err = r.Status().Update(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	r.Eventf(cr, coreV1.EventTypeNormal, "RolesAssigned",
+		"Roles %s of %s %s assigned on project.", strings.Join(desired, ","), kind(*cr), name(*cr))
+	return nil
+}
+
+// assignRoles assigns roles in desired and not in assigned, and unassigns
+// roles in assigned and not in desired.
+func (r *Reconciler) assignRoles(osclient *gophercloud.ServiceClient, cr kstypes.ProjectMember,
+	projectID string, assigned, desired []string) error {
+
+	for _, role := range desired {
+		if utils.ContainsString(assigned, role) {
+			continue
+		}
+		id, err := roleID(osclient, role)
+		if err != nil {
+			return err
+		}
+		opts := roles.AssignOpts{ProjectID: projectID}
+		if cr.Spec.User != "" {
+			opts.UserID = cr.Status.ID
+		} else {
+			opts.GroupID = cr.Status.ID
+		}
+		err = roles.Assign(osclient, id, opts).ExtractErr()
+		if err != nil {
+			return fmt.Errorf("cannot assign role %s: %w", role, err)
+		}
+	}
+
+	for _, role := range assigned {
+		if utils.ContainsString(desired, role) {
+			continue
+		}
+		err := unassignRole(osclient, cr, projectID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unassignRole(osclient *gophercloud.ServiceClient, cr kstypes.ProjectMember, projectID, role string) error {
+
+	id, err := roleID(osclient, role)
+	if err != nil {
+		return err
+	}
+	opts := roles.UnassignOpts{ProjectID: projectID}
+	if cr.Spec.User != "" {
+		opts.UserID = cr.Status.ID
+	} else {
+		opts.GroupID = cr.Status.ID
+	}
+	err = roles.Unassign(osclient, id, opts).ExtractErr()
+	if openstack.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("cannot unassign role %s: %w", role, err)
+	}
+	return nil
+}
+
+// roleID returns ID of role `name`.
+func roleID(osclient *gophercloud.ServiceClient, name string) (string, error) {
+
+	allPages, err := roles.List(osclient, roles.ListOpts{Name: name}).AllPages()
+	if err != nil {
+		return "", err
+	}
+	allRoles, err := roles.ExtractRoles(allPages)
+	if err != nil {
+		return "", err
+	}
+	if len(allRoles) == 0 {
+		return "", fmt.Errorf("role %s not found", name)
+	}
+	return allRoles[0].ID, nil
+}
+
+// desiredRoles returns roles of spec of cr, or defaultRole when it has none.
+func desiredRoles(cr kstypes.ProjectMember) []string {
+	if len(cr.Spec.Roles) == 0 {
+		return []string{defaultRole}
+	}
+	return cr.Spec.Roles
+}
+
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, role := range a {
+		if !utils.ContainsString(b, role) {
+			return false
+		}
+	}
+	return true
+}
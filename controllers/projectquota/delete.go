@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectquota
+
+import (
+	"context"
+
+	storagequotas "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	computequotas "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	coreV1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// delete resets quotas of project of namespace to defaults of cloud.
+func (r *Reconciler) delete(ctx context.Context, cr kstypes.ProjectQuota, ns coreV1.Namespace) error {
+	log := r.Log.WithValues("projectquota", cr.Namespace+"/"+cr.Name)
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		return nil
+	}
+
+	// quotas may be applied without status recording it
+	projectID := ns.Annotations[project.ExternalIDAnnotation]
+	if projectID == "" {
+		projectID = cr.Status.ProjectID
+	}
+
+	if projectID != "" {
+		cloud := r.OS.For(&ns)
+
+		err := resetCompute(cloud, projectID)
+		if err == nil {
+			err = resetNetwork(cloud, projectID)
+		}
+		if err == nil {
+			err = resetStorage(cloud, projectID)
+		}
+		if err != nil {
+			log.Error(err, msgResetFailed)
+			return err
+		}
+		log.Info(msgResetSuccessful)
+	}
+
+	controllerutil.RemoveFinalizer(&cr, Finalizer)
+	err := r.Update(ctx, &cr)
+	if err != nil {
+		log.Error(err, msgFinalizerRemoveFailed)
+		return err
+	}
+
+	r.Eventf(&cr, coreV1.EventTypeNormal, "Deleted", "Project quota deleted, quotas reset to defaults.")
+	return nil
+}
+
+// resetCompute resets nova quotas of project.
+func resetCompute(cloud *openstack.Client, projectID string) error {
+
+	osclient, err := cloud.GetClient("compute")
+	if err != nil {
+		return err
+	}
+	err = computequotas.Delete(osclient, projectID).Err
+	return openstack.IgnoreNotFound(err)
+}
+
+// resetNetwork resets neutron quotas of project.
+func resetNetwork(cloud *openstack.Client, projectID string) error {
+
+	// quotas package of networking has no delete request
+	osclient, err := cloud.GetClient("network")
+	if err != nil {
+		return err
+	}
+	_, err = osclient.Delete(osclient.ServiceURL("quotas", projectID), nil)
+	return openstack.IgnoreNotFound(err)
+}
+
+// resetStorage resets block storage quotas of project, if cloud has block
+// storage service.
+func resetStorage(cloud *openstack.Client, projectID string) error {
+
+	osclient, err := cloud.GetClient("volume")
+	if openstack.IsEndpointNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = storagequotas.Delete(osclient, projectID).ExtractErr()
+	return openstack.IgnoreNotFound(err)
+}
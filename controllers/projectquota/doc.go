@@ -0,0 +1,35 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package projectquota implements projectquota-reconciler for kupenstack controller.
+//
+// Working: applies compute, network and storage quotas of ProjectQuota on
+// project of its namespace through nova, neutron and cinder quota apis,
+// and reports limits and usage of project in status every minute. Only the
+// oldest ProjectQuota of a namespace is applied. Deleting ProjectQuota
+// resets quotas of project to defaults of cloud.
+//
+// Events
+//
+// The following events are thrown by reconciler:
+//  REASON                MESSAGE
+//
+//  Applied               Project quota applied.
+//  ApplyFailed           Applying project quota failed. error: %s
+//  Duplicate             Namespace already has a project quota, this one is ignored.
+//  DeleteFailed          Project quota deletion failed. error: %s
+//  Deleted               Project quota deleted, quotas reset to defaults.
+package projectquota
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectquota
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/k8s"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+const (
+	Finalizer = "kupenstack.io/finalizer"
+)
+
+// Log messages
+const (
+	msgApplyFailed           = "Failed to apply quotas at openstack."
+	msgApplySuccessful       = "Successfully applied quotas at openstack."
+	msgResetFailed           = "Failed to reset quotas at openstack."
+	msgResetSuccessful       = "Successfully reset quotas at openstack."
+	msgStatusUpdateFailed    = "Failed to update projectquota status at kubernetes."
+	msgFinalizerRemoveFailed = "Failed to remove projectquota finalizer at kubernetes."
+)
+
+// Requeue periods
+const (
+	// project of namespace is waited for
+	projectRequeuePeriod = 5 * time.Second
+
+	// usage in status is refreshed periodically
+	usageRequeuePeriod = 60 * time.Second
+)
+
+// Reconciler reconciles a ProjectQuota object
+type Reconciler struct {
+	client.Client
+	OS            *openstack.Clouds
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	EventRecorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectquotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectquotas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kupenstack.io,resources=projectquotas/finalizers,verbs=update
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("projectquota", req.NamespacedName)
+
+	var cr kstypes.ProjectQuota
+	err := r.Get(ctx, req.NamespacedName, &cr)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var ns coreV1.Namespace
+	err = r.Get(ctx, types.NamespacedName{Name: cr.Namespace}, &ns)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// delete
+	if !cr.ObjectMeta.DeletionTimestamp.IsZero() {
+		err = r.delete(ctx, cr, ns)
+		if err != nil {
+			r.Eventf(&cr, coreV1.EventTypeWarning, "DeleteFailed",
+				"Project quota deletion failed. error: %s", err)
+		}
+		return openstack.Result(err, 0)
+	}
+
+	// only oldest quota of namespace is applied
+	active, err := r.isActive(ctx, cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !active {
+		if !meta.IsStatusConditionPresentAndEqual(cr.Status.Conditions, kstypes.ProjectQuotaReady, metav1.ConditionFalse) {
+			r.Eventf(&cr, coreV1.EventTypeWarning, "Duplicate",
+				"Namespace already has a project quota, this one is ignored.")
+		}
+		meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+			Type:    kstypes.ProjectQuotaReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Duplicate",
+			Message: "Namespace already has a project quota, this one is ignored.",
+		})
+		err = r.Status().Update(ctx, &cr)
+		return openstack.Result(err, usageRequeuePeriod)
+	}
+
+	// wait for project of namespace
+	projectID := ns.Annotations[project.ExternalIDAnnotation]
+	if projectID == "" {
+		return ctrl.Result{RequeueAfter: projectRequeuePeriod}, nil
+	}
+
+	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
+		controllerutil.AddFinalizer(&cr, Finalizer)
+		err = r.Update(ctx, &cr)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// apply
+	if cr.Status.ObservedGeneration != cr.Generation || cr.Status.ProjectID != projectID ||
+		!meta.IsStatusConditionTrue(cr.Status.Conditions, kstypes.ProjectQuotaReady) {
+
+		condition, err := r.apply(cr, ns, projectID)
+		if err != nil {
+			log.Error(err, msgApplyFailed)
+			r.Eventf(&cr, coreV1.EventTypeWarning, "ApplyFailed",
+				"Applying project quota failed. error: %s", err)
+			meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+				Type:    kstypes.ProjectQuotaReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ApplyFailed",
+				Message: err.Error(),
+			})
+			if err := r.Status().Update(ctx, &cr); err != nil {
+				log.Error(err, msgStatusUpdateFailed)
+			}
+			return openstack.Result(err, 0)
+		}
+		log.Info(msgApplySuccessful)
+		r.Eventf(&cr, coreV1.EventTypeNormal, "Applied", "Project quota applied.")
+
+		// persisted before reading usage, which may fail on its own
+		cr.Status.ProjectID = projectID
+		cr.Status.ObservedGeneration = cr.Generation
+		cr.Status.Applied = cr.Spec.DeepCopy()
+		meta.SetStatusCondition(&cr.Status.Conditions, condition)
+		err = r.Status().Update(ctx, &cr)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// usage
+	err = r.updateUsage(&cr, ns, projectID)
+	if err != nil {
+		return openstack.Result(err, 0)
+	}
+
+	err = r.Status().Update(ctx, &cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("reconciled")
+	return ctrl.Result{RequeueAfter: usageRequeuePeriod}, nil
+}
+
+// isActive returns whether cr is the oldest quota of its namespace.
+func (r *Reconciler) isActive(ctx context.Context, cr kstypes.ProjectQuota) (bool, error) {
+
+	var list kstypes.ProjectQuotaList
+	err := r.List(ctx, &list, client.InNamespace(cr.Namespace))
+	if err != nil {
+		return false, err
+	}
+
+	var quotas []kstypes.ProjectQuota
+	for _, quota := range list.Items {
+		if quota.DeletionTimestamp.IsZero() {
+			quotas = append(quotas, quota)
+		}
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].CreationTimestamp.Equal(&quotas[j].CreationTimestamp) {
+			return quotas[i].Name < quotas[j].Name
+		}
+		return quotas[i].CreationTimestamp.Before(&quotas[j].CreationTimestamp)
+	})
+
+	return len(quotas) == 0 || quotas[0].Name == cr.Name, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kstypes.ProjectQuota{}).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
+		Complete(r)
+}
+
+// Records kubernetes event for passed custom resources.
+func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt string, args ...interface{}) error {
+	return k8s.RecordEventf(r.EventRecorder, cr, r.Scheme,
+		eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectquota_test
+
+import (
+	"context"
+	"testing"
+
+	computequotas "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/controllers/projectquota"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// setup returns client with objs and namespace demo whose project is
+// created, reconciler of ProjectQuotas and admin client of cloud.
+func setup(t *testing.T, objs ...client.Object) (client.Client, *projectquota.Reconciler, *openstack.Client) {
+	ctx := context.Background()
+
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	clouds.SetDefault("fake")
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	kstypes.AddToScheme(scheme)
+
+	objs = append(objs, &coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}})
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	projectReconciler := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+	_, err = projectReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}})
+	if err != nil {
+		t.Fatalf("project not created: %s", err)
+	}
+
+	return c, &projectquota.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}, admin
+}
+
+func projectID(t *testing.T, c client.Client) string {
+	var ns coreV1.Namespace
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "demo"}, &ns); err != nil {
+		t.Fatal(err)
+	}
+	return ns.Annotations[project.ExternalIDAnnotation]
+}
+
+func computeQuota(t *testing.T, admin *openstack.Client, projectID string) computequotas.QuotaSet {
+	compute, _ := admin.GetClient("compute")
+	quota, err := computequotas.Get(compute, projectID).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *quota
+}
+
+func TestProjectQuotaLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	c, r, admin := setup(t, &kstypes.ProjectQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "demo"},
+		Spec: kstypes.ProjectQuotaSpec{
+			Compute: &kstypes.ComputeQuota{Instances: int32Ptr(3)},
+			Network: &kstypes.NetworkQuota{Networks: int32Ptr(2)},
+			Storage: &kstypes.StorageQuota{Volumes: int32Ptr(5)},
+		},
+	})
+	id := projectID(t, c)
+	defaults := computeQuota(t, admin, id)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "quota"}}
+	_, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("quota not applied: %s", err)
+	}
+
+	var cr kstypes.ProjectQuota
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if cr.Status.ProjectID != id || cr.Status.Compute["instances"].Limit != 3 || cr.Status.Network["networks"].Limit != 2 {
+		t.Errorf("expected applied quotas in status, got %+v", cr.Status)
+	}
+	// fake cloud has no block storage
+	ready := meta.FindStatusCondition(cr.Status.Conditions, kstypes.ProjectQuotaReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue || ready.Reason != "StorageUnavailable" {
+		t.Errorf("expected ready quota without storage, got %+v", ready)
+	}
+	if got := computeQuota(t, admin, id).Instances; got != 3 {
+		t.Errorf("expected instances quota 3 at openstack, got %d", got)
+	}
+
+	if err := c.Delete(ctx, &cr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("quota not deleted: %s", err)
+	}
+	if got := computeQuota(t, admin, id).Instances; got != defaults.Instances {
+		t.Errorf("expected instances quota reset to %d, got %d", defaults.Instances, got)
+	}
+}
+
+// Quotas applied without status recording it, e.g. as a later step failed,
+// are reset on deletion too.
+func TestProjectQuotaResetWithoutStatus(t *testing.T) {
+	ctx := context.Background()
+
+	c, r, admin := setup(t, &kstypes.ProjectQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "demo",
+			Finalizers: []string{projectquota.Finalizer}},
+		Spec: kstypes.ProjectQuotaSpec{Compute: &kstypes.ComputeQuota{Instances: int32Ptr(3)}},
+	})
+	id := projectID(t, c)
+	defaults := computeQuota(t, admin, id)
+
+	compute, _ := admin.GetClient("compute")
+	three := 3
+	_, err := computequotas.Update(compute, id, computequotas.UpdateOpts{Instances: &three}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cr kstypes.ProjectQuota
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "quota"}}
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, &cr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("quota not deleted: %s", err)
+	}
+	if got := computeQuota(t, admin, id).Instances; got != defaults.Instances {
+		t.Errorf("expected instances quota reset to %d, got %d", defaults.Instances, got)
+	}
+}
+
+// Quotas removed from spec, alone or with their section, are reset while
+// remaining ones stay applied.
+func TestProjectQuotaRemoved(t *testing.T) {
+	ctx := context.Background()
+
+	c, r, admin := setup(t, &kstypes.ProjectQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "demo", Generation: 1},
+		Spec: kstypes.ProjectQuotaSpec{
+			Compute: &kstypes.ComputeQuota{Instances: int32Ptr(3), Cores: int32Ptr(6)},
+			Network: &kstypes.NetworkQuota{Networks: int32Ptr(2)},
+		},
+	})
+	id := projectID(t, c)
+	defaults := computeQuota(t, admin, id)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "quota"}}
+	_, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("quota not applied: %s", err)
+	}
+
+	var cr kstypes.ProjectQuota
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	if got := computeQuota(t, admin, id).Cores; got != 6 {
+		t.Fatalf("expected cores quota 6 at openstack, got %d", got)
+	}
+
+	cr.Spec.Compute.Cores = nil
+	cr.Spec.Network = nil
+	cr.Generation = 2
+	if err := c.Update(ctx, &cr); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("quota not applied: %s", err)
+	}
+
+	quota := computeQuota(t, admin, id)
+	if quota.Cores != defaults.Cores || quota.Instances != 3 {
+		t.Errorf("expected cores quota reset to %d and instances kept 3, got %d and %d",
+			defaults.Cores, quota.Cores, quota.Instances)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+		t.Fatal(err)
+	}
+	// default of fake cloud
+	if got := cr.Status.Network["networks"].Limit; got != 100 {
+		t.Errorf("expected networks quota reset to 100, got %d", got)
+	}
+	if cr.Status.Applied == nil || cr.Status.Applied.Network != nil || cr.Status.Applied.Compute.Cores != nil {
+		t.Errorf("expected applied quotas without cores and network, got %+v", cr.Status.Applied)
+	}
+}
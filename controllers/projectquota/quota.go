@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projectquota
+
+import (
+	"encoding/json"
+	"fmt"
+
+	storagequotas "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	computequotas "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	networkquotas "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// apply updates quotas of project set in spec of cr, and returns condition
+// reporting result. Quotas of a service are first reset when any of them
+// was removed from spec since it was last applied.
+func (r *Reconciler) apply(cr kstypes.ProjectQuota, ns coreV1.Namespace, projectID string) (metav1.Condition, error) {
+
+	condition := metav1.Condition{
+		Type:    kstypes.ProjectQuotaReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: "Quotas are applied on project.",
+	}
+	cloud := r.OS.For(&ns)
+	// quotas applied on another project are not for this one to reset
+	applied := cr.Status.Applied
+	if applied == nil || cr.Status.ProjectID != projectID {
+		applied = &kstypes.ProjectQuotaSpec{}
+	}
+
+	if removed(applied.Compute, cr.Spec.Compute) {
+		err := resetCompute(cloud, projectID)
+		if err != nil {
+			return condition, fmt.Errorf("cannot reset compute quotas: %w", err)
+		}
+	}
+	if q := cr.Spec.Compute; q != nil {
+		osclient, err := cloud.GetClient("compute")
+		if err != nil {
+			return condition, err
+		}
+		_, err = computequotas.Update(osclient, projectID, computequotas.UpdateOpts{
+			Instances: intPtr(q.Instances),
+			Cores:     intPtr(q.Cores),
+			RAM:       intPtr(q.RAMMB),
+		}).Extract()
+		if err != nil {
+			return condition, fmt.Errorf("cannot update compute quotas: %w", err)
+		}
+	}
+
+	if removed(applied.Network, cr.Spec.Network) {
+		err := resetNetwork(cloud, projectID)
+		if err != nil {
+			return condition, fmt.Errorf("cannot reset network quotas: %w", err)
+		}
+	}
+	if q := cr.Spec.Network; q != nil {
+		osclient, err := cloud.GetClient("network")
+		if err != nil {
+			return condition, err
+		}
+		_, err = networkquotas.Update(osclient, projectID, networkquotas.UpdateOpts{
+			Network:       intPtr(q.Networks),
+			Subnet:        intPtr(q.Subnets),
+			Port:          intPtr(q.Ports),
+			Router:        intPtr(q.Routers),
+			FloatingIP:    intPtr(q.FloatingIPs),
+			SecurityGroup: intPtr(q.SecurityGroups),
+		}).Extract()
+		if err != nil {
+			return condition, fmt.Errorf("cannot update network quotas: %w", err)
+		}
+	}
+
+	if removed(applied.Storage, cr.Spec.Storage) {
+		err := resetStorage(cloud, projectID)
+		if err != nil {
+			return condition, fmt.Errorf("cannot reset storage quotas: %w", err)
+		}
+	}
+	if q := cr.Spec.Storage; q != nil {
+		osclient, err := cloud.GetClient("volume")
+		if openstack.IsEndpointNotFound(err) {
+			condition.Reason = "StorageUnavailable"
+			condition.Message = "Quotas are applied on project, except storage as cloud has no block storage service."
+			return condition, nil
+		}
+		if err != nil {
+			return condition, err
+		}
+		_, err = storagequotas.Update(osclient, projectID, storagequotas.UpdateOpts{
+			Volumes:   intPtr(q.Volumes),
+			Snapshots: intPtr(q.Snapshots),
+			Gigabytes: intPtr(q.Gigabytes),
+		}).Extract()
+		if err != nil {
+			return condition, fmt.Errorf("cannot update storage quotas: %w", err)
+		}
+	}
+
+	return condition, nil
+}
+
+// removed reports whether any quota set in `applied` is not set in `desired`,
+// both quotas of one service.
+func removed(applied, desired interface{}) bool {
+
+	var appliedQuotas, desiredQuotas map[string]interface{}
+	buf, _ := json.Marshal(applied)
+	json.Unmarshal(buf, &appliedQuotas)
+	buf, _ = json.Marshal(desired)
+	json.Unmarshal(buf, &desiredQuotas)
+
+	for name := range appliedQuotas {
+		if _, ok := desiredQuotas[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// updateUsage reads limits and usage of project into status of cr. Storage
+// is reported only when cloud has block storage service.
+func (r *Reconciler) updateUsage(cr *kstypes.ProjectQuota, ns coreV1.Namespace, projectID string) error {
+
+	cloud := r.OS.For(&ns)
+
+	osclient, err := cloud.GetClient("compute")
+	if err != nil {
+		return err
+	}
+	compute, err := computequotas.GetDetail(osclient, projectID).Extract()
+	if err != nil {
+		return err
+	}
+	cr.Status.Compute = map[string]kstypes.QuotaUsage{
+		"instances": computeUsage(compute.Instances),
+		"cores":     computeUsage(compute.Cores),
+		"ramMB":     computeUsage(compute.RAM),
+	}
+
+	osclient, err = cloud.GetClient("network")
+	if err != nil {
+		return err
+	}
+	network, err := networkquotas.GetDetail(osclient, projectID).Extract()
+	if err != nil {
+		return err
+	}
+	cr.Status.Network = map[string]kstypes.QuotaUsage{
+		"networks":       networkUsage(network.Network),
+		"subnets":        networkUsage(network.Subnet),
+		"ports":          networkUsage(network.Port),
+		"routers":        networkUsage(network.Router),
+		"floatingIPs":    networkUsage(network.FloatingIP),
+		"securityGroups": networkUsage(network.SecurityGroup),
+	}
+
+	cr.Status.Storage = nil
+	osclient, err = cloud.GetClient("volume")
+	if openstack.IsEndpointNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	storage, err := storagequotas.GetUsage(osclient, projectID).Extract()
+	if err != nil {
+		return err
+	}
+	cr.Status.Storage = map[string]kstypes.QuotaUsage{
+		"volumes":   storageUsage(storage.Volumes),
+		"snapshots": storageUsage(storage.Snapshots),
+		"gigabytes": storageUsage(storage.Gigabytes),
+	}
+
+	return nil
+}
+
+func computeUsage(q computequotas.QuotaDetail) kstypes.QuotaUsage {
+	return kstypes.QuotaUsage{Limit: int32(q.Limit), InUse: int32(q.InUse)}
+}
+
+func networkUsage(q networkquotas.QuotaDetail) kstypes.QuotaUsage {
+	return kstypes.QuotaUsage{Limit: int32(q.Limit), InUse: int32(q.Used)}
+}
+
+func storageUsage(q storagequotas.QuotaUsage) kstypes.QuotaUsage {
+	return kstypes.QuotaUsage{Limit: int32(q.Limit), InUse: int32(q.InUse)}
+}
+
+func intPtr(i *int32) *int {
+	if i == nil {
+		return nil
+	}
+	v := int(*i)
+	return &v
+}
@@ -28,4 +28,8 @@ limitations under the License.
 //  CreateFailed          Virtual Machine create failed. error: %s
 //  DeleteFailed          Vitual Machine deletion failed. error: %s
 //  Deleted               Virtual Machine deleted.
+//  Starting              Starting Virtual Machine.
+//  Stopping              Stopping Virtual Machine.
+//  PowerFailed           Changing power state of Virtual Machine failed. error: %s
+//  ConsoleFailed         Creating console of Virtual Machine failed. error: %s
 package vm
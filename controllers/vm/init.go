@@ -29,13 +29,19 @@ import (
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
 	kpctrl "github.com/kupenstack/kupenstack/controllers/keypair"
+	"github.com/kupenstack/kupenstack/controllers/project"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) init(ctx context.Context, cr kstypes.VirtualMachine) error {
 	log := r.Log.WithValues("virtual-machine", cr.Namespace+"/"+cr.Name)
 
-	osclient, err := r.OS.GetClient("compute")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, false)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("compute")
 	if err != nil {
 		return err
 	}
@@ -120,7 +126,11 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.VirtualMachine) error
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
+	cr.Annotations[project.ProjectIDAnnotation] = cloud.ProjectID()
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
 	}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/remoteconsoles"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// Compute api microversion supporting remote consoles.
+const consoleMicroversion = "2.6"
+
+// reconcilePower starts or stops virtual machine as per spec.running.
+func (r *Reconciler) reconcilePower(ctx context.Context, cr kstypes.VirtualMachine) error {
+
+	if cr.Spec.Running == nil {
+		return nil
+	}
+
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, true)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("compute")
+	if err != nil {
+		return err
+	}
+
+	server, err := servers.Get(osclient, cr.Status.ID).Extract()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *cr.Spec.Running && server.Status == "SHUTOFF":
+		err = startstop.Start(osclient, cr.Status.ID).ExtractErr()
+		if openstack.IsConflict(err) {
+			// server is changing state, power is reconciled again later
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.Eventf(&cr, coreV1.EventTypeNormal, "Starting", "Starting Virtual Machine.")
+	case !*cr.Spec.Running && server.Status == "ACTIVE":
+		err = startstop.Stop(osclient, cr.Status.ID).ExtractErr()
+		if openstack.IsConflict(err) {
+			// server is changing state, power is reconciled again later
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.Eventf(&cr, coreV1.EventTypeNormal, "Stopping", "Stopping Virtual Machine.")
+	}
+
+	return nil
+}
+
+// reconcileConsole creates a noVNC console of virtual machine when one is
+// requested with ConsoleRequestAnnotation. Url of console contains its
+// token, so it is stored in secret consoleSecretName(cr) and only that
+// secret is referenced in status of cr.
+func (r *Reconciler) reconcileConsole(ctx context.Context, cr *kstypes.VirtualMachine) error {
+
+	request := cr.Annotations[kstypes.ConsoleRequestAnnotation]
+	if request == "" || (cr.Status.Console != nil && cr.Status.Console.Request == request) {
+		return nil
+	}
+
+	cloud, err := project.ClientFor(ctx, r, r.OS, cr, true)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("compute")
+	if err != nil {
+		return err
+	}
+	client := *osclient
+	client.Microversion = consoleMicroversion
+
+	createOpts := remoteconsoles.CreateOpts{
+		Protocol: remoteconsoles.ConsoleProtocolVNC,
+		Type:     remoteconsoles.ConsoleTypeNoVNC,
+	}
+	console, err := remoteconsoles.Create(&client, cr.Status.ID, createOpts).Extract()
+	if err != nil {
+		return err
+	}
+
+	secret := coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cr.Namespace,
+			Name:      consoleSecretName(cr),
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, &secret, func() error {
+		secret.Data = map[string][]byte{
+			"url": []byte(console.URL),
+		}
+		return ctrl.SetControllerReference(cr, &secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	cr.Status.Console = &kstypes.ConsoleStatus{
+		Request:    request,
+		Type:       console.Type,
+		SecretName: secret.Name,
+	}
+	return nil
+}
+
+// consoleSecretName returns name of secret holding console of cr, which is
+// replaced by every new console.
+func consoleSecretName(cr *kstypes.VirtualMachine) string {
+	return cr.Name + "-console"
+}
@@ -30,8 +30,10 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
 	"github.com/kupenstack/kupenstack/pkg/k8s"
 	"github.com/kupenstack/kupenstack/pkg/openstack"
 )
@@ -52,10 +54,19 @@ const (
 	msgFinalizerRemoveFailed = "Failed to remove virtual machine finalizer at kubernetes."
 )
 
+// Requeue periods
+const (
+	// virtual machine is checked again soon after it is created at openstack
+	createRequeuePeriod = 500 * time.Millisecond
+
+	// power state, console and status are reconciled periodically
+	requeuePeriod = 2 * time.Second
+)
+
 // Reconciler reconciles a VirtualMachine object
 type Reconciler struct {
 	client.Client
-	OS            *openstack.Client
+	OS            *openstack.Clouds
 	Log           logr.Logger
 	Scheme        *runtime.Scheme
 	EventRecorder record.EventRecorder
@@ -64,11 +75,10 @@ type Reconciler struct {
 //+kubebuilder:rbac:groups=kupenstack.io,resources=virtualmachines,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kupenstack.io,resources=virtualmachines/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kupenstack.io,resources=virtualmachines/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("virtual-machine", req.NamespacedName)
 
-	requeuePeriod := time.Duration(2000000000)
-
 	var cr kstypes.VirtualMachine
 	err := r.Get(ctx, req.NamespacedName, &cr)
 	if err != nil {
@@ -83,7 +93,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "CreateFailed",
 				"Virtual Machine create failed. error: %s", err)
 		}
-		return ctrl.Result{RequeueAfter: 500000000}, err
+		return openstack.Result(err, createRequeuePeriod)
 	}
 
 	// delete
@@ -93,19 +103,34 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			r.Eventf(&cr, coreV1.EventTypeWarning, "DeleteFailed",
 				"Vitual Machine deletion failed. error: %s", err)
 		}
-		return ctrl.Result{}, err
+		return openstack.Result(err, 0)
+	}
+
+	err = r.reconcilePower(ctx, cr)
+	if err != nil {
+		r.Eventf(&cr, coreV1.EventTypeWarning, "PowerFailed",
+			"Changing power state of Virtual Machine failed. error: %s", err)
+		return openstack.Result(err, requeuePeriod)
+	}
+
+	err = r.reconcileConsole(ctx, &cr)
+	if err != nil {
+		r.Eventf(&cr, coreV1.EventTypeWarning, "ConsoleFailed",
+			"Creating console of Virtual Machine failed. error: %s", err)
+		return openstack.Result(err, requeuePeriod)
 	}
 
 	err = r.updateStatus(ctx, cr)
 
 	log.Info("reconciled")
-	return ctrl.Result{RequeueAfter: requeuePeriod}, err
+	return openstack.Result(err, requeuePeriod)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kstypes.VirtualMachine{}).
+		WithOptions(controller.Options{RateLimiter: openstack.RateLimiter()}).
 		Complete(r)
 }
 
@@ -117,7 +142,12 @@ func (r *Reconciler) Eventf(cr metav1.Object, eventtype, reason, messageFmt stri
 
 func (r *Reconciler) updateStatus(ctx context.Context, cr kstypes.VirtualMachine) error {
 
-	osclient, err := r.OS.GetClient("compute")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, true)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("compute")
 	if err != nil {
 		return err
 	}
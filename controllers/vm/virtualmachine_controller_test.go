@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/controllers/vm"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// setup returns client with VirtualMachine vm in namespace demo, whose
+// server is already created in project of demo, and its reconciler.
+func setup(t *testing.T) (client.Client, *vm.Reconciler) {
+	ctx := context.Background()
+
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	clouds.SetDefault("fake")
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	kstypes.AddToScheme(scheme)
+
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}},
+	).Build()
+
+	projectReconciler := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+	_, err = projectReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}})
+	if err != nil {
+		t.Fatalf("project not created: %s", err)
+	}
+	var ns coreV1.Namespace
+	if err := c.Get(ctx, types.NamespacedName{Name: "demo"}, &ns); err != nil {
+		t.Fatal(err)
+	}
+	projectID := ns.Annotations[project.ExternalIDAnnotation]
+
+	image, _ := admin.GetClient("image")
+	img, err := images.Create(image, images.CreateOpts{Name: "cirros"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCompute, _ := admin.GetClient("compute")
+	disk := 1
+	flavor, err := flavors.Create(adminCompute, flavors.CreateOpts{Name: "small", RAM: 512, VCPUs: 1, Disk: &disk}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud, err := admin.Project(projectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compute, _ := cloud.GetClient("compute")
+	created, err := servers.Create(compute, servers.CreateOpts{Name: "vm", ImageRef: img.ID, FlavorRef: flavor.ID}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &kstypes.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "demo",
+			Annotations: map[string]string{project.ProjectIDAnnotation: projectID}},
+		Status: kstypes.VirtualMachineStatus{ID: created.ID},
+	}
+	if err := c.Create(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	return c, &vm.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+		EventRecorder: record.NewFakeRecorder(100)}
+}
+
+func TestVirtualMachinePower(t *testing.T) {
+	ctx := context.Background()
+	c, r := setup(t)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "vm"}}
+
+	tests := []struct {
+		name    string
+		running *bool
+		state   string
+	}{
+		{name: "not set", state: "Running"},
+		{name: "stop", running: boolPtr(false), state: "SHUTOFF"},
+		{name: "stay stopped", running: boolPtr(false), state: "SHUTOFF"},
+		{name: "start", running: boolPtr(true), state: "Running"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cr kstypes.VirtualMachine
+			if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+				t.Fatal(err)
+			}
+			cr.Spec.Running = test.running
+			if err := c.Update(ctx, &cr); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+				t.Fatal(err)
+			}
+			if cr.Status.State != test.state {
+				t.Errorf("expected state %s, got %s", test.state, cr.Status.State)
+			}
+		})
+	}
+}
+
+// Url of console is stored in a secret, not in status.
+func TestVirtualMachineConsole(t *testing.T) {
+	ctx := context.Background()
+	c, r := setup(t)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "vm"}}
+
+	var urls []string
+	for _, request := range []string{"first", "second"} {
+		var cr kstypes.VirtualMachine
+		if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+			t.Fatal(err)
+		}
+		cr.Annotations[kstypes.ConsoleRequestAnnotation] = request
+		if err := c.Update(ctx, &cr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+			t.Fatal(err)
+		}
+		console := cr.Status.Console
+		if console == nil || console.Request != request || console.SecretName != "vm-console" {
+			t.Fatalf("expected console of request %s in secret vm-console, got %+v", request, console)
+		}
+
+		var secret coreV1.Secret
+		err := c.Get(ctx, types.NamespacedName{Namespace: "demo", Name: console.SecretName}, &secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		url := string(secret.Data["url"])
+		if !strings.Contains(url, "token") {
+			t.Errorf("expected console url in secret, got %q", url)
+		}
+		if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "vm" {
+			t.Errorf("expected secret owned by virtual machine, got %+v", secret.OwnerReferences)
+		}
+		urls = append(urls, url)
+	}
+
+	if urls[0] == urls[1] {
+		t.Error("expected new console for new request")
+	}
+}
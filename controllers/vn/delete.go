@@ -24,19 +24,26 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) delete(ctx context.Context, cr kstypes.VirtualNetwork) error {
 	log := r.Log.WithValues("virtualnetwork", cr.Name)
 
-	osclient, err := r.OS.GetClient("network")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, true)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("network")
 	if err != nil {
 		return err
 	}
 
 	err = networks.Delete(osclient, cr.Status.ID).ExtractErr()
-	if ignoreNotFoundError(err) != nil {
+	if openstack.IgnoreNotFound(err) != nil {
 		log.Error(err, msgDeleteFailed)
 		return err
 	}
@@ -55,15 +62,3 @@ func (r *Reconciler) delete(ctx context.Context, cr kstypes.VirtualNetwork) erro
 	r.Eventf(&cr, coreV1.EventTypeNormal, "Deleted", "VirtualNetwork deleted.")
 	return nil
 }
-
-func ignoreNotFoundError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	if err.Error() == "Resource not found" {
-		return nil
-	} else {
-		return err
-	}
-}
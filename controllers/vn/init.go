@@ -31,23 +31,28 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
 	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) init(ctx context.Context, cr kstypes.VirtualNetwork) error {
 	log := r.Log.WithValues("virtualnetwork", cr.Name)
 
-	osclient, err := r.OS.GetClient("network")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, false)
 	if err != nil {
 		return err
 	}
 
-	shared := true
+	osclient, err := cloud.GetClient("network")
+	if err != nil {
+		return err
+	}
+
+	// network is private to project of namespace
 	activate := true
 	createOpts := networks.CreateOpts{
 		Name:         cr.Name,
 		AdminStateUp: &activate,
-		Shared:       &shared,
 	}
 
 	opts := mtu.CreateOptsExt{
@@ -115,7 +120,11 @@ func (r *Reconciler) init(ctx context.Context, cr kstypes.VirtualNetwork) error
 		return err
 	}
 
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
 	cr.Annotations[ExternalNameAnnotation] = createResult.Name
+	cr.Annotations[project.ProjectIDAnnotation] = cloud.ProjectID()
 	if !utils.ContainsString(cr.GetFinalizers(), Finalizer) {
 		controllerutil.AddFinalizer(&cr, Finalizer)
 	}
@@ -29,13 +29,19 @@ import (
 	// "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
 	// "github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func (r *Reconciler) update(ctx context.Context, cr kstypes.VirtualNetwork) error {
 	log := r.Log.WithValues("virtualnetwork", cr.Name)
 
-	osclient, err := r.OS.GetClient("network")
+	cloud, err := project.ClientFor(ctx, r, r.OS, &cr, true)
+	if err != nil {
+		return err
+	}
+
+	osclient, err := cloud.GetClient("network")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vn_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kstypes "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/controllers/vn"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func TestVirtualNetworkLifecycle(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		spec        kstypes.VirtualNetworkSpec
+		cidrPrefix  string
+		gatewayIP   string
+		expectedMtu int32
+	}{
+		{
+			name:        "defaults",
+			cidrPrefix:  "10.",
+			expectedMtu: 1450,
+		},
+		{
+			name:        "explicit",
+			spec:        kstypes.VirtualNetworkSpec{Cidr: "192.168.10.0/24", GatewayIP: "192.168.10.254", Mtu: 1400},
+			cidrPrefix:  "192.168.10.0/24",
+			gatewayIP:   "192.168.10.254",
+			expectedMtu: 1400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			server := fake.NewServer()
+			defer server.Close()
+			admin, err := openstack.New(server.AdminAuthOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			clouds := openstack.NewClouds()
+			clouds.Set("fake", admin)
+			clouds.SetDefault("fake")
+
+			scheme := runtime.NewScheme()
+			clientgoscheme.AddToScheme(scheme)
+			kstypes.AddToScheme(scheme)
+
+			c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "demo"}},
+				&kstypes.VirtualNetwork{ObjectMeta: metav1.ObjectMeta{Name: "net", Namespace: "demo"}, Spec: test.spec},
+			).Build()
+
+			projectReconciler := &project.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+				EventRecorder: record.NewFakeRecorder(100)}
+			vnReconciler := &vn.Reconciler{Client: c, OS: clouds, Log: ctrl.Log, Scheme: scheme,
+				EventRecorder: record.NewFakeRecorder(100)}
+
+			_, err = projectReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "demo"}})
+			if err != nil {
+				t.Fatalf("project not created: %s", err)
+			}
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "net"}}
+			// create, then update status and spec
+			for i := 0; i < 2; i++ {
+				if _, err := vnReconciler.Reconcile(ctx, req); err != nil {
+					t.Fatalf("reconcile %d failed: %s", i, err)
+				}
+			}
+
+			var cr kstypes.VirtualNetwork
+			if err := c.Get(ctx, req.NamespacedName, &cr); err != nil {
+				t.Fatal(err)
+			}
+			if !cr.Status.Ready {
+				t.Error("expected network to be ready")
+			}
+			if !strings.HasPrefix(cr.Spec.Cidr, test.cidrPrefix) {
+				t.Errorf("expected cidr %s*, got %s", test.cidrPrefix, cr.Spec.Cidr)
+			}
+			if test.gatewayIP != "" && cr.Spec.GatewayIP != test.gatewayIP {
+				t.Errorf("expected gateway %s, got %s", test.gatewayIP, cr.Spec.GatewayIP)
+			}
+			if cr.Spec.Mtu != test.expectedMtu {
+				t.Errorf("expected mtu %d, got %d", test.expectedMtu, cr.Spec.Mtu)
+			}
+
+			cloud, err := admin.Project(cr.Annotations[project.ProjectIDAnnotation])
+			if err != nil {
+				t.Fatalf("network not created in project of namespace: %s", err)
+			}
+			network, _ := cloud.GetClient("network")
+			created, err := networks.Get(network, cr.Status.ID).Extract()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.Shared {
+				t.Error("expected network private to project")
+			}
+
+			if err := c.Delete(ctx, &cr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := vnReconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("network not deleted: %s", err)
+			}
+			if err := networks.Get(network, cr.Status.ID).Err; err == nil {
+				t.Error("network still exists at openstack")
+			}
+		})
+	}
+}
@@ -18,7 +18,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -30,23 +33,35 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
 	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
 
 	clustercontrollers "github.com/kupenstack/kupenstack/controllers/cluster"
+	configcontrollers "github.com/kupenstack/kupenstack/controllers/config"
 	"github.com/kupenstack/kupenstack/controllers/flavor"
 	"github.com/kupenstack/kupenstack/controllers/image"
 	"github.com/kupenstack/kupenstack/controllers/keypair"
 	"github.com/kupenstack/kupenstack/controllers/network"
+	nodecontrollers "github.com/kupenstack/kupenstack/controllers/node"
+	occpcontrollers "github.com/kupenstack/kupenstack/controllers/occp"
 	"github.com/kupenstack/kupenstack/controllers/project"
+	"github.com/kupenstack/kupenstack/controllers/projectmember"
+	"github.com/kupenstack/kupenstack/controllers/projectquota"
 	"github.com/kupenstack/kupenstack/controllers/vm"
 	"github.com/kupenstack/kupenstack/controllers/vn"
 	"github.com/kupenstack/kupenstack/oskops"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+	"github.com/kupenstack/kupenstack/pkg/metrics"
 	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
 	//+kubebuilder:scaffold:imports
 )
 
+// Name of cloud served by simulated OpenStack with --fake-openstack.
+const fakeCloudName = "fake"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("kupenstack.main")
@@ -65,18 +80,43 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var kupenstackConfigurationFile string
+	var enableWebhooks bool
+	var listBuiltinProfiles bool
+	var gracefulShutdownTimeout time.Duration
+	var fakeOpenStack bool
+	var projectMemberRoles string
+	var preflightImage string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
-	flag.StringVar(&kupenstackConfigurationFile, "kupenstack-configuration-file", "config.yaml", "The filepath to KupenstackConfiguration.")
+	flag.StringVar(&kupenstackConfigurationFile, "kupenstack-configuration-file", "config.yaml", "The filepath to KupenstackConfiguration, used when no KupenstackConfiguration resource named kupenstack exists.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable admission webhooks. Serving certificates must be mounted at /tmp/k8s-webhook-server/serving-certs.")
+	flag.BoolVar(&listBuiltinProfiles, "list-builtin-profiles", false,
+		"Print names of builtin profiles, usable as from: builtin://<name> in OpenStackCloudConfigurationProfile, and exit.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 2*time.Minute,
+		"Time given to helm operations in flight and controllers to finish on shutdown.")
+	flag.BoolVar(&fakeOpenStack, "fake-openstack", false,
+		"Run against an in-memory simulated OpenStack cloud instead of deploying and authenticating to one.")
+	flag.StringVar(&projectMemberRoles, "project-member-roles", strings.Join(projectmember.DefaultAllowedRoles, ","),
+		"Comma separated keystone roles ProjectMembers may assign on project of their namespace.")
+	flag.StringVar(&preflightImage, "preflight-image", clustercontrollers.DefaultPreflightImage,
+		"Image of pods running preflight checks on nodes. Only a shell is needed.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	if listBuiltinProfiles {
+		for _, name := range occp.BuiltinProfiles() {
+			fmt.Println(name)
+		}
+		return
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -86,21 +126,58 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "e2bdf9e1.kupenstack.io",
+		// Leadership is given up only after OpenStack component loops
+		// have stopped, so a new leader never runs helm operations
+		// alongside this one.
+		LeaderElectionReleaseOnCancel: true,
+		GracefulShutdownTimeout:       &gracefulShutdownTimeout,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	go oskops.ManageOskNodes(mgr.GetClient(), kupenstackConfigurationFile)
-	go oskops.Start(mgr.GetClient(), kupenstackConfigurationFile)
+	if _, err := metrics.NewResourceCollector(mgr.GetClient()); err != nil {
+		setupLog.Error(err, "unable to register metrics collector")
+		os.Exit(1)
+	}
+
+	kupenstackConfiguration := oskops.NewConfiguration(mgr.GetClient(), kupenstackConfigurationFile)
+	OSclient := openstack.NewClouds()
+
+	if fakeOpenStack {
+		cloud := fake.NewServer()
+		defer cloud.Close()
 
-	///// Temporary code
+		if err := OSclient.Authenticate(fakeCloudName, cloud.AdminAuthOptions()); err != nil {
+			setupLog.Error(err, "unable to authenticate to simulated OpenStack cloud")
+			os.Exit(1)
+		}
+		OSclient.SetDefault(fakeCloudName)
+		setupLog.Info("using simulated OpenStack cloud", "url", cloud.URL)
+	} else {
+		if err := mgr.Add(&oskops.Manager{
+			Client:          mgr.GetClient(),
+			Configuration:   kupenstackConfiguration,
+			ShutdownTimeout: gracefulShutdownTimeout,
+		}); err != nil {
+			setupLog.Error(err, "unable to add OpenStack component loops to manager")
+			os.Exit(1)
+		}
 
-	OSclient := &openstack.Client{}
-	go oskops.AuthenticateOpenstackClient(OSclient)
+		///// Temporary code
 
-	//////////////////////////
+		if err := mgr.Add(&oskops.Authenticator{
+			Client:        mgr.GetClient(),
+			Clouds:        OSclient,
+			Configuration: kupenstackConfiguration,
+		}); err != nil {
+			setupLog.Error(err, "unable to add OpenStack authenticator to manager")
+			os.Exit(1)
+		}
+
+		//////////////////////////
+	}
 
 	if err = (&project.Reconciler{
 		Client:        mgr.GetClient(),
@@ -113,6 +190,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&projectmember.Reconciler{
+		Client:        mgr.GetClient(),
+		OS:            OSclient,
+		Log:           ctrl.Log.WithName("controllers").WithName("ProjectMember"),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("kupenstack-controller"),
+		AllowedRoles:  strings.Split(projectMemberRoles, ","),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProjectMember")
+		os.Exit(1)
+	}
+
+	if err = (&projectquota.Reconciler{
+		Client:        mgr.GetClient(),
+		OS:            OSclient,
+		Log:           ctrl.Log.WithName("controllers").WithName("ProjectQuota"),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("kupenstack-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProjectQuota")
+		os.Exit(1)
+	}
+
 	if err = (&keypair.Reconciler{
 		Client:        mgr.GetClient(),
 		OS:            OSclient,
@@ -164,12 +264,45 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&clustercontrollers.Reconciler{
+		Client:                  mgr.GetClient(),
+		KupenstackConfiguration: kupenstackConfiguration,
+		OS:                      OSclient,
+		APIReader:               mgr.GetAPIReader(),
+		PreflightImage:          preflightImage,
+		Log:                     ctrl.Log.WithName("controllers").WithName("OpenstackNode"),
+		Scheme:                  mgr.GetScheme(),
+		EventRecorder:           mgr.GetEventRecorderFor("kupenstack-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OpenstackNode")
+		os.Exit(1)
+	}
+	if err = (&nodecontrollers.Reconciler{
+		Client:                  mgr.GetClient(),
+		KupenstackConfiguration: kupenstackConfiguration,
+		Log:                     ctrl.Log.WithName("controllers").WithName("Node"),
+		Scheme:                  mgr.GetScheme(),
+		EventRecorder:           mgr.GetEventRecorderFor("kupenstack-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Node")
+		os.Exit(1)
+	}
+	if err = (&configcontrollers.Reconciler{
+		Client:                  mgr.GetClient(),
+		KupenstackConfiguration: kupenstackConfiguration,
+		Log:                     ctrl.Log.WithName("controllers").WithName("KupenstackConfiguration"),
+		Scheme:                  mgr.GetScheme(),
+		EventRecorder:           mgr.GetEventRecorderFor("kupenstack-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "KupenstackConfiguration")
+		os.Exit(1)
+	}
+	if err = (&occpcontrollers.Reconciler{
 		Client:        mgr.GetClient(),
-		Log:           ctrl.Log.WithName("controllers").WithName("OpenstackNode"),
+		Log:           ctrl.Log.WithName("controllers").WithName("OpenStackCloudConfigurationProfile"),
 		Scheme:        mgr.GetScheme(),
 		EventRecorder: mgr.GetEventRecorderFor("kupenstack-controller"),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "OpenstackNode")
+		setupLog.Error(err, "unable to create controller", "controller", "OpenStackCloudConfigurationProfile")
 		os.Exit(1)
 	}
 	if err = (&vn.Reconciler{
@@ -184,6 +317,11 @@ func main() {
 	}
 	//+kubebuilder:scaffold:builder
 
+	if enableWebhooks {
+		mgr.GetWebhookServer().Register(occp.ValidatingWebhookPath,
+			&webhook.Admission{Handler: &occp.Validator{Client: mgr.GetClient()}})
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
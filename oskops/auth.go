@@ -1,29 +1,60 @@
 package oskops
 
 import (
+	"context"
 	"time"
 
-	"github.com/gophercloud/gophercloud"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/kupenstack/kupenstack/pkg/openstack"
 )
 
-// Temporary fix to dynamically authenticate to openstack.
-func AuthenticateOpenstackClient(OSclient *openstack.Client) {
+// Authenticator keeps Clouds authenticated to all clouds, until its
+// context is done. It runs only on elected leader, same as controllers
+// using Clouds.
+type Authenticator struct {
+	Client        k8sclient.Client
+	Clouds        *openstack.Clouds
+	Configuration *Configuration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (a *Authenticator) NeedLeaderElection() bool {
+	return true
+}
+
+func (a *Authenticator) Start(ctx context.Context) error {
 
+	changed := a.Configuration.Subscribe()
 	for {
-		time.Sleep(20 * time.Second)
-
-		gopheropts := &gophercloud.AuthOptions{
-			IdentityEndpoint: "http://keystone.kupenstack.svc.cluster.local/v3",
-			Username:         "admin",
-			Password:         "password",
-			DomainName:       "Default",
-			TenantName:       "admin",
+		select {
+		case <-time.After(20 * time.Second):
+		case <-changed:
+		case <-ctx.Done():
+			return nil
 		}
-		newClient, err := openstack.New(gopheropts)
+
+		clouds, err := ListClouds(ctx, a.Client, a.Configuration)
 		if err != nil {
 			continue
 		}
-		*OSclient = *newClient
+
+		desired := make(map[string]bool)
+		for _, cloud := range clouds {
+			desired[cloud.Name] = true
+			if cloud.Default {
+				a.Clouds.SetDefault(cloud.Name)
+			}
+
+			// Errors are retried on next round, clouds whose credentials
+			// are rejected are left without client until then.
+			a.Clouds.Authenticate(cloud.Name, cloud.AdminAuthOptions())
+		}
+
+		for _, name := range a.Clouds.Names() {
+			if !desired[name] {
+				a.Clouds.Delete(name)
+			}
+		}
 	}
 }
@@ -0,0 +1,45 @@
+package oskops
+
+import (
+	"context"
+	"sort"
+
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+)
+
+// ListClouds returns all OpenStack clouds to be deployed. Default profile
+// always yields a cloud, and each other profile referenced by any osknode
+// yields one more.
+func ListClouds(ctx context.Context, c k8sclient.Client, config *Configuration) ([]kupenstack.Cloud, error) {
+
+	cfg, err := config.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var oskNodeList v1alpha1.OpenstackNodeList
+	err = c.List(ctx, &oskNodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCloud := kupenstack.NewCloud(cfg.Spec.DefaultProfile, cfg.Spec.DefaultProfile)
+	clouds := map[string]kupenstack.Cloud{
+		defaultCloud.Name: defaultCloud,
+	}
+	for _, osknode := range oskNodeList.Items {
+		cloud := kupenstack.NewCloud(osknode.Spec.Occp, cfg.Spec.DefaultProfile)
+		clouds[cloud.Name] = cloud
+	}
+
+	var list []kupenstack.Cloud
+	for _, cloud := range clouds {
+		list = append(list, cloud)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
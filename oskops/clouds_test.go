@@ -0,0 +1,71 @@
+package oskops_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+)
+
+func osknode(name, profile string) *v1alpha1.OpenstackNode {
+	return &v1alpha1.OpenstackNode{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1alpha1.OpenstackNodeSpec{Occp: *profileRef(profile)},
+	}
+}
+
+func TestListClouds(t *testing.T) {
+
+	cfg := config()
+	cfg.Name = v1alpha1.KupenstackConfigurationName
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	v1alpha1.AddToScheme(scheme)
+
+	tests := []struct {
+		name   string
+		nodes  []client.Object
+		clouds []string
+	}{
+		{
+			name:   "no osknodes",
+			clouds: []string{"default.default"},
+		},
+		{
+			name: "osknodes of profiles",
+			nodes: []client.Object{osknode("node-1", "gpu"), osknode("node-2", "default"),
+				osknode("node-3", "gpu"), osknode("node-4", "arm")},
+			clouds: []string{"arm.default", "default.default", "gpu.default"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(append(test.nodes, &cfg)...).Build()
+
+			clouds, err := oskops.ListClouds(context.Background(), c, oskops.NewConfiguration(c, ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, cloud := range clouds {
+				names = append(names, cloud.Name)
+				if cloud.Default != (cloud.Name == "default.default") {
+					t.Errorf("expected only cloud of default profile to be default, got %+v", cloud)
+				}
+			}
+			if !reflect.DeepEqual(names, test.clouds) {
+				t.Errorf("expected clouds %v, got %v", test.clouds, names)
+			}
+		})
+	}
+}
@@ -15,76 +15,71 @@ import (
 	"github.com/kupenstack/kupenstack/pkg/helm"
 	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
 	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
-func Manage(c client.Client, profilename string, log logr.Logger) {
+func Manage(ctx context.Context, c client.Client, cloud ksk.Cloud, log logr.Logger) {
 	log = log.WithName("glance")
 
 	for {
 
-		_, err := reconcile(c, profilename)
+		wait := 30 * time.Second
+		_, err := reconcile(ctx, c, cloud)
 		if err != nil {
 			log.Error(err, "")
-			time.Sleep(10 * time.Second)
-			continue
+			wait = 10 * time.Second
 		}
 
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
 }
 
-func reconcile(c client.Client, profilename string) (bool, error) {
+func reconcile(ctx context.Context, c client.Client, cloud ksk.Cloud) (bool, error) {
 
-	ok, err := ksk.OccpExists(c, profilename)
+	ok, err := ksk.OccpExists(c, cloud.Name)
 	if !ok || err != nil {
 		return ok, err
 	}
 
-	osknodeList, err := osknode.GetList(context.Background(), c)
+	vals, nodesReady, err := osknode.DesiredConfiguration(ctx, c, cloud.Name, "glance")
 	if err != nil {
 		return false, err
 	}
 
-	nodesReady := false
-	vals := make(map[string]interface{})
-	for _, n := range osknodeList.Items {
-		oskNode, err := osknode.AsStruct(&n)
-		if err != nil {
-			return false, err
-		}
-
-		occp := oskNode.Spec.Occp.Name + "." + oskNode.Spec.Occp.Namespace
-		if occp == profilename {
-
-			nodesReady = oskNode.Status.Generated
-
-			if oskNode.Status.DesiredNodeConfiguration != nil {
-				if oskNode.Status.DesiredNodeConfiguration["glance"] != nil {
-					vals = oskNode.Status.DesiredNodeConfiguration["glance"].(map[string]interface{})
-				}
-			}
-		}
-	}
-
 	if nodesReady == false {
 		return false, nil
 	}
 
 	vals["storage"] = "pvc"
 
-	release, err := helm.GetRelease("glance", "kupenstack")
+	selectors, err := cloud.NodeSelectorValues("glance")
+	if err != nil {
+		return false, err
+	}
+	vals = utils.PatchJson(selectors, vals)
+	vals, err = ksk.OverrideValues(vals)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := helm.GetRelease(cloud.ReleaseName("glance"), cloud.Namespace)
 	if err != nil {
 		return false, err
 	}
 
 	// create pv if not exists
+	pvName := cloud.ReleaseName("glance") + "-pv"
 	pv := &core.PersistentVolume{}
-	err = c.Get(context.Background(), types.NamespacedName{Name: "glance-pv"}, pv)
+	err = c.Get(ctx, types.NamespacedName{Name: pvName}, pv)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			pv := &core.PersistentVolume{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: "glance-pv",
+					Name: pvName,
 				},
 				Spec: core.PersistentVolumeSpec{
 					StorageClassName: "general",
@@ -96,12 +91,12 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 					},
 					PersistentVolumeSource: core.PersistentVolumeSource{
 						HostPath: &core.HostPathVolumeSource{
-							Path: "/mnt/glance",
+							Path: "/mnt/" + cloud.ReleaseName("glance"),
 						},
 					},
 				},
 			}
-			err = c.Create(context.Background(), pv)
+			err = c.Create(ctx, pv)
 			if err != nil {
 				return false, err
 			}
@@ -109,8 +104,16 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 		return false, err
 	}
 
-	if release == nil {
-		result, err := helm.UpgradeRelease("glance", "osh", "glance", "kupenstack", vals)
+	changed := true
+	if release != nil {
+		changed, err = helm.ValuesChanged(release, vals)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if changed {
+		result, err := helm.UpgradeRelease(ctx, cloud.ReleaseName("glance"), "osh", "glance", cloud.Namespace, vals)
 		if err != nil {
 			return false, err
 		}
@@ -10,68 +10,70 @@ import (
 	"github.com/kupenstack/kupenstack/pkg/helm"
 	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
 	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
-func Manage(c client.Client, profilename string, log logr.Logger) {
+func Manage(ctx context.Context, c client.Client, cloud ksk.Cloud, log logr.Logger) {
 	log = log.WithName("horizon")
 
 	for {
 
-		_, err := reconcile(c, profilename)
+		wait := 30 * time.Second
+		_, err := reconcile(ctx, c, cloud)
 		if err != nil {
 			log.Error(err, "")
-			time.Sleep(10 * time.Second)
-			continue
+			wait = 10 * time.Second
 		}
 
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
 }
 
-func reconcile(c client.Client, profilename string) (bool, error) {
+func reconcile(ctx context.Context, c client.Client, cloud ksk.Cloud) (bool, error) {
 
-	ok, err := ksk.OccpExists(c, profilename)
+	ok, err := ksk.OccpExists(c, cloud.Name)
 	if !ok || err != nil {
 		return ok, err
 	}
 
-	osknodeList, err := osknode.GetList(context.Background(), c)
+	vals, nodesReady, err := osknode.DesiredConfiguration(ctx, c, cloud.Name, "horizon")
 	if err != nil {
 		return false, err
 	}
 
-	nodesReady := false
-	vals := make(map[string]interface{})
-	for _, n := range osknodeList.Items {
-		oskNode, err := osknode.AsStruct(&n)
-		if err != nil {
-			return false, err
-		}
-
-		occp := oskNode.Spec.Occp.Name + "." + oskNode.Spec.Occp.Namespace
-		if occp == profilename {
-
-			nodesReady = oskNode.Status.Generated
-
-			if oskNode.Status.DesiredNodeConfiguration != nil {
-				if oskNode.Status.DesiredNodeConfiguration["horizon"] != nil {
-					vals = oskNode.Status.DesiredNodeConfiguration["horizon"].(map[string]interface{})
-				}
-			}
-		}
-	}
-
 	if nodesReady == false {
 		return false, nil
 	}
 
-	release, err := helm.GetRelease("horizon", "kupenstack")
+	selectors, err := cloud.NodeSelectorValues("horizon")
+	if err != nil {
+		return false, err
+	}
+	vals = utils.PatchJson(selectors, vals)
+	vals, err = ksk.OverrideValues(vals)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := helm.GetRelease(cloud.ReleaseName("horizon"), cloud.Namespace)
 	if err != nil {
 		return false, err
 	}
 
-	if release == nil {
-		result, err := helm.UpgradeRelease("horizon", "osh", "horizon", "kupenstack", vals)
+	changed := true
+	if release != nil {
+		changed, err = helm.ValuesChanged(release, vals)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if changed {
+		result, err := helm.UpgradeRelease(ctx, cloud.ReleaseName("horizon"), "osh", "horizon", cloud.Namespace, vals)
 		if err != nil {
 			return false, err
 		}
@@ -1,20 +1,26 @@
 package ingress
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kupenstack/kupenstack/pkg/helm"
+	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
-func Manage(c client.Client, profilename string, log logr.Logger) {
+// ManageClusterIngress deploys ingress in kube-system namespace, which is
+// shared by all clouds.
+func ManageClusterIngress(ctx context.Context, c client.Client, log logr.Logger) {
 	log = log.WithName("ingress")
 
 	for {
 
-		ok, err := reconcile(c, profilename)
+		ok, err := reconcileClusterIngress(ctx, c)
 		if err != nil {
 			log.Error(err, "")
 		}
@@ -22,11 +28,39 @@ func Manage(c client.Client, profilename string, log logr.Logger) {
 		if ok {
 			break
 		}
-		time.Sleep(30 * time.Second)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
 	}
 }
 
-func reconcile(c client.Client, profilename string) (bool, error) {
+// Manage deploys ingress in namespace of cloud.
+func Manage(ctx context.Context, c client.Client, cloud ksk.Cloud, log logr.Logger) {
+	log = log.WithName("ingress")
+
+	for {
+
+		ok, err := reconcile(ctx, c, cloud)
+		if err != nil {
+			log.Error(err, "")
+		}
+
+		if ok {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func reconcileClusterIngress(ctx context.Context, c client.Client) (bool, error) {
 
 	vals := map[string]interface{}{
 		"deployment": map[string]interface{}{
@@ -49,7 +83,7 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 	}
 
 	if release == nil {
-		result, err := helm.UpgradeRelease("kube-system-ingress", "osh", "ingress", "kube-system", vals)
+		result, err := helm.UpgradeRelease(ctx, "kube-system-ingress", "osh", "ingress", "kube-system", vals)
 		if err != nil {
 			return false, err
 		}
@@ -58,7 +92,12 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 		}
 	}
 
-	vals = map[string]interface{}{
+	return true, nil
+}
+
+func reconcile(ctx context.Context, c client.Client, cloud ksk.Cloud) (bool, error) {
+
+	vals := map[string]interface{}{
 		"pod": map[string]interface{}{
 			"replicas": map[string]interface{}{
 				"ingress":    1,
@@ -67,13 +106,47 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 		},
 	}
 
-	release, err = helm.GetRelease("kupenstack-ingress", "kupenstack")
+	cfg, nodesReady, err := osknode.DesiredConfiguration(ctx, c, cloud.Name, "ingress")
 	if err != nil {
 		return false, err
 	}
 
-	if release == nil {
-		result, err := helm.UpgradeRelease("kupenstack-ingress", "osh", "ingress", "kupenstack", vals)
+	// Configuration of profile is known once osknodes of cloud have
+	// generated it, so that release is not installed with defaults first.
+	if nodesReady == false {
+		return false, nil
+	}
+
+	vals, err = ksk.WithDefaults(vals, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	selectors, err := cloud.NodeSelectorValues("ingress")
+	if err != nil {
+		return false, err
+	}
+	vals = utils.PatchJson(selectors, vals)
+	vals, err = ksk.OverrideValues(vals)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := helm.GetRelease(cloud.ReleaseName("kupenstack-ingress"), cloud.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	changed := true
+	if release != nil {
+		changed, err = helm.ValuesChanged(release, vals)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if changed {
+		result, err := helm.UpgradeRelease(ctx, cloud.ReleaseName("kupenstack-ingress"), "osh", "ingress", cloud.Namespace, vals)
 		if err != nil {
 			return false, err
 		}
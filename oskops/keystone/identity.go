@@ -0,0 +1,343 @@
+package keystone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// Default auth methods of keystone, to which federation protocols are added.
+var defaultAuthMethods = []string{"external", "password", "token", "oauth1", "mapped", "application_credential"}
+
+var defaultRemoteIDAttributes = map[string]string{
+	"saml2":  "Shib-Identity-Provider",
+	"openid": "HTTP_OIDC_ISS",
+}
+
+// identity holds identity backends of keystone configuration of a profile.
+type identity struct {
+	Domains    []clusterv1alpha1.KeystoneDomain    `json:"domains"`
+	Federation *clusterv1alpha1.KeystoneFederation `json:"federation"`
+
+	// Mapping of each identity provider, which typed fields do not hold.
+	mappings []interface{}
+}
+
+// popIdentity removes `domains` and `federation` from keystone
+// configuration `vals` and returns them.
+func popIdentity(vals map[string]interface{}) (identity, error) {
+
+	var id identity
+	raw := map[string]interface{}{
+		"domains":    vals["domains"],
+		"federation": vals["federation"],
+	}
+	delete(vals, "domains")
+	delete(vals, "federation")
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return id, err
+	}
+	err = json.Unmarshal(data, &id)
+	if err != nil {
+		return id, fmt.Errorf("invalid keystone identity configuration: %s", err)
+	}
+
+	federation, _ := raw["federation"].(map[string]interface{})
+	providers, _ := federation["identityProviders"].([]interface{})
+	for _, provider := range providers {
+		p, _ := provider.(map[string]interface{})
+		id.mappings = append(id.mappings, p["mapping"])
+	}
+	return id, nil
+}
+
+// values returns keystone chart values configuring LDAP domains and
+// federation. Bind credentials are read from secrets in `namespace`.
+func (id identity) values(ctx context.Context, c client.Client, namespace string) (map[string]interface{}, error) {
+
+	keystoneConf := make(map[string]interface{})
+	conf := map[string]interface{}{
+		"keystone": keystoneConf,
+	}
+
+	if len(id.Domains) > 0 {
+		// Chart mounts `ks_domains` in its domain_config_dir.
+		keystoneConf["identity"] = map[string]interface{}{
+			"domain_specific_drivers_enabled": true,
+		}
+
+		domains := make(map[string]interface{})
+		for _, domain := range id.Domains {
+			ldap, err := ldapValues(ctx, c, namespace, domain.LDAP)
+			if err != nil {
+				return nil, fmt.Errorf("domain %s: %s", domain.Name, err)
+			}
+			domains[domain.Name] = map[string]interface{}{
+				"identity": map[string]interface{}{
+					"driver": "ldap",
+				},
+				"ldap": ldap,
+			}
+		}
+		conf["ks_domains"] = domains
+	}
+
+	if id.Federation != nil {
+		methods := append([]string{}, defaultAuthMethods...)
+		for _, provider := range id.Federation.IdentityProviders {
+			if !utils.ContainsString(methods, provider.Protocol) {
+				methods = append(methods, provider.Protocol)
+			}
+
+			attribute := provider.RemoteIDAttribute
+			if attribute == "" {
+				attribute = defaultRemoteIDAttributes[provider.Protocol]
+			}
+			keystoneConf[provider.Protocol] = map[string]interface{}{
+				"remote_id_attribute": attribute,
+			}
+		}
+		keystoneConf["auth"] = map[string]interface{}{
+			"methods": strings.Join(methods, ","),
+		}
+
+		if len(id.Federation.TrustedDashboards) > 0 {
+			dashboards := make([]interface{}, 0, len(id.Federation.TrustedDashboards))
+			for _, dashboard := range id.Federation.TrustedDashboards {
+				dashboards = append(dashboards, dashboard)
+			}
+			keystoneConf["federation"] = map[string]interface{}{
+				"trusted_dashboard": map[string]interface{}{
+					"type":   "multistring",
+					"values": dashboards,
+				},
+			}
+		}
+	}
+
+	if len(keystoneConf) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{
+		"conf": conf,
+	}, nil
+}
+
+func ldapValues(ctx context.Context, c client.Client, namespace string, backend clusterv1alpha1.LDAPBackend) (map[string]interface{}, error) {
+
+	ldap := make(map[string]interface{})
+	for key, val := range backend.Options {
+		ldap[key] = val
+	}
+
+	set := func(key, val string) {
+		if val != "" {
+			ldap[key] = val
+		}
+	}
+	set("url", backend.URL)
+	set("suffix", backend.Suffix)
+	set("user_tree_dn", backend.UserTreeDN)
+	set("user_objectclass", backend.UserObjectClass)
+	set("user_id_attribute", backend.UserIDAttribute)
+	set("user_name_attribute", backend.UserNameAttribute)
+	set("user_filter", backend.UserFilter)
+	set("group_tree_dn", backend.GroupTreeDN)
+	set("group_objectclass", backend.GroupObjectClass)
+	set("group_filter", backend.GroupFilter)
+	if backend.UseTLS {
+		ldap["use_tls"] = true
+	}
+
+	if backend.BindCredentials != nil {
+		var secret corev1.Secret
+		err := c.Get(ctx, types.NamespacedName{Name: backend.BindCredentials.Name, Namespace: namespace}, &secret)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read bind credentials: %s", err)
+		}
+		if len(secret.Data["user"]) == 0 {
+			return nil, fmt.Errorf("secret %s has no user", backend.BindCredentials.Name)
+		}
+		ldap["user"] = string(secret.Data["user"])
+		ldap["password"] = string(secret.Data["password"])
+	}
+
+	return ldap, nil
+}
+
+// Description of identity providers registered by kupenstack. Providers
+// with it that are removed from profile are deleted from keystone, others
+// are left alone.
+const managedDescription = "Managed by kupenstack."
+
+// adminClient authenticates as admin of a cloud once, and keeps the client
+// across reconciles until keystone rejects its credentials.
+type adminClient struct {
+	cloud  ksk.Cloud
+	client *openstack.Client
+}
+
+func (a *adminClient) get() (*openstack.Client, error) {
+
+	if a.client != nil {
+		return a.client, nil
+	}
+
+	opts := a.cloud.AdminAuthOptions()
+	opts.AllowReauth = true
+	client, err := openstack.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	a.client = client
+	return client, nil
+}
+
+// check drops client when err shows its authentication failed, so that
+// next reconcile authenticates again.
+func (a *adminClient) check(err error) error {
+	if openstack.IsAuthFailure(err) {
+		a.client = nil
+	}
+	return err
+}
+
+// registerIdentityProviders creates or updates identity providers in
+// keystone of cloud, each with a mapping of same name and its protocol.
+// Providers registered earlier and since removed from profile are
+// deleted with their mapping.
+func (id identity) registerIdentityProviders(admin *adminClient) error {
+
+	var providers []clusterv1alpha1.IdentityProvider
+	if id.Federation != nil {
+		providers = id.Federation.IdentityProviders
+	}
+
+	osclient, err := admin.get()
+	if err != nil {
+		return err
+	}
+	identityClient, err := osclient.GetClient("identity")
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]bool)
+	for i, provider := range providers {
+		desired[provider.Name] = true
+
+		err = putOrPatch(identityClient, identityClient.ServiceURL("OS-FEDERATION", "mappings", provider.Name),
+			map[string]interface{}{"mapping": id.mappings[i]})
+		if err != nil {
+			return admin.check(fmt.Errorf("mapping %s: %w", provider.Name, err))
+		}
+
+		err = putOrPatch(identityClient, identityClient.ServiceURL("OS-FEDERATION", "identity_providers", provider.Name),
+			map[string]interface{}{"identity_provider": map[string]interface{}{
+				"enabled":     true,
+				"description": managedDescription,
+				"remote_ids":  provider.RemoteIDs,
+			}})
+		if err != nil {
+			return admin.check(fmt.Errorf("identity provider %s: %w", provider.Name, err))
+		}
+
+		err = putOrPatch(identityClient, identityClient.ServiceURL("OS-FEDERATION", "identity_providers", provider.Name, "protocols", provider.Protocol),
+			map[string]interface{}{"protocol": map[string]interface{}{
+				"mapping_id": provider.Name,
+			}})
+		if err != nil {
+			return admin.check(fmt.Errorf("protocol %s of identity provider %s: %w", provider.Protocol, provider.Name, err))
+		}
+
+		err = deleteProtocols(identityClient, provider)
+		if err != nil {
+			return admin.check(fmt.Errorf("protocols of identity provider %s: %w", provider.Name, err))
+		}
+	}
+
+	return admin.check(deleteIdentityProviders(identityClient, desired))
+}
+
+// deleteProtocols deletes protocols of identity provider other than its
+// current one, left behind when protocol of provider is changed.
+func deleteProtocols(sc *gophercloud.ServiceClient, provider clusterv1alpha1.IdentityProvider) error {
+
+	var body struct {
+		Protocols []struct {
+			ID string `json:"id"`
+		} `json:"protocols"`
+	}
+	_, err := sc.Get(sc.ServiceURL("OS-FEDERATION", "identity_providers", provider.Name, "protocols"), &body, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, protocol := range body.Protocols {
+		if protocol.ID == provider.Protocol {
+			continue
+		}
+		_, err = sc.Delete(sc.ServiceURL("OS-FEDERATION", "identity_providers", provider.Name, "protocols", protocol.ID), nil)
+		if openstack.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteIdentityProviders deletes identity providers registered by
+// kupenstack that are not `desired`, along with their mapping.
+func deleteIdentityProviders(sc *gophercloud.ServiceClient, desired map[string]bool) error {
+
+	var body struct {
+		IdentityProviders []struct {
+			ID          string `json:"id"`
+			Description string `json:"description"`
+		} `json:"identity_providers"`
+	}
+	_, err := sc.Get(sc.ServiceURL("OS-FEDERATION", "identity_providers"), &body, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, provider := range body.IdentityProviders {
+		if desired[provider.ID] || provider.Description != managedDescription {
+			continue
+		}
+
+		// Deleting provider deletes its protocols too.
+		_, err = sc.Delete(sc.ServiceURL("OS-FEDERATION", "identity_providers", provider.ID), nil)
+		if openstack.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("identity provider %s: %w", provider.ID, err)
+		}
+		_, err = sc.Delete(sc.ServiceURL("OS-FEDERATION", "mappings", provider.ID), nil)
+		if openstack.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("mapping %s: %w", provider.ID, err)
+		}
+	}
+	return nil
+}
+
+// putOrPatch creates resource at url with PUT, or updates it with PATCH if
+// it already exists.
+func putOrPatch(sc *gophercloud.ServiceClient, url string, body map[string]interface{}) error {
+
+	_, err := sc.Put(url, body, nil, &gophercloud.RequestOpts{OkCodes: []int{201}})
+	if _, ok := err.(gophercloud.ErrDefault409); ok {
+		_, err = sc.Patch(url, body, nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	}
+	return err
+}
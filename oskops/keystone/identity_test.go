@@ -0,0 +1,169 @@
+package keystone
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+)
+
+func TestPopIdentity(t *testing.T) {
+
+	mapping := map[string]interface{}{"rules": []interface{}{map[string]interface{}{"local": "user"}}}
+	vals := map[string]interface{}{
+		"replicas": map[string]interface{}{"api": 2},
+		"domains": []interface{}{map[string]interface{}{
+			"name": "corp",
+			"ldap": map[string]interface{}{"url": "ldaps://ad.example.com", "suffix": "dc=example,dc=com"},
+		}},
+		"federation": map[string]interface{}{
+			"identityProviders": []interface{}{map[string]interface{}{
+				"name":      "okta",
+				"protocol":  "openid",
+				"remoteIDs": []interface{}{"https://example.okta.com"},
+				"mapping":   mapping,
+			}},
+		},
+	}
+
+	id, err := popIdentity(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(vals, map[string]interface{}{"replicas": map[string]interface{}{"api": 2}}) {
+		t.Errorf("expected identity removed from values, got %v", vals)
+	}
+	if len(id.Domains) != 1 || id.Domains[0].Name != "corp" || id.Domains[0].LDAP.URL != "ldaps://ad.example.com" {
+		t.Errorf("expected domain corp, got %+v", id.Domains)
+	}
+	if id.Federation == nil || len(id.Federation.IdentityProviders) != 1 || id.Federation.IdentityProviders[0].Name != "okta" {
+		t.Fatalf("expected identity provider okta, got %+v", id.Federation)
+	}
+	if len(id.mappings) != 1 || !reflect.DeepEqual(id.mappings[0], mapping) {
+		t.Errorf("expected mapping of okta, got %v", id.mappings)
+	}
+
+	_, err = popIdentity(map[string]interface{}{"domains": "corp"})
+	if err == nil {
+		t.Error("expected error for invalid domains")
+	}
+}
+
+func TestIdentityValues(t *testing.T) {
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).Build()
+
+	empty, err := identity{}.values(context.Background(), c, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("expected no values without identity backends, got %v", empty)
+	}
+
+	id := identity{
+		Domains: []clusterv1alpha1.KeystoneDomain{{
+			Name: "corp",
+			LDAP: clusterv1alpha1.LDAPBackend{URL: "ldaps://ad.example.com", Suffix: "dc=example,dc=com"},
+		}},
+		Federation: &clusterv1alpha1.KeystoneFederation{
+			TrustedDashboards: []string{"https://horizon.example.com/auth/websso/"},
+			IdentityProviders: []clusterv1alpha1.IdentityProvider{
+				{Name: "okta", Protocol: "openid"},
+				{Name: "adfs", Protocol: "saml2", RemoteIDAttribute: "MELLON_IDP"},
+				{Name: "google", Protocol: "openid"},
+			},
+		},
+	}
+	vals, err := id.values(context.Background(), c, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"conf": map[string]interface{}{
+			"keystone": map[string]interface{}{
+				"identity": map[string]interface{}{"domain_specific_drivers_enabled": true},
+				"auth": map[string]interface{}{
+					"methods": "external,password,token,oauth1,mapped,application_credential,openid,saml2",
+				},
+				"openid": map[string]interface{}{"remote_id_attribute": "HTTP_OIDC_ISS"},
+				"saml2":  map[string]interface{}{"remote_id_attribute": "MELLON_IDP"},
+				"federation": map[string]interface{}{
+					"trusted_dashboard": map[string]interface{}{
+						"type":   "multistring",
+						"values": []interface{}{"https://horizon.example.com/auth/websso/"},
+					},
+				},
+			},
+			"ks_domains": map[string]interface{}{
+				"corp": map[string]interface{}{
+					"identity": map[string]interface{}{"driver": "ldap"},
+					"ldap":     map[string]interface{}{"url": "ldaps://ad.example.com", "suffix": "dc=example,dc=com"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected values %v, got %v", expected, vals)
+	}
+}
+
+func TestLDAPValues(t *testing.T) {
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bind", Namespace: "default"},
+			Data: map[string][]byte{"user": []byte("cn=admin,dc=example,dc=com"), "password": []byte("secret")}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "nouser", Namespace: "default"},
+			Data: map[string][]byte{"password": []byte("secret")}},
+	).Build()
+
+	backend := clusterv1alpha1.LDAPBackend{
+		URL:             "ldap://ldap.example.com",
+		Suffix:          "dc=example,dc=com",
+		UserTreeDN:      "ou=Users,dc=example,dc=com",
+		UserObjectClass: "inetOrgPerson",
+		UseTLS:          true,
+		BindCredentials: &clusterv1alpha1.SecretRef{Name: "bind"},
+		// Typed fields take precedence over options.
+		Options: map[string]string{"page_size": "500", "url": "ldap://other.example.com"},
+	}
+	ldap, err := ldapValues(context.Background(), c, "default", backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"url":              "ldap://ldap.example.com",
+		"suffix":           "dc=example,dc=com",
+		"user_tree_dn":     "ou=Users,dc=example,dc=com",
+		"user_objectclass": "inetOrgPerson",
+		"use_tls":          true,
+		"page_size":        "500",
+		"user":             "cn=admin,dc=example,dc=com",
+		"password":         "secret",
+	}
+	if !reflect.DeepEqual(ldap, expected) {
+		t.Errorf("expected ldap values %v, got %v", expected, ldap)
+	}
+
+	for _, name := range []string{"nouser", "missing"} {
+		backend.BindCredentials = &clusterv1alpha1.SecretRef{Name: name}
+		_, err = ldapValues(context.Background(), c, "default", backend)
+		if err == nil {
+			t.Errorf("expected error for bind credentials of secret %s", name)
+		}
+	}
+}
@@ -10,74 +10,104 @@ import (
 	"github.com/kupenstack/kupenstack/pkg/helm"
 	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
 	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
-func Manage(c client.Client, profilename string, log logr.Logger) {
+func Manage(ctx context.Context, c client.Client, cloud ksk.Cloud, log logr.Logger) {
 	log = log.WithName("keystone")
 
+	admin := &adminClient{cloud: cloud}
 	for {
 
-		_, err := reconcile(c, profilename)
+		wait := 30 * time.Second
+		_, err := reconcile(ctx, c, cloud, admin)
 		if err != nil {
 			log.Error(err, "")
-			time.Sleep(10 * time.Second)
-			continue
+			wait = 10 * time.Second
 		}
 
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
 }
 
-func reconcile(c client.Client, profilename string) (bool, error) {
+func reconcile(ctx context.Context, c client.Client, cloud ksk.Cloud, admin *adminClient) (bool, error) {
 
-	ok, err := ksk.OccpExists(c, profilename)
+	ok, err := ksk.OccpExists(c, cloud.Name)
 	if !ok || err != nil {
 		return ok, err
 	}
 
-	osknodeList, err := osknode.GetList(context.Background(), c)
+	vals, nodesReady, err := osknode.DesiredConfiguration(ctx, c, cloud.Name, "keystone")
 	if err != nil {
 		return false, err
 	}
 
-	nodesReady := false
-	vals := make(map[string]interface{})
-	for _, n := range osknodeList.Items {
-		oskNode, err := osknode.AsStruct(&n)
-		if err != nil {
-			return false, err
-		}
-
-		occp := oskNode.Spec.Occp.Name + "." + oskNode.Spec.Occp.Namespace
-		if occp == profilename {
+	if nodesReady == false {
+		return false, nil
+	}
 
-			nodesReady = oskNode.Status.Generated
+	id, err := popIdentity(vals)
+	if err != nil {
+		return false, err
+	}
 
-			if oskNode.Status.DesiredNodeConfiguration != nil {
-				if oskNode.Status.DesiredNodeConfiguration["keystone"] != nil {
-					vals = oskNode.Status.DesiredNodeConfiguration["keystone"].(map[string]interface{})
-				}
-			}
-		}
+	// Generated identity values go under conf given in profile, so
+	// that any of them can be overridden. They are rendered on every
+	// reconcile, so that changed domains, federation or bind credentials
+	// upgrade keystone.
+	identityVals, err := id.values(ctx, c, cloud.Profile().Namespace)
+	if err != nil {
+		return false, err
+	}
+	vals, err = utils.MergeJson(identityVals, vals)
+	if err != nil {
+		return false, err
 	}
 
-	if nodesReady == false {
-		return false, nil
+	selectors, err := cloud.NodeSelectorValues("keystone")
+	if err != nil {
+		return false, err
+	}
+	vals = utils.PatchJson(selectors, vals)
+	vals, err = ksk.OverrideValues(vals)
+	if err != nil {
+		return false, err
 	}
 
-	release, err := helm.GetRelease("keystone", "kupenstack")
+	release, err := helm.GetRelease(cloud.ReleaseName("keystone"), cloud.Namespace)
 	if err != nil {
 		return false, err
 	}
 
-	if release == nil {
-		result, err := helm.UpgradeRelease("keystone", "osh", "keystone", "kupenstack", vals)
+	changed := true
+	if release != nil {
+		changed, err = helm.ValuesChanged(release, vals)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if changed {
+		result, err := helm.UpgradeRelease(ctx, cloud.ReleaseName("keystone"), "osh", "keystone", cloud.Namespace, vals)
 		if err != nil {
 			return false, err
 		}
 		if result == nil {
 			return false, nil
 		}
+		if release == nil {
+			return true, nil
+		}
+	}
+
+	// Keystone is running, identity providers can be registered.
+	err = id.registerIdentityProviders(admin)
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
@@ -1,14 +1,91 @@
 package oskops
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"sync"
 
-	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
-	"github.com/kupenstack/kupenstack/oskops/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
 )
 
+// Configuration provides KupenstackConfiguration in use. The cluster-scoped
+// KupenstackConfiguration resource named `kupenstack` is used when it exists,
+// otherwise the configuration file given on startup, also when its CRD is not
+// installed. It is safe for concurrent use.
+type Configuration struct {
+	client k8sclient.Client
+
+	// Filepath to KupenstackConfiguration used as fallback.
+	file string
+
+	mu          sync.Mutex
+	subscribers []chan struct{}
+}
+
+// NewConfiguration returns Configuration reading resource with client `c`,
+// and falling back to `file`.
+func NewConfiguration(c k8sclient.Client, file string) *Configuration {
+	return &Configuration{
+		client: c,
+		file:   file,
+	}
+}
+
+// Read returns KupenstackConfiguration in use, or error when it is invalid
+// so that it is not applied.
+func (c *Configuration) Read(ctx context.Context) (v1alpha1.KupenstackConfiguration, error) {
+
+	var cfg v1alpha1.KupenstackConfiguration
+	err := c.client.Get(ctx, types.NamespacedName{Name: v1alpha1.KupenstackConfigurationName}, &cfg)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return ReadKupenStackConfiguration(c.file)
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	err = ValidateKupenstackConfiguration(cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("Invalid KupenstackConfiguration %s: %s", cfg.Name, err)
+	}
+
+	return cfg, nil
+}
+
+// Subscribe returns a channel receiving a value whenever configuration
+// changes. Changes happening before the value is consumed are coalesced.
+func (c *Configuration) Subscribe() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	c.subscribers = append(c.subscribers, ch)
+	return ch
+}
+
+// Notify tells all subscribers that configuration has changed.
+func (c *Configuration) Notify() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func ReadKupenStackConfiguration(filename string) (v1alpha1.KupenstackConfiguration, error) {
 
 	var cfg v1alpha1.KupenstackConfiguration
@@ -23,7 +100,7 @@ func ReadKupenStackConfiguration(filename string) (v1alpha1.KupenstackConfigurat
 		return cfg, err
 	}
 
-	if cfg.ApiVersion != "kupenstack.io/v1alpha1" {
+	if cfg.APIVersion != "kupenstack.io/v1alpha1" && cfg.APIVersion != v1alpha1.GroupVersion.String() {
 		return cfg, fmt.Errorf("Invalid apiVersion in %s for KupenStackConfiguration", filename)
 	}
 
@@ -31,5 +108,76 @@ func ReadKupenStackConfiguration(filename string) (v1alpha1.KupenstackConfigurat
 		return cfg, fmt.Errorf("Invalid kind in %s for KupenStackConfiguration", filename)
 	}
 
-	return cfg, err
+	err = ValidateKupenstackConfiguration(cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("Invalid KupenstackConfiguration in %s: %s", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// nodeTypePattern is the schema pattern of `type` of nodes and node rules.
+var nodeTypePattern = regexp.MustCompile(`^\s*(control|compute)?\s*(,\s*(control|compute)\s*)*$`)
+
+// ValidateKupenstackConfiguration checks what schema of KupenstackConfiguration
+// checks, as configuration file is not checked by the schema, and what it
+// cannot, i.e. that selectors of node rules are valid and nodes are not
+// configured twice.
+func ValidateKupenstackConfiguration(cfg v1alpha1.KupenstackConfiguration) error {
+
+	err := validateProfile(&cfg.Spec.DefaultProfile)
+	if err != nil {
+		return fmt.Errorf("invalid defaultProfile: %s", err)
+	}
+
+	for i, rule := range cfg.Spec.NodeRules {
+		_, err := nodeSelector(rule)
+		if err != nil {
+			return fmt.Errorf("invalid selector in nodeRules[%d]: %s", i, err)
+		}
+		if !nodeTypePattern.MatchString(rule.Type) {
+			return fmt.Errorf("invalid type %q in nodeRules[%d]: roles must be control or compute", rule.Type, i)
+		}
+		err = validateProfile(rule.Profile)
+		if err != nil {
+			return fmt.Errorf("invalid profile in nodeRules[%d]: %s", i, err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, node := range cfg.Spec.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("nodes[%d] has no name", i)
+		}
+		if names[node.Name] {
+			return fmt.Errorf("node %s is configured more than once in nodes", node.Name)
+		}
+		names[node.Name] = true
+		if !nodeTypePattern.MatchString(node.Type) {
+			return fmt.Errorf("invalid type %q of node %s: roles must be control or compute", node.Type, node.Name)
+		}
+		err = validateProfile(node.Profile)
+		if err != nil {
+			return fmt.Errorf("invalid profile of node %s: %s", node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateProfile checks that profile, when set, has name and namespace.
+func validateProfile(profile *v1alpha1.OccpRef) error {
+	if profile == nil {
+		return nil
+	}
+	if profile.Name == "" || profile.Namespace == "" {
+		return fmt.Errorf("name and namespace are required")
+	}
+	return nil
+}
+
+// nodeSelector converts selector of NodeRule to k8s labels.Selector.
+// An empty selector matches all nodes.
+func nodeSelector(rule v1alpha1.NodeRule) (labels.Selector, error) {
+	return metav1.LabelSelectorAsSelector(&rule.Selector)
 }
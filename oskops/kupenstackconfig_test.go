@@ -0,0 +1,167 @@
+package oskops_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+)
+
+func TestValidateKupenstackConfiguration(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		edit  func(cfg *v1alpha1.KupenstackConfiguration)
+		error string
+	}{
+		{
+			name: "valid",
+			edit: func(cfg *v1alpha1.KupenstackConfiguration) {},
+		},
+		{
+			name:  "default profile without namespace",
+			edit:  func(cfg *v1alpha1.KupenstackConfiguration) { cfg.Spec.DefaultProfile.Namespace = "" },
+			error: "invalid defaultProfile",
+		},
+		{
+			name: "invalid selector",
+			edit: func(cfg *v1alpha1.KupenstackConfiguration) {
+				cfg.Spec.NodeRules[0].Selector = matchLabels(map[string]string{"gpu": "not valid"})
+			},
+			error: "invalid selector in nodeRules[0]",
+		},
+		{
+			name:  "unknown role in rule",
+			edit:  func(cfg *v1alpha1.KupenstackConfiguration) { cfg.Spec.NodeRules[0].Type = "compute,storage" },
+			error: `invalid type "compute,storage" in nodeRules[0]`,
+		},
+		{
+			name:  "rule profile without name",
+			edit:  func(cfg *v1alpha1.KupenstackConfiguration) { cfg.Spec.NodeRules[0].Profile.Name = "" },
+			error: "invalid profile in nodeRules[0]",
+		},
+		{
+			name: "node without name",
+			edit: func(cfg *v1alpha1.KupenstackConfiguration) {
+				cfg.Spec.Nodes = append(cfg.Spec.Nodes, v1alpha1.NodeConfiguration{})
+			},
+			error: "nodes[1] has no name",
+		},
+		{
+			name: "node listed twice",
+			edit: func(cfg *v1alpha1.KupenstackConfiguration) {
+				cfg.Spec.Nodes = append(cfg.Spec.Nodes, v1alpha1.NodeConfiguration{Name: "node-1"})
+			},
+			error: "node node-1 is configured more than once",
+		},
+		{
+			name:  "unknown role of node",
+			edit:  func(cfg *v1alpha1.KupenstackConfiguration) { cfg.Spec.Nodes[0].Type = "master" },
+			error: `invalid type "master" of node node-1`,
+		},
+		{
+			name:  "node profile without namespace",
+			edit:  func(cfg *v1alpha1.KupenstackConfiguration) { cfg.Spec.Nodes[0].Profile.Namespace = "" },
+			error: "invalid profile of node node-1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config(v1alpha1.NodeRule{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"gpu": "true"}},
+				Type:     " control , compute",
+				Profile:  profileRef("gpu"),
+			})
+			cfg.Spec.Nodes = []v1alpha1.NodeConfiguration{{Name: "node-1", Type: "compute", Profile: profileRef("node")}}
+			test.edit(&cfg)
+
+			err := oskops.ValidateKupenstackConfiguration(cfg)
+			if test.error == "" {
+				if err != nil {
+					t.Errorf("expected valid configuration, got %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("expected error %q, got %v", test.error, err)
+			}
+		})
+	}
+}
+
+// noMatchClient fails as when CRD of KupenstackConfiguration is not installed.
+type noMatchClient struct {
+	k8sclient.Client
+}
+
+func (c noMatchClient) Get(ctx context.Context, key k8sclient.ObjectKey, obj k8sclient.Object) error {
+	return &meta.NoKindMatchError{GroupKind: v1alpha1.GroupVersion.WithKind("KupenstackConfiguration").GroupKind()}
+}
+
+func TestConfigurationRead(t *testing.T) {
+	ctx := context.Background()
+
+	fileCfg := config()
+	fileCfg.APIVersion = v1alpha1.GroupVersion.String()
+	fileCfg.Kind = "KupenstackConfiguration"
+	buf, err := yaml.Marshal(fileCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(file, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	v1alpha1.AddToScheme(scheme)
+	resource := config()
+	resource.Name = v1alpha1.KupenstackConfigurationName
+	resource.Spec.DefaultProfile.Name = "resource"
+
+	tests := []struct {
+		name    string
+		client  k8sclient.Client
+		profile string
+	}{
+		{
+			name:    "resource",
+			client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(&resource).Build(),
+			profile: "resource",
+		},
+		{
+			name:    "resource not found",
+			client:  fake.NewClientBuilder().WithScheme(scheme).Build(),
+			profile: "default",
+		},
+		{
+			name:    "CRD not installed",
+			client:  noMatchClient{fake.NewClientBuilder().WithScheme(scheme).Build()},
+			profile: "default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := oskops.NewConfiguration(test.client, file).Read(ctx)
+			if err != nil {
+				t.Fatalf("expected configuration, got %s", err)
+			}
+			if cfg.Spec.DefaultProfile.Name != test.profile {
+				t.Errorf("expected default profile %s, got %s", test.profile, cfg.Spec.DefaultProfile.Name)
+			}
+		})
+	}
+}
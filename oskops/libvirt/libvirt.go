@@ -1,35 +1,43 @@
 package libvirt
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kupenstack/kupenstack/pkg/helm"
+	ksk "github.com/kupenstack/kupenstack/pkg/kupenstack"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
-func Manage(c client.Client, profilename string, log logr.Logger) {
+func Manage(ctx context.Context, c client.Client, cloud ksk.Cloud, log logr.Logger) {
 	log = log.WithName("libvirt")
 
 	for {
 
-		_, err := reconcile(c, profilename)
+		wait := 30 * time.Second
+		_, err := reconcile(ctx, c, cloud)
 		if err != nil {
 			log.Error(err, "")
-			time.Sleep(10 * time.Second)
-			continue
+			wait = 10 * time.Second
 		}
 
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
 }
 
-func reconcile(c client.Client, profilename string) (bool, error) {
+func reconcile(ctx context.Context, c client.Client, cloud ksk.Cloud) (bool, error) {
 
 	vals := map[string]interface{}{
 		"network": map[string]interface{}{
-			"backend": []string{
+			"backend": []interface{}{
 				"linuxbridge",
 			},
 		},
@@ -40,13 +48,47 @@ func reconcile(c client.Client, profilename string) (bool, error) {
 		},
 	}
 
-	release, err := helm.GetRelease("libvirt", "kupenstack")
+	cfg, nodesReady, err := osknode.DesiredConfiguration(ctx, c, cloud.Name, "libvirt")
+	if err != nil {
+		return false, err
+	}
+
+	// Configuration of profile is known once osknodes of cloud have
+	// generated it, so that release is not installed with defaults first.
+	if nodesReady == false {
+		return false, nil
+	}
+
+	vals, err = ksk.WithDefaults(vals, cfg)
+	if err != nil {
+		return false, err
+	}
+
+	selectors, err := cloud.NodeSelectorValues("libvirt")
 	if err != nil {
 		return false, err
 	}
+	vals = utils.PatchJson(selectors, vals)
+	vals, err = ksk.OverrideValues(vals)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := helm.GetRelease(cloud.ReleaseName("libvirt"), cloud.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	changed := true
+	if release != nil {
+		changed, err = helm.ValuesChanged(release, vals)
+		if err != nil {
+			return false, err
+		}
+	}
 
-	if release == nil {
-		result, err := helm.UpgradeRelease("libvirt", "osh", "libvirt", "kupenstack", vals)
+	if changed {
+		result, err := helm.UpgradeRelease(ctx, cloud.ReleaseName("libvirt"), "osh", "libvirt", cloud.Namespace, vals)
 		if err != nil {
 			return false, err
 		}
@@ -1,9 +1,12 @@
 package oskops
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -19,40 +22,265 @@ import (
 	"github.com/kupenstack/kupenstack/oskops/placement"
 	"github.com/kupenstack/kupenstack/oskops/rabbitmq"
 	"github.com/kupenstack/kupenstack/pkg/helm"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
 )
 
-func Start(c k8sclient.Client, kupenstackConfig string) {
+// Manager runs reconciliation loops of OpenStack components for all
+// clouds. It is a manager.Runnable started only on elected leader, so that
+// a single replica deploys OpenStack at a time.
+type Manager struct {
+	Client        k8sclient.Client
+	Configuration *Configuration
+
+	// Time given to helm operations in flight to finish on shutdown.
+	ShutdownTimeout time.Duration
+
+	loops sync.WaitGroup
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (m *Manager) NeedLeaderElection() bool {
+	return true
+}
+
+// Start runs loops until ctx is done. Loops are then stopped, and Start
+// returns after helm operations in flight have finished or
+// ShutdownTimeout has passed.
+func (m *Manager) Start(ctx context.Context) error {
 	log := ctrl.Log.WithName("kupenstack.oskops")
 
-	cfg, err := ReadKupenStackConfiguration(kupenstackConfig)
+	err := helm.AddRepoIfNotExist("osh", "https://charts.kupenstack.io")
 	if err != nil {
-		log.Error(err, "Unable to read KupenstackConfiguration.")
-		os.Exit(1)
+		return fmt.Errorf("unable to add helm repo https://charts.kupenstack.io: %w", err)
 	}
-	profilename := cfg.Spec.DefaultProfile.Name + "." + cfg.Spec.DefaultProfile.Namespace
 
-	err = helm.AddRepoIfNotExist("osh", "https://charts.kupenstack.io")
+	err = helm.UpdateHelmRepos()
 	if err != nil {
-		log.Error(err, "Unable to add helm repo: https://charts.kupenstack.io.")
-		os.Exit(1)
+		return fmt.Errorf("failed to update helm repositories: %w", err)
 	}
 
-	err = helm.UpdateHelmRepos()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(5 * time.Second):
+	}
+	m.run(func() { ingress.ManageClusterIngress(ctx, m.Client, log) })
+
+	// Each cloud runs its own set of component loops, which are stopped
+	// when no osknode refers to its profile anymore. Releases of a stopped
+	// cloud are then uninstalled, and it is not started again before
+	// that is over.
+	// Clouds are checked again as soon as KupenstackConfiguration changes.
+	changed := m.Configuration.Subscribe()
+	clouds := &cloudSet{
+		running:  make(map[string]*cloudLoops),
+		removing: make(map[string]chan struct{}),
+	}
+	for {
+		desired, err := ListClouds(ctx, m.Client, m.Configuration)
+		if err != nil {
+			log.Error(err, "Failed to list OpenStack clouds.")
+		}
+		m.syncClouds(ctx, clouds, desired, err, log)
+
+		wait := 30 * time.Second
+		if err != nil {
+			wait = 10 * time.Second
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-changed:
+		case <-ctx.Done():
+			return m.shutdown(log)
+		}
+	}
+}
+
+// cloudSet holds clouds being managed, and clouds being removed with
+// channel closed when their removal is over.
+type cloudSet struct {
+	running  map[string]*cloudLoops
+	removing map[string]chan struct{}
+}
+
+// syncClouds starts loops of desired clouds not running yet, and stops and
+// removes running clouds not desired anymore. Clouds are not stopped when
+// listing them failed with `listErr`, as it would otherwise look like no
+// cloud is desired.
+func (m *Manager) syncClouds(ctx context.Context, clouds *cloudSet, desired []kupenstack.Cloud, listErr error, log logr.Logger) {
+
+	for name, removed := range clouds.removing {
+		select {
+		case <-removed:
+			delete(clouds.removing, name)
+		default:
+		}
+	}
+
+	isDesired := make(map[string]bool)
+	for _, cloud := range desired {
+		isDesired[cloud.Name] = true
+		if clouds.running[cloud.Name] != nil || clouds.removing[cloud.Name] != nil {
+			continue
+		}
+
+		log.Info("Managing OpenStack cloud.", "cloud", cloud.Name, "namespace", cloud.Namespace)
+		cloudCtx, cancel := context.WithCancel(ctx)
+		clouds.running[cloud.Name] = m.startCloud(cloudCtx, cancel, cloud, log.WithValues("cloud", cloud.Name))
+	}
+
+	for name, loops := range clouds.running {
+		if listErr == nil && !isDesired[name] {
+			log.Info("Stopped managing OpenStack cloud.", "cloud", name)
+			loops.cancel()
+			delete(clouds.running, name)
+			clouds.removing[name] = m.removeCloud(ctx, loops, log.WithValues("cloud", name))
+		}
+	}
+}
+
+// shutdown refuses new helm operations and waits for loops to return.
+// Loops return once their current reconcile is over, which may be stuck
+// in a helm operation.
+func (m *Manager) shutdown(log logr.Logger) error {
+	log.Info("Stopping OpenStack component loops.")
+	helm.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.ShutdownTimeout)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		m.loops.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+	}
+
+	err := helm.Wait(ctx)
 	if err != nil {
-		log.Error(err, "Failed to update helm repositories.")
-		os.Exit(1)
-	}
-
-	time.Sleep(5 * time.Second)
-	go ingress.Manage(c, profilename, log)
-	go mariadb.Manage(c, profilename, log)
-	go rabbitmq.Manage(c, profilename, log)
-	go memcached.Manage(c, profilename, log)
-	go keystone.Manage(c, profilename, log)
-	go glance.Manage(c, profilename, log)
-	go horizon.Manage(c, profilename, log)
-	go nova.Manage(c, profilename, log)
-	go neutron.Manage(c, profilename, log)
-	go placement.Manage(c, profilename, log)
-	go libvirt.Manage(c, profilename, log)
+		return fmt.Errorf("helm operations still in flight after %s: %w", m.ShutdownTimeout, err)
+	}
+	return nil
+}
+
+// run runs loop in a new goroutine, tracked for shutdown.
+func (m *Manager) run(loop func()) {
+	m.loops.Add(1)
+	go func() {
+		defer m.loops.Done()
+		loop()
+	}()
+}
+
+// cloudLoops are reconciliation loops of OpenStack components of a cloud.
+type cloudLoops struct {
+	cloud  kupenstack.Cloud
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// run runs loop of cloud in a new goroutine, tracked for shutdown.
+func (l *cloudLoops) run(m *Manager, loop func()) {
+	l.wg.Add(1)
+	m.run(func() {
+		defer l.wg.Done()
+		loop()
+	})
+}
+
+// Reconciliation loops of OpenStack components run for each cloud.
+var components = []func(context.Context, k8sclient.Client, kupenstack.Cloud, logr.Logger){
+	ingress.Manage,
+	mariadb.Manage,
+	rabbitmq.Manage,
+	memcached.Manage,
+	keystone.Manage,
+	glance.Manage,
+	horizon.Manage,
+	nova.Manage,
+	neutron.Manage,
+	placement.Manage,
+	libvirt.Manage,
+}
+
+// startCloud starts reconciliation loops of all OpenStack components of
+// cloud, which are stopped by `cancel`.
+func (m *Manager) startCloud(ctx context.Context, cancel context.CancelFunc, cloud kupenstack.Cloud, log logr.Logger) *cloudLoops {
+	l := &cloudLoops{cloud: cloud, cancel: cancel}
+	for _, manage := range components {
+		manage := manage
+		l.run(m, func() { manage(ctx, m.Client, cloud, log) })
+	}
+	return l
+}
+
+// Releases of a cloud, in order they are uninstalled.
+var releases = []string{"libvirt", "placement", "neutron", "nova", "horizon", "glance",
+	"keystone", "memcached", "rabbitmq", "mariadb", "kupenstack-ingress"}
+
+// removeCloud uninstalls releases of cloud of stopped `loops` once they
+// have returned, retrying until it succeeds or ctx is done. Returned
+// channel is closed when it is over.
+func (m *Manager) removeCloud(ctx context.Context, loops *cloudLoops, log logr.Logger) chan struct{} {
+
+	removed := make(chan struct{})
+	m.run(func() {
+		defer close(removed)
+		loops.wg.Wait()
+
+		// Default cloud is always desired, this only guards its releases.
+		if loops.cloud.Default {
+			return
+		}
+
+		for {
+			err := uninstall(ctx, loops.cloud)
+			if err == nil {
+				log.Info("Uninstalled OpenStack cloud.", "namespace", loops.cloud.Namespace)
+				return
+			}
+			log.Error(err, "Failed to uninstall OpenStack cloud.")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+	})
+	return removed
+}
+
+// uninstall removes a cloud, replaced in tests.
+var uninstall = uninstallCloud
+
+// uninstallCloud uninstalls all helm releases of cloud. Namespace of cloud,
+// persistent volumes and claims are kept, so that data of cloud survives
+// until an administrator deletes them.
+func uninstallCloud(ctx context.Context, cloud kupenstack.Cloud) error {
+
+	for _, component := range releases {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		name := cloud.ReleaseName(component)
+		release, err := helm.GetRelease(name, cloud.Namespace)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			continue
+		}
+		err = helm.DeleteRelease(ctx, name, cloud.Namespace)
+		if err != nil {
+			return fmt.Errorf("cannot uninstall release %s: %w", name, err)
+		}
+	}
+	return nil
 }
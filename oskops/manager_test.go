@@ -0,0 +1,126 @@
+package oskops
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+)
+
+// fakeComponents records clouds whose loops run, and clouds uninstalled.
+type fakeComponents struct {
+	mu          sync.Mutex
+	running     map[string]int
+	uninstalled []string
+
+	// Closed to let uninstall return.
+	release chan struct{}
+}
+
+func (f *fakeComponents) manage(ctx context.Context, _ k8sclient.Client, cloud kupenstack.Cloud, _ logr.Logger) {
+	f.mu.Lock()
+	f.running[cloud.Name]++
+	f.mu.Unlock()
+
+	<-ctx.Done()
+
+	f.mu.Lock()
+	f.running[cloud.Name]--
+	f.mu.Unlock()
+}
+
+func (f *fakeComponents) uninstall(_ context.Context, cloud kupenstack.Cloud) error {
+	<-f.release
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uninstalled = append(f.uninstalled, cloud.Name)
+	return nil
+}
+
+// loops waits until `expected` loops of cloud are running.
+func (f *fakeComponents) loops(t *testing.T, cloud string, expected int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		f.mu.Lock()
+		n := f.running[cloud]
+		f.mu.Unlock()
+		if n == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d loops of cloud %s running", expected, cloud)
+}
+
+func setupComponents(t *testing.T) *fakeComponents {
+	f := &fakeComponents{running: make(map[string]int), release: make(chan struct{})}
+
+	savedComponents, savedUninstall := components, uninstall
+	components = []func(context.Context, k8sclient.Client, kupenstack.Cloud, logr.Logger){f.manage, f.manage}
+	uninstall = f.uninstall
+	t.Cleanup(func() { components, uninstall = savedComponents, savedUninstall })
+	return f
+}
+
+func TestSyncClouds(t *testing.T) {
+
+	f := setupComponents(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	defaultProfile := v1alpha1.OccpRef{Name: "default", Namespace: "default"}
+	defaultCloud := kupenstack.NewCloud(defaultProfile, defaultProfile)
+	gpuCloud := kupenstack.NewCloud(v1alpha1.OccpRef{Name: "gpu", Namespace: "default"}, defaultProfile)
+
+	m := &Manager{}
+	clouds := &cloudSet{running: make(map[string]*cloudLoops), removing: make(map[string]chan struct{})}
+	log := ctrl.Log
+
+	m.syncClouds(ctx, clouds, []kupenstack.Cloud{defaultCloud, gpuCloud}, nil, log)
+	f.loops(t, defaultCloud.Name, 2)
+	f.loops(t, gpuCloud.Name, 2)
+
+	// Nothing is stopped when listing clouds failed.
+	m.syncClouds(ctx, clouds, nil, errors.New("list failed"), log)
+	if len(clouds.running) != 2 {
+		t.Fatalf("expected clouds kept running, got %v", clouds.running)
+	}
+
+	// Cloud no longer desired is stopped and uninstalled. It is not
+	// started again until that is over.
+	m.syncClouds(ctx, clouds, []kupenstack.Cloud{defaultCloud}, nil, log)
+	f.loops(t, gpuCloud.Name, 0)
+	removed := clouds.removing[gpuCloud.Name]
+	if removed == nil {
+		t.Fatalf("expected cloud %s being removed", gpuCloud.Name)
+	}
+	m.syncClouds(ctx, clouds, []kupenstack.Cloud{defaultCloud, gpuCloud}, nil, log)
+	if clouds.running[gpuCloud.Name] != nil {
+		t.Fatalf("expected cloud %s not started while being removed", gpuCloud.Name)
+	}
+
+	close(f.release)
+	<-removed
+	if len(f.uninstalled) != 1 || f.uninstalled[0] != gpuCloud.Name {
+		t.Errorf("expected cloud %s uninstalled, got %v", gpuCloud.Name, f.uninstalled)
+	}
+	m.syncClouds(ctx, clouds, []kupenstack.Cloud{defaultCloud, gpuCloud}, nil, log)
+	f.loops(t, gpuCloud.Name, 2)
+
+	// Releases of default cloud are never uninstalled.
+	m.syncClouds(ctx, clouds, nil, nil, log)
+	f.loops(t, defaultCloud.Name, 0)
+	<-clouds.removing[defaultCloud.Name]
+	<-clouds.removing[gpuCloud.Name]
+	if len(f.uninstalled) != 2 || f.uninstalled[1] != gpuCloud.Name {
+		t.Errorf("expected only cloud %s uninstalled again, got %v", gpuCloud.Name, f.uninstalled)
+	}
+}
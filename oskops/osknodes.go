@@ -1,181 +1,65 @@
 package oskops
 
 import (
-	"context"
-	"time"
-
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	ctrl "sigs.k8s.io/controller-runtime"
-	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
-	ksk "github.com/kupenstack/kupenstack/oskops/apis/v1alpha1"
-)
-
-var (
-	log = ctrl.Log.WithName("kupenstack").WithName("sync").WithName("osknode")
+	osknodeutils "github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
 )
 
-var client k8sclient.Client
-
-// TODO: Improve execution using context.Context properly
-func ManageOskNodes(c k8sclient.Client, kupenstackConfig string) {
-	client = c
-
-	for {
-		time.Sleep(10 * time.Second)
-
-		k8snodes, err := getK8sNodes()
-		if err != nil {
-			log.Error(err, "Failed to get list of k8s nodes.")
-			continue
-		}
-
-		osknodes, err := getOsksNodes()
-		if err != nil {
-			log.Error(err, "Failed to get list of osknodes.")
-			continue
-		}
-
-		err = removeExtraOskNodes(k8snodes, osknodes)
-		if err != nil {
-			log.Error(err, "Failed to remove extra osknodes.")
-			continue
-		}
-
-		err = syncOskNodes(k8snodes, osknodes, kupenstackConfig)
-		if err != nil {
-			log.Error(err, "Failed to sync osknodes objects with kubernetes nodes.")
-			continue
-		}
-
-		time.Sleep(30 * time.Second)
-	}
-}
-
-func getK8sNodes() ([]core.Node, error) {
-	var nodeList core.NodeList
-	err := client.List(context.Background(), &nodeList)
-	if err != nil {
-		return nil, err
-	}
-	return nodeList.Items, nil
-}
-
-func getOsksNodes() ([]v1alpha1.OpenstackNode, error) {
-	var oskNodeList v1alpha1.OpenstackNodeList
-	err := client.List(context.Background(), &oskNodeList)
-	if err != nil {
-		return nil, err
-	}
-	return oskNodeList.Items, nil
-}
-
-func removeExtraOskNodes(k8snodes []core.Node, osknodes []v1alpha1.OpenstackNode) error {
-
-	for _, osknode := range osknodes {
-
-		isExtra := true
-		for _, k8snode := range k8snodes {
-			if osknode.Name == k8snode.Name {
-				isExtra = false
-				break
-			}
-		}
-
-		if isExtra {
-			err := client.Delete(context.Background(), &osknode)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func syncOskNodes(k8snodes []core.Node, osknodes []v1alpha1.OpenstackNode, kupenstackConfig string) error {
-
-	for _, k8snode := range k8snodes {
-
-		foundAt := -1
-		for i, osknode := range osknodes {
-			if osknode.Name == k8snode.Name {
-				foundAt = i
-				break
-			}
-		}
-
-		if foundAt == -1 {
-			// create
-			err := createOskNode(k8snode, kupenstackConfig)
-			if err != nil {
-				return err
-			}
-		} else {
-			// check for update
-			err := syncOskNode(k8snode, osknodes[foundAt], kupenstackConfig)
-			if err != nil {
-				return err
-			}
-		}
-
-	}
-
-	return nil
-}
-
-func createOskNode(node core.Node, kupenstackConfig string) error {
-
-	cfg, err := ReadKupenStackConfiguration(kupenstackConfig)
-	if err != nil {
-		return err
-	}
-
-	nodeRole := desiredNodeRole(node, cfg)
+// NewOskNode returns osknode desired for kubernetes node as per
+// KupenstackConfiguration.
+func NewOskNode(node core.Node, cfg v1alpha1.KupenstackConfiguration) v1alpha1.OpenstackNode {
 
 	newNode := v1alpha1.OpenstackNode{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: node.Name,
 			Annotations: map[string]string{
-				"node-role": nodeRole,
+				"node-role": desiredNodeRole(node, cfg),
 			},
 		},
 		Spec: v1alpha1.OpenstackNodeSpec{
-			Occp: cfg.Spec.DefaultProfile,
+			Occp: desiredNodeProfile(node, cfg),
 		},
 	}
+	if isNodeDisabled(node, cfg) {
+		newNode.Annotations[osknodeutils.DisabledAnnotation] = "true"
+	}
 
-	return client.Create(context.Background(), &newNode)
+	return newNode
 }
 
-func syncOskNode(node core.Node, osknode v1alpha1.OpenstackNode, kupenstackConfig string) error {
-
-	cfg, err := ReadKupenStackConfiguration(kupenstackConfig)
-	if err != nil {
-		return err
-	}
+// SyncOskNode sets role, profile and disabled state of osknode as desired
+// for kubernetes node. Returns whether osknode was changed.
+func SyncOskNode(osknode *v1alpha1.OpenstackNode, node core.Node, cfg v1alpha1.KupenstackConfiguration) bool {
 
 	nodeRole := desiredNodeRole(node, cfg)
+	nodeProfile := desiredNodeProfile(node, cfg)
+	disabled := isNodeDisabled(node, cfg)
 
-	if osknode.Annotations["node-role"] != nodeRole ||
-		osknode.Spec.Occp.Name != cfg.Spec.DefaultProfile.Name ||
-		osknode.Spec.Occp.Namespace != cfg.Spec.DefaultProfile.Namespace {
-
-		if osknode.Annotations == nil {
-			osknode.Annotations = make(map[string]string)
-		}
-		osknode.Annotations["node-role"] = nodeRole
-		osknode.Spec.Occp.Name = cfg.Spec.DefaultProfile.Name
-		osknode.Spec.Occp.Namespace = cfg.Spec.DefaultProfile.Namespace
+	if osknode.Annotations["node-role"] == nodeRole &&
+		osknode.Spec.Occp == nodeProfile &&
+		(osknode.Annotations[osknodeutils.DisabledAnnotation] == "true") == disabled {
+		return false
+	}
 
-		return client.Update(context.Background(), &osknode)
+	if osknode.Annotations == nil {
+		osknode.Annotations = make(map[string]string)
+	}
+	osknode.Annotations["node-role"] = nodeRole
+	osknode.Spec.Occp = nodeProfile
+	if disabled {
+		osknode.Annotations[osknodeutils.DisabledAnnotation] = "true"
+	} else {
+		delete(osknode.Annotations, osknodeutils.DisabledAnnotation)
 	}
 
-	return nil
+	return true
 }
 
-func desiredNodeRole(node core.Node, cfg ksk.KupenstackConfiguration) string {
+func desiredNodeRole(node core.Node, cfg v1alpha1.KupenstackConfiguration) string {
 	nodeRole := "compute"
 	_, controlplane := node.Labels["node-role.kubernetes.io/control-plane"]
 	_, master := node.Labels["node-role.kubernetes.io/master"]
@@ -183,6 +67,13 @@ func desiredNodeRole(node core.Node, cfg ksk.KupenstackConfiguration) string {
 		nodeRole = "control"
 	}
 
+	for _, rule := range matchingNodeRules(node, cfg) {
+		if rule.Type != "" {
+			nodeRole = rule.Type
+			break
+		}
+	}
+
 	for _, n := range cfg.Spec.Nodes {
 		if n.Name == node.Name {
 			if n.Type != "" {
@@ -193,3 +84,51 @@ func desiredNodeRole(node core.Node, cfg ksk.KupenstackConfiguration) string {
 
 	return nodeRole
 }
+
+// isNodeDisabled returns whether node is disabled in KupenstackConfiguration.
+func isNodeDisabled(node core.Node, cfg v1alpha1.KupenstackConfiguration) bool {
+	for _, n := range cfg.Spec.Nodes {
+		if n.Name == node.Name {
+			return n.Disabled
+		}
+	}
+	return false
+}
+
+// desiredNodeProfile returns profile set for node in KupenstackConfiguration,
+// either by name or by first matching node rule, or default profile when
+// node has none.
+func desiredNodeProfile(node core.Node, cfg v1alpha1.KupenstackConfiguration) v1alpha1.OccpRef {
+	for _, n := range cfg.Spec.Nodes {
+		if n.Name == node.Name && n.Profile != nil {
+			return *n.Profile
+		}
+	}
+
+	for _, rule := range matchingNodeRules(node, cfg) {
+		if rule.Profile != nil {
+			return *rule.Profile
+		}
+	}
+
+	return cfg.Spec.DefaultProfile
+}
+
+// matchingNodeRules returns node rules whose selector matches labels of node,
+// in order of their definition.
+func matchingNodeRules(node core.Node, cfg v1alpha1.KupenstackConfiguration) []v1alpha1.NodeRule {
+
+	var rules []v1alpha1.NodeRule
+	for _, rule := range cfg.Spec.NodeRules {
+		selector, err := nodeSelector(rule)
+		if err != nil {
+			// Selectors are validated on reading configuration.
+			continue
+		}
+		if selector.Matches(labels.Set(node.Labels)) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,211 @@
+package oskops_test
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/oskops"
+	osknodeutils "github.com/kupenstack/kupenstack/pkg/kupenstack/osknode"
+)
+
+func node(name string, labels map[string]string) core.Node {
+	return core.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func profileRef(name string) *v1alpha1.OccpRef {
+	return &v1alpha1.OccpRef{Name: name, Namespace: "default"}
+}
+
+// config returns configuration with default profile `default` and rules.
+func config(rules ...v1alpha1.NodeRule) v1alpha1.KupenstackConfiguration {
+	return v1alpha1.KupenstackConfiguration{Spec: v1alpha1.KupenstackConfigurationSpec{
+		DefaultProfile: *profileRef("default"),
+		NodeRules:      rules,
+	}}
+}
+
+func matchLabels(labels map[string]string) metav1.LabelSelector {
+	return metav1.LabelSelector{MatchLabels: labels}
+}
+
+func TestNewOskNode(t *testing.T) {
+
+	gpu := map[string]string{"gpu": "true"}
+	controlPlane := map[string]string{"node-role.kubernetes.io/control-plane": ""}
+
+	tests := []struct {
+		name     string
+		node     core.Node
+		cfg      v1alpha1.KupenstackConfiguration
+		role     string
+		profile  string
+		disabled bool
+	}{
+		{
+			name:    "no rules",
+			node:    node("node-1", nil),
+			cfg:     config(),
+			role:    "compute",
+			profile: "default",
+		},
+		{
+			name:    "control plane without rules",
+			node:    node("node-1", controlPlane),
+			cfg:     config(),
+			role:    "control",
+			profile: "default",
+		},
+		{
+			name:    "matching rule",
+			node:    node("node-1", gpu),
+			cfg:     config(v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "compute", Profile: profileRef("gpu")}),
+			role:    "compute",
+			profile: "gpu",
+		},
+		{
+			name:    "rule not matching",
+			node:    node("node-1", controlPlane),
+			cfg:     config(v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "compute", Profile: profileRef("gpu")}),
+			role:    "control",
+			profile: "default",
+		},
+		{
+			name:    "empty selector matches all nodes",
+			node:    node("node-1", nil),
+			cfg:     config(v1alpha1.NodeRule{Type: "control,compute"}),
+			role:    "control,compute",
+			profile: "default",
+		},
+		{
+			name: "match expressions",
+			node: node("node-1", map[string]string{"zone": "b"}),
+			cfg: config(v1alpha1.NodeRule{
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+				}},
+				Profile: profileRef("zoned"),
+			}),
+			role:    "compute",
+			profile: "zoned",
+		},
+		{
+			name: "first matching rule setting a field decides it",
+			node: node("node-1", gpu),
+			cfg: config(
+				v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "control"},
+				v1alpha1.NodeRule{Type: "compute", Profile: profileRef("gpu")},
+				v1alpha1.NodeRule{Profile: profileRef("other")},
+			),
+			role:    "control",
+			profile: "gpu",
+		},
+		{
+			name: "invalid selector is skipped",
+			node: node("node-1", gpu),
+			cfg: config(
+				v1alpha1.NodeRule{Selector: matchLabels(map[string]string{"gpu": "not valid"}), Profile: profileRef("invalid")},
+				v1alpha1.NodeRule{Selector: matchLabels(gpu), Profile: profileRef("gpu")},
+			),
+			role:    "compute",
+			profile: "gpu",
+		},
+		{
+			name: "node entry takes precedence over rules",
+			node: node("node-1", gpu),
+			cfg: func() v1alpha1.KupenstackConfiguration {
+				cfg := config(v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "compute", Profile: profileRef("gpu")})
+				cfg.Spec.Nodes = []v1alpha1.NodeConfiguration{
+					{Name: "node-2", Type: "compute", Profile: profileRef("other")},
+					{Name: "node-1", Type: "control", Profile: profileRef("node"), Disabled: true},
+				}
+				return cfg
+			}(),
+			role:     "control",
+			profile:  "node",
+			disabled: true,
+		},
+		{
+			name: "node entry without fields keeps rules",
+			node: node("node-1", gpu),
+			cfg: func() v1alpha1.KupenstackConfiguration {
+				cfg := config(v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "control", Profile: profileRef("gpu")})
+				cfg.Spec.Nodes = []v1alpha1.NodeConfiguration{{Name: "node-1"}}
+				return cfg
+			}(),
+			role:    "control",
+			profile: "gpu",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			osknode := oskops.NewOskNode(test.node, test.cfg)
+			if osknode.Name != test.node.Name {
+				t.Errorf("expected osknode %s, got %s", test.node.Name, osknode.Name)
+			}
+			if role := osknode.Annotations["node-role"]; role != test.role {
+				t.Errorf("expected role %q, got %q", test.role, role)
+			}
+			if osknode.Spec.Occp != *profileRef(test.profile) {
+				t.Errorf("expected profile %s, got %+v", test.profile, osknode.Spec.Occp)
+			}
+			if disabled := osknode.Annotations[osknodeutils.DisabledAnnotation] == "true"; disabled != test.disabled {
+				t.Errorf("expected disabled %v, got %v", test.disabled, disabled)
+			}
+		})
+	}
+}
+
+// Osknode follows labels of its node and changes of node rules.
+func TestSyncOskNode(t *testing.T) {
+
+	gpu := map[string]string{"gpu": "true"}
+	cfg := config(v1alpha1.NodeRule{Selector: matchLabels(gpu), Type: "control,compute", Profile: profileRef("gpu")})
+
+	n := node("node-1", nil)
+	osknode := oskops.NewOskNode(n, cfg)
+	if oskops.SyncOskNode(&osknode, n, cfg) {
+		t.Errorf("expected new osknode in sync, got changed")
+	}
+
+	// node labelled to match rule
+	n.Labels = gpu
+	if !oskops.SyncOskNode(&osknode, n, cfg) {
+		t.Fatalf("expected osknode changed when node matches rule")
+	}
+	if osknode.Annotations["node-role"] != "control,compute" || osknode.Spec.Occp != *profileRef("gpu") {
+		t.Errorf("expected role and profile of rule, got %v %+v", osknode.Annotations, osknode.Spec.Occp)
+	}
+	if oskops.SyncOskNode(&osknode, n, cfg) {
+		t.Errorf("expected synced osknode unchanged")
+	}
+
+	// rule removed, node disabled
+	cfg.Spec.NodeRules = nil
+	cfg.Spec.Nodes = []v1alpha1.NodeConfiguration{{Name: "node-1", Disabled: true}}
+	if !oskops.SyncOskNode(&osknode, n, cfg) {
+		t.Fatalf("expected osknode changed when rule is removed")
+	}
+	if osknode.Annotations["node-role"] != "compute" || osknode.Spec.Occp != *profileRef("default") ||
+		osknode.Annotations[osknodeutils.DisabledAnnotation] != "true" {
+		t.Errorf("expected disabled compute node with default profile, got %v %+v", osknode.Annotations, osknode.Spec.Occp)
+	}
+
+	// node enabled again
+	cfg.Spec.Nodes = nil
+	if !oskops.SyncOskNode(&osknode, n, cfg) {
+		t.Fatalf("expected osknode changed when node is enabled")
+	}
+	if _, ok := osknode.Annotations[osknodeutils.DisabledAnnotation]; ok {
+		t.Errorf("expected disabled annotation removed, got %v", osknode.Annotations)
+	}
+
+	// osknode without annotations, e.g. created by hand
+	osknode = v1alpha1.OpenstackNode{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	if !oskops.SyncOskNode(&osknode, n, cfg) || osknode.Annotations["node-role"] != "compute" {
+		t.Errorf("expected role set on osknode without annotations, got %v", osknode.Annotations)
+	}
+}
@@ -2,10 +2,12 @@ package helm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -20,9 +22,12 @@ import (
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/kupenstack/kupenstack/pkg/metrics"
 )
 
 var settings *cli.EnvSettings = cli.New()
@@ -160,7 +165,9 @@ func UpdateHelmRepos() error {
 }
 
 // UpgradeRelease upgrades a existing release or creates it if not exists.
-func UpgradeRelease(name, repo, chart, namespace string, vals map[string]interface{}) (*release.Release, error) {
+// It returns an error without starting when ctx is done or operations are
+// shut down.
+func UpgradeRelease(ctx context.Context, name, repo, chart, namespace string, vals map[string]interface{}) (*release.Release, error) {
 	cfg := new(action.Configuration)
 	if err := cfg.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), debug); err != nil {
 		return nil, err
@@ -197,14 +204,31 @@ func UpgradeRelease(name, repo, chart, namespace string, vals map[string]interfa
 	upgradeClient.Install = true
 	upgradeClient.DryRun = false
 
+	err = begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	start := time.Now()
 	result, err := upgradeClient.Run(name, chartRequested, vals)
 
 	if isReleaseDoesNotExistsErrorWithName(name, err) {
-		return installRelease(cfg, name, namespace, vals, chartRequested)
+		start = time.Now()
+		result, err = installRelease(cfg, name, namespace, vals, chartRequested)
+		observe(chart, "install", start, err)
+		return result, err
 	}
+	observe(chart, "upgrade", start, err)
 	return result, err
 }
 
+// observe records helm `operation` on `chart` started at `start` in metrics.
+func observe(chart, operation string, start time.Time, err error) {
+	metrics.HelmOperations.WithLabelValues(chart, operation, metrics.Result(err)).Inc()
+	metrics.HelmOperationDuration.WithLabelValues(chart, operation).Observe(time.Since(start).Seconds())
+}
+
 func checkDependencies(helmChart *chart.Chart, chartPath string, client *action.Upgrade) error {
 	req := helmChart.Metadata.Dependencies
 	if req == nil {
@@ -262,8 +286,9 @@ func installRelease(cfg *action.Configuration, name, namespace string, vals map[
 
 }
 
-// DeleteRelease will delete the given release
-func DeleteRelease(name, namespace string) error {
+// DeleteRelease will delete the given release. Like UpgradeRelease it is
+// not started when ctx is done or operations are shut down.
+func DeleteRelease(ctx context.Context, name, namespace string) error {
 
 	actionConfig := new(action.Configuration)
 	err := actionConfig.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), debug)
@@ -278,7 +303,22 @@ func DeleteRelease(name, namespace string) error {
 	}
 
 	client := action.NewUninstall(actionConfig)
-	_, err = client.Run(name)
+
+	err = begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	start := time.Now()
+	resp, err := client.Run(name)
+
+	chartName := "unknown"
+	if resp != nil && resp.Release != nil && resp.Release.Chart != nil && resp.Release.Chart.Metadata != nil {
+		chartName = resp.Release.Chart.Metadata.Name
+	}
+	observe(chartName, "uninstall", start, err)
+
 	if err != nil {
 		return err
 	}
@@ -313,3 +353,127 @@ func GetRelease(name, namespace string) (*release.Release, error) {
 	}
 	return nil, nil
 }
+
+// ValuesChanged returns true when `vals` differ from values `rel` was
+// installed or last upgraded with.
+func ValuesChanged(rel *release.Release, vals map[string]interface{}) (bool, error) {
+
+	// Values of release are read back from JSON, so numbers of `vals`
+	// are compared as JSON too.
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return false, err
+	}
+	var desired map[string]interface{}
+	err = json.Unmarshal(data, &desired)
+	if err != nil {
+		return false, err
+	}
+
+	if len(desired) == 0 && len(rel.Config) == 0 {
+		return false, nil
+	}
+	return !reflect.DeepEqual(desired, rel.Config), nil
+}
+
+// Charts loaded by GetChart, keyed by `<repo>/<name>`.
+var charts = struct {
+	sync.Mutex
+	loaded map[string]loadedChart
+}{loaded: make(map[string]loadedChart)}
+
+type loadedChart struct {
+	// Modification time of index of repository chart was located in.
+	index time.Time
+	chart *chart.Chart
+}
+
+// GetChart returns chart `name` from helm repository `repo`. Chart is
+// downloaded once, and again only after index of repository is updated.
+// Returned chart is shared and must not be modified.
+func GetChart(repo, name string) (*chart.Chart, error) {
+
+	key := fmt.Sprintf("%s/%s", repo, name)
+	indexFile, err := os.Stat(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(repo)))
+	if err != nil {
+		return nil, err
+	}
+
+	charts.Lock()
+	cached, ok := charts.loaded[key]
+	charts.Unlock()
+	if ok && cached.index.Equal(indexFile.ModTime()) {
+		return cached.chart, nil
+	}
+
+	var pathOptions action.ChartPathOptions
+	chartPath, err := pathOptions.LocateChart(key, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+
+	charts.Lock()
+	charts.loaded[key] = loadedChart{index: indexFile.ModTime(), chart: ch}
+	charts.Unlock()
+	return ch, nil
+}
+
+// Indexes of repositories read by ChartVersion, keyed by repository.
+var indexes = struct {
+	sync.Mutex
+	loaded map[string]loadedIndex
+}{loaded: make(map[string]loadedIndex)}
+
+type loadedIndex struct {
+	modified time.Time
+	index    *repo.IndexFile
+}
+
+// ChartVersion returns newest version of chart `name` in helm repository
+// `repoName`, i.e. version UpgradeRelease installs. Chart is not
+// downloaded, and index of repository is read again only after it is
+// updated.
+func ChartVersion(repoName, name string) (string, error) {
+
+	path := filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(repoName))
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	indexes.Lock()
+	defer indexes.Unlock()
+
+	cached, ok := indexes.loaded[repoName]
+	if !ok || !cached.modified.Equal(info.ModTime()) {
+		index, err := repo.LoadIndexFile(path)
+		if err != nil {
+			return "", err
+		}
+		cached = loadedIndex{modified: info.ModTime(), index: index}
+		indexes.loaded[repoName] = cached
+	}
+
+	version, err := cached.index.Get(name, "")
+	if err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// GetChartValues returns default values of `chart` from helm repository
+// `repo`. Like the chart, values are shared and must not be modified.
+func GetChartValues(repo, chart string) (map[string]interface{}, error) {
+
+	chartRequested, err := GetChart(repo, chart)
+	if err != nil {
+		return nil, err
+	}
+
+	return chartRequested.Values, nil
+}
@@ -0,0 +1,81 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MaxConcurrentOperations limits install, upgrade and uninstall operations
+// running at same time, so that a shutdown never has to wait for more
+// than these many operations.
+const MaxConcurrentOperations = 4
+
+// Holds a value for every operation in flight.
+var operations = make(chan struct{}, MaxConcurrentOperations)
+
+// Closed by Shutdown, after which no operation is started.
+var (
+	stopping     = make(chan struct{})
+	stoppingOnce sync.Once
+)
+
+// ErrShutdown is returned for operations requested after Shutdown.
+var ErrShutdown = errors.New("helm operations are shut down")
+
+// begin blocks until an operation may start. It returns an error instead
+// when ctx is done or Shutdown is called first.
+func begin(ctx context.Context) error {
+
+	select {
+	case <-stopping:
+		return ErrShutdown
+	default:
+	}
+
+	select {
+	case operations <- struct{}{}:
+	case <-stopping:
+		return ErrShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Shutdown may have been called while waiting.
+	select {
+	case <-stopping:
+		end()
+		return ErrShutdown
+	default:
+	}
+	return nil
+}
+
+func end() {
+	<-operations
+}
+
+// Shutdown refuses all operations not yet started. Operations in flight
+// continue, Wait waits for them.
+func Shutdown() {
+	stoppingOnce.Do(func() { close(stopping) })
+}
+
+// Wait blocks until operations in flight are finished, or ctx is done.
+// Helm operations can not be cancelled midway, so process should not exit
+// before they finish to not leave releases in pending state.
+func Wait(ctx context.Context) error {
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for len(operations) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
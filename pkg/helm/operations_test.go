@@ -0,0 +1,37 @@
+package helm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBegin(t *testing.T) {
+
+	for i := 0; i < MaxConcurrentOperations; i++ {
+		if err := begin(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// All operations are in flight, next one waits until ctx is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := begin(ctx); err != context.Canceled {
+		t.Errorf("expected context error when no operation may start, got %v", err)
+	}
+
+	for i := 0; i < MaxConcurrentOperations; i++ {
+		end()
+	}
+	if err := Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	Shutdown()
+	if err := begin(context.Background()); err != ErrShutdown {
+		t.Errorf("expected operations refused after shutdown, got %v", err)
+	}
+	if len(operations) != 0 {
+		t.Errorf("expected no operation in flight, got %d", len(operations))
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chartmap translates typed sections of OpenStackCloudConfigurationProfile
+// to values of openstack-helm charts, using a table of field paths per chart
+// version.
+package chartmap
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed charts.yaml
+var defaultTable []byte
+
+// Default is the table embedded in kupenstack, read from charts.yaml.
+var Default Table
+
+func init() {
+	var err error
+	Default, err = Parse(defaultTable)
+	if err != nil {
+		panic(fmt.Sprintf("invalid charts.yaml: %s", err))
+	}
+}
+
+// Table holds mappings of each chart, ordered from newest chart version.
+type Table map[string][]Mapping
+
+// Mapping translates fields of a profile section for chart versions
+// matching Versions.
+type Mapping struct {
+
+	// Semver constraint on chart version, e.g. `>= 0.2.0`.
+	Versions string `json:"versions"`
+
+	// Field path in profile section to path in chart values.
+	Fields map[string]string `json:"fields"`
+
+	// Values set at chart paths whenever field is given and not empty.
+	Implies map[string]map[string]interface{} `json:"implies,omitempty"`
+
+	constraint *semver.Constraints
+}
+
+// Parse reads table from YAML document.
+func Parse(data []byte) (Table, error) {
+
+	var table Table
+	err := yaml.Unmarshal(data, &table)
+	if err != nil {
+		return nil, err
+	}
+
+	for chart, mappings := range table {
+		for i := range mappings {
+			mappings[i].constraint, err = semver.NewConstraint(mappings[i].Versions)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: invalid versions: %s", chart, i, err)
+			}
+			for field, path := range mappings[i].Fields {
+				if strings.HasSuffix(field, ".*") != strings.HasSuffix(path, ".*") {
+					return nil, fmt.Errorf("%s[%d]: field %s and path %s must both end with .* or not", chart, i, field, path)
+				}
+			}
+		}
+	}
+	return table, nil
+}
+
+// Lookup returns mapping of `chart` at `version`. When version is empty,
+// mapping of newest version is returned.
+func (t Table) Lookup(chart, version string) (*Mapping, error) {
+
+	mappings, ok := t[chart]
+	if !ok || len(mappings) == 0 {
+		return nil, fmt.Errorf("no mapping for chart %s", chart)
+	}
+	if version == "" {
+		return &mappings[0], nil
+	}
+
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q of chart %s: %s", version, chart, err)
+	}
+	for i := range mappings {
+		if mappings[i].constraint.Check(v) {
+			return &mappings[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no mapping for chart %s version %s", chart, version)
+}
+
+// ChartPath returns path in chart values of `field` of profile section,
+// and whether field is mapped. Unmapped fields keep their path.
+func (m *Mapping) ChartPath(field string) (string, bool) {
+
+	if path, ok := m.Fields[field]; ok {
+		return path, true
+	}
+
+	i := strings.LastIndex(field, ".")
+	if i < 0 {
+		return field, false
+	}
+	if path, ok := m.Fields[field[:i]+".*"]; ok {
+		return strings.TrimSuffix(path, "*") + field[i+1:], true
+	}
+	return field, false
+}
+
+// Apply returns chart values for profile `section`. Section is not modified.
+func (m *Mapping) Apply(section map[string]interface{}) (map[string]interface{}, error) {
+	vals := make(map[string]interface{})
+	err := m.apply(vals, "", section)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(m.Implies))
+	for field := range m.Implies {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		val, ok := GetPath(section, field)
+		if !ok || isEmpty(val) {
+			continue
+		}
+		for _, path := range sortedKeys(m.Implies[field]) {
+			err := setPath(vals, path, m.Implies[field][path])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return vals, nil
+}
+
+func isEmpty(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func (m *Mapping) apply(vals map[string]interface{}, prefix string, section map[string]interface{}) error {
+
+	for _, key := range sortedKeys(section) {
+		field := prefix + key
+		val := section[key]
+
+		if path, ok := m.ChartPath(field); ok {
+			err := setPath(vals, path, val)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Descend only into maps holding mapped fields.
+		if nested, ok := val.(map[string]interface{}); ok && m.hasFieldsUnder(field) {
+			err := m.apply(vals, field+".", nested)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := setPath(vals, field, val)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mapping) hasFieldsUnder(field string) bool {
+	for f := range m.Fields {
+		if strings.HasPrefix(f, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// setPath sets `val` at dot separated `path` of vals, creating maps on the way.
+func setPath(vals map[string]interface{}, path string, val interface{}) error {
+
+	keys := strings.Split(path, ".")
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := vals[key]
+		if !ok {
+			next = make(map[string]interface{})
+			vals[key] = next
+		}
+		nextMap, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set %s: %s is not a map", path, strings.Join(keys[:i+1], "."))
+		}
+		vals = nextMap
+	}
+
+	last := keys[len(keys)-1]
+	if _, ok := vals[last]; ok {
+		return fmt.Errorf("cannot set %s: more than one field maps to it", path)
+	}
+	vals[last] = val
+	return nil
+}
+
+// GetPath returns value at dot separated `path` of vals.
+func GetPath(vals map[string]interface{}, path string) (interface{}, bool) {
+
+	var val interface{} = vals
+	for _, key := range strings.Split(path, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		val, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartmap_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/chartmap"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+func parse(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+	var out map[string]interface{}
+	if err := yaml.Unmarshal([]byte(data), &out); err != nil {
+		t.Fatalf("invalid test data: %s", err)
+	}
+	return out
+}
+
+func TestDefaultTableCoversComponents(t *testing.T) {
+	for _, component := range occp.Components {
+		if _, err := chartmap.Default.Lookup(component, ""); err != nil {
+			t.Errorf("component %s: %s", component, err)
+		}
+	}
+}
+
+// Default table covers 0.x charts only, so that a chart of another major
+// version is refused instead of being configured with stale paths.
+func TestDefaultTableVersions(t *testing.T) {
+	for _, component := range occp.Components {
+		if _, err := chartmap.Default.Lookup(component, "0.2.5"); err != nil {
+			t.Errorf("component %s: %s", component, err)
+		}
+		if _, err := chartmap.Default.Lookup(component, "1.0.0"); err == nil {
+			t.Errorf("component %s: expected no mapping for chart version 1.0.0", component)
+		}
+	}
+}
+
+// Every replica field of profile must map to a key of `pod.replicas`, named
+// as in charts, i.e. in snake case.
+func TestDefaultTableMapsTypedReplicas(t *testing.T) {
+
+	spec := reflect.TypeOf(clusterv1alpha1.OpenStackCloudConfigurationProfileSpec{})
+	for i := 0; i < spec.NumField(); i++ {
+		component := jsonName(spec.Field(i))
+		section := spec.Field(i).Type
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+
+		mapping, err := chartmap.Default.Lookup(component, "")
+		if err != nil {
+			t.Errorf("component %s: %s", component, err)
+			continue
+		}
+
+		replicas, ok := section.FieldByName("Replicas")
+		if !ok {
+			continue
+		}
+		for j := 0; j < replicas.Type.NumField(); j++ {
+			field := "replicas." + jsonName(replicas.Type.Field(j))
+			path, ok := mapping.ChartPath(field)
+			if !ok {
+				t.Errorf("%s.%s is not mapped", component, field)
+				continue
+			}
+			if !strings.HasPrefix(path, "pod.replicas.") || strings.ToLower(path) != path {
+				t.Errorf("%s.%s maps to %s, expected a snake case key of pod.replicas", component, field, path)
+			}
+		}
+	}
+}
+
+// Resources, tolerations and affinity of every component must map to `pod`
+// values of its chart.
+func TestDefaultTableMapsPodPlacement(t *testing.T) {
+
+	for _, component := range occp.Components {
+		mapping, err := chartmap.Default.Lookup(component, "")
+		if err != nil {
+			t.Errorf("component %s: %s", component, err)
+			continue
+		}
+
+		for _, field := range []string{"resources.api", "tolerations", "affinity.anti.type", "affinity.anti.topologyKey", "affinity.anti.weight"} {
+			path, ok := mapping.ChartPath(field)
+			if !ok || !strings.HasPrefix(path, "pod.") {
+				t.Errorf("%s.%s maps to %s, expected a path under pod", component, field, path)
+			}
+		}
+		if path, _ := mapping.ChartPath("tolerations"); path != "pod.tolerations."+component+".tolerations" {
+			t.Errorf("%s.tolerations maps to %s", component, path)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+
+	tests := []struct {
+		component string
+		section   string
+		want      string
+	}{
+		{
+			component: "nova",
+			section: `
+disable: false
+replicas: {metadata: 2, ironic: 0, osapi: 3, conductor: 1}
+conf: {nova: {libvirt: {virt_type: qemu}}}`,
+			want: `
+disable: false
+pod: {replicas: {api_metadata: 2, compute_ironic: 0, osapi: 3, conductor: 1}}
+conf: {nova: {libvirt: {virt_type: qemu}}}`,
+		},
+		{
+			component: "neutron",
+			section:   `replicas: {server: 1, ironicAgent: 2}`,
+			want:      `pod: {replicas: {server: 1, ironic_agent: 2}}`,
+		},
+		{
+			component: "ingress",
+			section:   `replicas: {ingress: 2, errorPage: 1}`,
+			want:      `pod: {replicas: {ingress: 2, error_page: 1}}`,
+		},
+		{
+			component: "keystone",
+			section: `
+replicas: {api: 1}
+values: {pod: {replicas: {api: 3}}, endpoints: {identity: {port: {api: {public: 443}}}}}`,
+			want: `
+pod: {replicas: {api: 1}}
+values: {pod: {replicas: {api: 3}}, endpoints: {identity: {port: {api: {public: 443}}}}}`,
+		},
+		{
+			component: "glance",
+			section:   `replicas: {}`,
+			want:      `{}`,
+		},
+		{
+			component: "nova",
+			section: `
+resources:
+  api_metadata: {requests: {cpu: 100m, memory: 128Mi}, limits: {memory: 1Gi}}
+  conductor: {requests: {cpu: 200m}}
+tolerations:
+- {key: node-role.kubernetes.io/master, operator: Exists, effect: NoSchedule}
+affinity:
+  anti: {type: requiredDuringSchedulingIgnoredDuringExecution, topologyKey: topology.kubernetes.io/zone, weight: 20}`,
+			want: `
+pod:
+  resources:
+    enabled: true
+    api_metadata: {requests: {cpu: 100m, memory: 128Mi}, limits: {memory: 1Gi}}
+    conductor: {requests: {cpu: 200m}}
+  tolerations:
+    nova:
+      enabled: true
+      tolerations:
+      - {key: node-role.kubernetes.io/master, operator: Exists, effect: NoSchedule}
+  affinity:
+    anti:
+      type: {default: requiredDuringSchedulingIgnoredDuringExecution}
+      topologyKey: {default: topology.kubernetes.io/zone}
+      weight: {default: 20}`,
+		},
+		{
+			component: "mariadb",
+			section:   `{replicas: {server: 3}, resources: {}, tolerations: []}`,
+			want:      `{pod: {replicas: {server: 3}, tolerations: {mariadb: {tolerations: []}}}}`,
+		},
+		{
+			component: "libvirt",
+			section:   `conf: {qemu: {max_files: 32768}}`,
+			want:      `conf: {qemu: {max_files: 32768}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.component, func(t *testing.T) {
+			mapping, err := chartmap.Default.Lookup(tt.component, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			section := parse(t, tt.section)
+			got, err := mapping.Apply(section)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := parse(t, tt.want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("values mismatch\n got: %v\nwant: %v", got, want)
+			}
+			if !reflect.DeepEqual(section, parse(t, tt.section)) {
+				t.Fatalf("section was modified: %v", section)
+			}
+		})
+	}
+}
+
+const versionedTable = `
+nova:
+- versions: ">= 0.3.0"
+  fields:
+    replicas.*: pod.replicas.*
+    replicas.metadata: pod.replicas.api_metadata
+- versions: "< 0.3.0"
+  fields:
+    replicas.*: pod.replicas.*
+    replicas.metadata: pod.replicas.metadata
+`
+
+func TestLookupVersions(t *testing.T) {
+
+	table, err := chartmap.Parse([]byte(versionedTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: "0.3.2", want: "pod.replicas.api_metadata"},
+		{version: "0.3.0", want: "pod.replicas.api_metadata"},
+		{version: "0.2.9", want: "pod.replicas.metadata"},
+		{version: "", want: "pod.replicas.api_metadata"},
+		{version: "not-a-version", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mapping, err := table.Lookup("nova", tt.version)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("version %q: expected error", tt.version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("version %q: %s", tt.version, err)
+			continue
+		}
+		if got, _ := mapping.ChartPath("replicas.metadata"); got != tt.want {
+			t.Errorf("version %q: replicas.metadata maps to %s, want %s", tt.version, got, tt.want)
+		}
+	}
+
+	if _, err := table.Lookup("glance", "0.3.0"); err == nil {
+		t.Errorf("expected error for chart without mapping")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := map[string]string{
+		"invalid constraint": `
+nova:
+- versions: "> > 1"
+  fields: {}`,
+		"wildcard mismatch": `
+nova:
+- versions: "*"
+  fields:
+    replicas.*: pod.replicas`,
+	}
+
+	for name, table := range tests {
+		if _, err := chartmap.Parse([]byte(table)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+
+	table, err := chartmap.Parse([]byte(`
+nova:
+- versions: "*"
+  fields:
+    replicas.metadata: pod.replicas.api_metadata
+    replicas.apiMetadata: pod.replicas.api_metadata
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := table.Lookup("nova", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mapping.Apply(parse(t, `replicas: {metadata: 1, apiMetadata: 2}`))
+	if err == nil {
+		t.Fatalf("expected error for two fields mapped to same path")
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
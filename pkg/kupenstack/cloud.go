@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kupenstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/helm"
+)
+
+const (
+	// Namespace where components of default cloud are deployed.
+	DefaultCloudNamespace = "kupenstack"
+
+	// Node selector value used by openstack-helm charts of default cloud.
+	DefaultNodeSelectorValue = "enabled"
+
+	// Maximum length of namespaces and label values.
+	maxNameLength = 63
+)
+
+// Cloud is one OpenStack deployment managed by kupenstack. Every OCCP
+// referenced by OpenstackNodes yields an independent cloud with its own
+// namespace, helm releases and keystone.
+type Cloud struct {
+
+	// Name of cloud. It is same as name of its profile `<occp-name>.<occp-namespace>`.
+	Name string
+
+	// Kubernetes namespace in which all components of cloud are deployed.
+	Namespace string
+
+	// Whether cloud is generated from default profile of KupenstackConfiguration.
+	Default bool
+}
+
+// CloudName returns name of cloud generated from OCCP referenced by `ref`.
+func CloudName(ref clusterv1alpha1.OccpRef) string {
+	return ref.Name + "." + ref.Namespace
+}
+
+// NewCloud returns cloud for OCCP `profile`. Default cloud keeps the
+// `kupenstack` namespace and release names, so that existing deployments
+// are not disturbed.
+func NewCloud(profile, defaultProfile clusterv1alpha1.OccpRef) Cloud {
+
+	if profile == defaultProfile {
+		return Cloud{
+			Name:      CloudName(profile),
+			Namespace: DefaultCloudNamespace,
+			Default:   true,
+		}
+	}
+
+	name := CloudName(profile)
+	namespace := DefaultCloudNamespace + "-" + strings.ReplaceAll(profile.Name, ".", "-") + "-" + profile.Namespace
+
+	return Cloud{
+		Name:      name,
+		Namespace: bounded(namespace, name),
+	}
+}
+
+// bounded returns s if it fits in maxNameLength, otherwise s truncated and
+// suffixed with a short hash of `name`, so that names of different clouds
+// never collide.
+func bounded(s, name string) string {
+
+	if len(s) <= maxNameLength {
+		return s
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+
+	// Truncated value must end with an alphanumeric character.
+	s = strings.TrimRight(s[:maxNameLength-len(hash)-1], "-_.")
+	return s + "-" + hash
+}
+
+// ReleaseName returns name of helm release for component in this cloud.
+func (c Cloud) ReleaseName(component string) string {
+	if c.Default {
+		return component
+	}
+	return component + "-" + c.Namespace
+}
+
+// NodeSelectorValue returns value of openstack node labels, such as
+// `openstack-control-plane`, for nodes that belong to this cloud. It is
+// name of cloud, bounded to length of a label value.
+func (c Cloud) NodeSelectorValue() string {
+	if c.Default {
+		return DefaultNodeSelectorValue
+	}
+	return bounded(c.Name, c.Name)
+}
+
+// Profile returns reference to OCCP from which cloud is generated.
+func (c Cloud) Profile() clusterv1alpha1.OccpRef {
+	// Namespaces cannot contain dots, so the last one separates it.
+	i := strings.LastIndex(c.Name, ".")
+	return clusterv1alpha1.OccpRef{
+		Name:      c.Name[:i],
+		Namespace: c.Name[i+1:],
+	}
+}
+
+// AdminAuthOptions returns credentials of keystone admin of this cloud.
+func (c Cloud) AdminAuthOptions() *gophercloud.AuthOptions {
+	return &gophercloud.AuthOptions{
+		IdentityEndpoint: c.IdentityEndpoint(),
+		Username:         "admin",
+		Password:         "password",
+		DomainName:       "Default",
+		TenantName:       "admin",
+	}
+}
+
+// IdentityEndpoint returns in-cluster url of keystone of this cloud.
+func (c Cloud) IdentityEndpoint() string {
+	return "http://keystone." + c.Namespace + ".svc.cluster.local/v3"
+}
+
+// NodeSelectorValues returns chart values which override every
+// `labels.*.node_selector_value` in openstack-helm `chart`, so that pods
+// of this cloud are only scheduled on nodes of this cloud. Default cloud
+// uses chart defaults, and nil is returned.
+func (c Cloud) NodeSelectorValues(chart string) (map[string]interface{}, error) {
+
+	if c.Default {
+		return nil, nil
+	}
+
+	defaults, err := helm.GetChartValues("osh", chart)
+	if err != nil {
+		return nil, err
+	}
+
+	chartLabels, ok := defaults["labels"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	labels := make(map[string]interface{})
+	for key, value := range chartLabels {
+		selector, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if selector["node_selector_value"] == DefaultNodeSelectorValue {
+			labels[key] = map[string]interface{}{
+				"node_selector_value": c.NodeSelectorValue(),
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"labels": labels,
+	}, nil
+}
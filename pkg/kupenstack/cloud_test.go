@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kupenstack_test
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack"
+)
+
+var defaultProfile = clusterv1alpha1.OccpRef{Name: "default", Namespace: "kupenstack"}
+
+func TestNewCloud(t *testing.T) {
+
+	long := strings.Repeat("a", 60)
+
+	tests := []struct {
+		name      string
+		profile   clusterv1alpha1.OccpRef
+		namespace string
+		release   string
+		selector  string
+	}{
+		{
+			name:      "default profile",
+			profile:   defaultProfile,
+			namespace: "kupenstack",
+			release:   "keystone",
+			selector:  "enabled",
+		},
+		{
+			name:      "other profile",
+			profile:   clusterv1alpha1.OccpRef{Name: "gpu.v1", Namespace: "team"},
+			namespace: "kupenstack-gpu-v1-team",
+			release:   "keystone-kupenstack-gpu-v1-team",
+			selector:  "gpu.v1.team",
+		},
+		{
+			name:      "long profile",
+			profile:   clusterv1alpha1.OccpRef{Name: long, Namespace: "team"},
+			namespace: "kupenstack-" + long[:43] + "-60d98403",
+			release:   "keystone-kupenstack-" + long[:43] + "-60d98403",
+			selector:  long[:54] + "-60d98403",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cloud := kupenstack.NewCloud(test.profile, defaultProfile)
+			if cloud.Name != kupenstack.CloudName(test.profile) || cloud.Profile() != test.profile {
+				t.Errorf("expected cloud of profile %+v, got %+v", test.profile, cloud)
+			}
+			if cloud.Default != (test.profile == defaultProfile) {
+				t.Errorf("expected default %v, got %v", test.profile == defaultProfile, cloud.Default)
+			}
+			if cloud.Namespace != test.namespace {
+				t.Errorf("expected namespace %s, got %s", test.namespace, cloud.Namespace)
+			}
+			if errs := validation.IsDNS1123Label(cloud.Namespace); len(errs) != 0 {
+				t.Errorf("expected valid namespace, got %v", errs)
+			}
+			if release := cloud.ReleaseName("keystone"); release != test.release {
+				t.Errorf("expected release %s, got %s", test.release, release)
+			}
+			if selector := cloud.NodeSelectorValue(); selector != test.selector {
+				t.Errorf("expected node selector value %s, got %s", test.selector, selector)
+			}
+			if errs := validation.IsValidLabelValue(cloud.NodeSelectorValue()); len(errs) != 0 {
+				t.Errorf("expected valid label value, got %v", errs)
+			}
+		})
+	}
+}
+
+// Profiles with long names sharing a prefix yield different clouds.
+func TestNewCloudLongNames(t *testing.T) {
+
+	prefix := strings.Repeat("a", 70)
+	first := kupenstack.NewCloud(clusterv1alpha1.OccpRef{Name: prefix + "-1", Namespace: "team"}, defaultProfile)
+	second := kupenstack.NewCloud(clusterv1alpha1.OccpRef{Name: prefix + "-2", Namespace: "team"}, defaultProfile)
+
+	if first.Namespace == second.Namespace {
+		t.Errorf("expected different namespaces, got %s", first.Namespace)
+	}
+	if first.ReleaseName("keystone") == second.ReleaseName("keystone") {
+		t.Errorf("expected different release names, got %s", first.ReleaseName("keystone"))
+	}
+	if first.NodeSelectorValue() == second.NodeSelectorValue() {
+		t.Errorf("expected different node selector values, got %s", first.NodeSelectorValue())
+	}
+	for _, cloud := range []kupenstack.Cloud{first, second} {
+		if len(cloud.Namespace) > 63 || len(cloud.NodeSelectorValue()) > 63 {
+			t.Errorf("expected names of at most 63 characters, got %s and %s", cloud.Namespace, cloud.NodeSelectorValue())
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// BuiltinScheme prefixes profiles of the catalog embedded in the operator,
+// e.g. `from: builtin://ha-production`.
+const BuiltinScheme = "builtin://"
+
+//go:embed builtin/*.yaml
+var builtinProfiles embed.FS
+
+// IsBuiltin tells if profile refers to the embedded catalog.
+func IsBuiltin(profile string) bool {
+	return strings.HasPrefix(profile, BuiltinScheme)
+}
+
+// BuiltinProfiles returns names of profiles in the embedded catalog.
+func BuiltinProfiles() []string {
+	entries, err := builtinProfiles.ReadDir("builtin")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinProfile returns document of profile `name` in the embedded catalog.
+func BuiltinProfile(name string) ([]byte, error) {
+	data, err := builtinProfiles.ReadFile(path.Join("builtin", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown builtin profile %q, available profiles are: %s", name, strings.Join(BuiltinProfiles(), ", "))
+	}
+	return data, nil
+}
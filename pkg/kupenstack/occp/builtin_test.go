@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+func TestBuiltinProfiles(t *testing.T) {
+
+	expected := []string{"dev-all-in-one", "ha-production", "minimal"}
+	if got := occp.BuiltinProfiles(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected builtin profiles %v, got %v", expected, got)
+	}
+
+	_, err := occp.BuiltinProfile("missing")
+	if err == nil || !strings.Contains(err.Error(), strings.Join(expected, ", ")) {
+		t.Errorf("expected error listing available profiles, got %v", err)
+	}
+}
+
+// Every profile of the catalog resolves to a valid configuration.
+func TestResolveBuiltin(t *testing.T) {
+
+	r := newResolver(nil)
+
+	tests := []struct {
+		name  string
+		chain []string
+	}{
+		{
+			name:  "minimal",
+			chain: []string{"builtin://minimal"},
+		},
+		{
+			name:  "ha-production",
+			chain: []string{"builtin://ha-production"},
+		},
+		{
+			name:  "dev-all-in-one",
+			chain: []string{"builtin://dev-all-in-one", "builtin://minimal"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, chain, err := r.Resolve(context.Background(), occp.BuiltinScheme+test.name, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(chain, test.chain) {
+				t.Errorf("expected chain %v, got %v", test.chain, chain)
+			}
+			if problems, _ := occp.Lint(context.Background(), data, true); len(problems) != 0 {
+				t.Errorf("expected valid profile, got problems %v", problems)
+			}
+		})
+	}
+}
+
+// Profile in cluster inheriting dev-all-in-one gets values of both builtin
+// profiles, overridden in order of the chain.
+func TestResolveBuiltinChain(t *testing.T) {
+
+	r := newResolver(map[string]map[string]interface{}{
+		"dev.default": {
+			"from": "builtin://dev-all-in-one",
+			"nova": map[string]interface{}{"replicas": map[string]interface{}{"osapi": int64(2)}},
+		},
+	})
+
+	data, chain, err := r.Resolve(context.Background(), "dev", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedChain := []string{"dev.default", "builtin://dev-all-in-one", "builtin://minimal"}
+	if !reflect.DeepEqual(chain, expectedChain) {
+		t.Errorf("expected chain %v, got %v", expectedChain, chain)
+	}
+
+	tests := []struct {
+		path     []string
+		expected string
+	}{
+		// dev.default
+		{[]string{"nova", "replicas", "osapi"}, "2"},
+		// dev-all-in-one
+		{[]string{"horizon", "disable"}, "false"},
+		{[]string{"nova", "conf", "nova", "libvirt", "virt_type"}, "qemu"},
+		// minimal
+		{[]string{"nova", "replicas", "conductor"}, "1"},
+		{[]string{"keystone", "replicas", "api"}, "1"},
+	}
+	for _, test := range tests {
+		got, err := lookup(data, test.path)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if fmt.Sprint(got) != test.expected {
+			t.Errorf("expected %s to be %s, got %v", strings.Join(test.path, "."), test.expected, got)
+		}
+	}
+}
+
+func TestResolveBuiltinUnknown(t *testing.T) {
+
+	r := newResolver(map[string]map[string]interface{}{
+		"dev.default": {"from": "builtin://missing"},
+	})
+
+	_, _, err := r.Resolve(context.Background(), "dev", "default")
+	var sourceErr *occp.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Source != "builtin://missing" {
+		t.Errorf("expected source error of builtin://missing, got %v", err)
+	}
+}
+
+func lookup(data map[string]interface{}, path []string) (interface{}, error) {
+	var value interface{} = data
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s not found", strings.Join(path, "."))
+		}
+		value = m[key]
+	}
+	return value, nil
+}
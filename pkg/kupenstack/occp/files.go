@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Kind of OpenStackCloudConfigurationProfile.
+var profileKind = schema.GroupVersionKind{
+	Group:   "cluster.kupenstack.io",
+	Kind:    "OpenStackCloudConfigurationProfile",
+	Version: "v1alpha1",
+}
+
+// FileReader is a client.Reader serving profiles read from YAML files, so
+// that profiles can be resolved without a cluster. Namespaces of read
+// profiles exist, and all other objects are not found.
+type FileReader struct {
+	profiles map[types.NamespacedName]*unstructured.Unstructured
+	order    []types.NamespacedName
+}
+
+// ReadFiles reads profiles from YAML files at `paths`, each of which may
+// hold multiple documents. Documents of other kinds are skipped. Profiles
+// without namespace are put in `namespace`.
+func ReadFiles(namespace string, paths ...string) (*FileReader, error) {
+
+	f := &FileReader{
+		profiles: make(map[types.NamespacedName]*unstructured.Unstructured),
+	}
+
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = f.add(content, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *FileReader) add(content []byte, namespace string) error {
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		obj := &unstructured.Unstructured{}
+		err = yaml.Unmarshal(doc, &obj.Object)
+		if err != nil {
+			return err
+		}
+		if obj.Object == nil || obj.GroupVersionKind() != profileKind {
+			continue
+		}
+		if obj.GetName() == "" {
+			return fmt.Errorf("profile without name")
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+
+		key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+		if f.profiles[key] != nil {
+			return fmt.Errorf("profile %s defined more than once", profileID(key.Name, key.Namespace))
+		}
+		f.profiles[key] = obj
+		f.order = append(f.order, key)
+	}
+}
+
+// Profiles returns name and namespace of read profiles, in order of files.
+func (f *FileReader) Profiles() []types.NamespacedName {
+	return f.order
+}
+
+// Get implements client.Reader.
+func (f *FileReader) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+
+	switch o := obj.(type) {
+	case *unstructured.Unstructured:
+		profile := f.profiles[key]
+		if o.GroupVersionKind() != profileKind || profile == nil {
+			break
+		}
+		profile.DeepCopyInto(o)
+		return nil
+
+	case *corev1.Namespace:
+		for profile := range f.profiles {
+			if profile.Namespace == key.Name {
+				o.Name = key.Name
+				return nil
+			}
+		}
+	}
+
+	// Typed objects have no kind set.
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		gvk.Kind = reflect.TypeOf(obj).Elem().Name()
+	}
+	return errors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, key.Name)
+}
+
+// List implements client.Reader. Nothing is listed from files.
+func (f *FileReader) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	return fmt.Errorf("listing objects is not supported for profiles read from files")
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+// writeFile writes content to file `name` in a temporary directory and
+// returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const baseProfile = `
+apiVersion: cluster.kupenstack.io/v1alpha1
+kind: OpenStackCloudConfigurationProfile
+metadata:
+  name: base
+spec:
+  nova:
+    replicas: {osapi: 2}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: skipped
+---
+`
+
+const productionProfile = `
+apiVersion: cluster.kupenstack.io/v1alpha1
+kind: OpenStackCloudConfigurationProfile
+metadata:
+  name: production
+  namespace: prod
+spec:
+  from: base.kupenstack
+  nova:
+    replicas: {conductor: 3}
+`
+
+func TestReadFiles(t *testing.T) {
+	ctx := context.Background()
+
+	reader, err := occp.ReadFiles("kupenstack",
+		writeFile(t, "base.yaml", baseProfile), writeFile(t, "production.yaml", productionProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []types.NamespacedName{{Name: "base", Namespace: "kupenstack"}, {Name: "production", Namespace: "prod"}}
+	if !reflect.DeepEqual(reader.Profiles(), expected) {
+		t.Errorf("expected profiles %v, got %v", expected, reader.Profiles())
+	}
+
+	var ns corev1.Namespace
+	if err := reader.Get(ctx, types.NamespacedName{Name: "prod"}, &ns); err != nil || ns.Name != "prod" {
+		t.Errorf("expected namespace of profile to exist, got %v", err)
+	}
+	if err := reader.Get(ctx, types.NamespacedName{Name: "other"}, &ns); !errors.IsNotFound(err) {
+		t.Errorf("expected other namespace not found, got %v", err)
+	}
+	var osknode clusterv1alpha1.OpenstackNode
+	if err := reader.Get(ctx, types.NamespacedName{Name: "node-1"}, &osknode); !errors.IsNotFound(err) {
+		t.Errorf("expected other objects not found, got %v", err)
+	}
+	if err := reader.List(ctx, &clusterv1alpha1.OpenstackNodeList{}); err == nil {
+		t.Error("expected listing to fail")
+	}
+
+	resolver := occp.Resolver{Client: reader}
+	data, chain, err := resolver.Resolve(ctx, "production", "prod")
+	if err != nil {
+		t.Fatalf("resolving %v: %s", chain, err)
+	}
+	replicas, _ := data["nova"].(map[string]interface{})["replicas"].(map[string]interface{})
+	if replicas["osapi"] == nil || replicas["conductor"] == nil {
+		t.Errorf("expected replicas merged from base, got %v", data["nova"])
+	}
+}
+
+func TestReadFilesErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "duplicate",
+			content: baseProfile + baseProfile,
+		},
+		{
+			name: "without name",
+			content: `
+apiVersion: cluster.kupenstack.io/v1alpha1
+kind: OpenStackCloudConfigurationProfile
+spec: {}
+`,
+		},
+		{
+			name:    "invalid yaml",
+			content: "spec: [",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := occp.ReadFiles("kupenstack", writeFile(t, "profiles.yaml", test.content))
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := occp.ReadFiles("kupenstack", filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/chartmap"
+)
+
+// Keys of profile spec besides component configurations.
+var specKeys = []string{"from", "fromCredentials"}
+
+// ChartValues maps configuration of every component in resolved profile
+// `data` to values of its openstack-helm chart. `chartVersion` returns
+// version of chart which would be deployed, or empty string to use mapping
+// of newest chart version. Other keys of data are kept as they are.
+func ChartValues(data map[string]interface{}, chartVersion func(chart string) string) (map[string]interface{}, error) {
+
+	for _, component := range Components {
+		section, ok := data[component].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		mapping, err := chartmap.Default.Lookup(component, chartVersion(component))
+		if err != nil {
+			return nil, err
+		}
+
+		data[component], err = mapping.Apply(section)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s configuration: %w", component, err)
+		}
+	}
+
+	return data, nil
+}
+
+// Lint checks resolved profile `data` and returns problems that make it
+// unusable, and warnings about checks that could not be done. Keys are
+// checked against chart values in local helm repository cache. Keys of data
+// which are not components are ignored when profile is deployed, so they
+// are problems only when `strict`, and warnings otherwise. Charts not
+// available before ctx is done are not checked, with a warning.
+func Lint(ctx context.Context, data map[string]interface{}, strict bool) ([]string, []string) {
+
+	var problems, warnings []string
+
+	for _, key := range sortedKeys(data) {
+		if contains(specKeys, key) || contains(Components, key) {
+			continue
+		}
+		if strict {
+			problems = append(problems, fmt.Sprintf("%s is not a known component", key))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s is not a known component and is ignored", key))
+		}
+	}
+
+	for _, component := range Components {
+		p, w := checkComponent(ctx, component, data[component])
+		problems = append(problems, p...)
+		warnings = append(warnings, w...)
+	}
+
+	return problems, warnings
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
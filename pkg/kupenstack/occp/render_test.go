@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+func TestChartValues(t *testing.T) {
+
+	newest := func(string) string { return "" }
+
+	data := map[string]interface{}{
+		"from":    "base",
+		"nova":    map[string]interface{}{"replicas": map[string]interface{}{"metadata": 2}},
+		"neutron": "not a section",
+	}
+	vals, err := occp.ChartValues(data, newest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"from": "base",
+		"nova": map[string]interface{}{"pod": map[string]interface{}{
+			"replicas": map[string]interface{}{"api_metadata": 2}}},
+		"neutron": "not a section",
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected values %v, got %v", expected, vals)
+	}
+
+	// No mapping covers chart version.
+	_, err = occp.ChartValues(map[string]interface{}{"nova": map[string]interface{}{}},
+		func(string) string { return "1.0.0" })
+	if err == nil {
+		t.Error("expected error for chart version without mapping")
+	}
+}
+
+func TestLint(t *testing.T) {
+	ctx := context.Background()
+
+	data := map[string]interface{}{
+		"from":     "base",
+		"horizon":  map[string]interface{}{"disabled": true},
+		"nova":     map[string]interface{}{"replicas": map[string]interface{}{"osapi": -1}},
+		"keystone": map[string]interface{}{"replicas": map[string]interface{}{"api": "two"}},
+		"swift":    map[string]interface{}{},
+	}
+
+	problems, warnings := occp.Lint(ctx, data, true)
+	expected := []string{
+		"swift is not a known component",
+		"keystone.replicas.api must be a non-negative integer",
+		"nova.replicas.osapi must be a non-negative integer",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems %v, got %v", expected, problems)
+	}
+
+	// Unknown keys only warn when not strict. Other warnings are about
+	// chart values, which depend on local helm repository cache.
+	problems, warnings = occp.Lint(ctx, data, false)
+	if !reflect.DeepEqual(problems, expected[1:]) {
+		t.Errorf("expected problems %v, got %v", expected[1:], problems)
+	}
+	if len(warnings) == 0 || warnings[0] != "swift is not a known component and is ignored" {
+		t.Errorf("expected warning about swift, got %v", warnings)
+	}
+
+	problems, _ = occp.Lint(ctx, map[string]interface{}{"horizon": map[string]interface{}{"disabled": true}}, true)
+	if len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	// Charts are not read once ctx is done.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	data = map[string]interface{}{"nova": map[string]interface{}{"conf": map[string]interface{}{"nova": nil}}}
+	problems, warnings = occp.Lint(canceled, data, true)
+	if len(problems) != 0 || len(warnings) != 1 || !strings.Contains(warnings[0], context.Canceled.Error()) {
+		t.Errorf("expected only warning about canceled context, got %v and %v", problems, warnings)
+	}
+}
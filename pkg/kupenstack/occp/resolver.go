@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package occp resolves and validates OpenStackCloudConfigurationProfiles.
+package occp
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+// Maximum length of inheritance chain of a profile.
+const MaxDepth = 32
+
+var (
+	// ErrCycle is returned when a profile inherits from itself.
+	ErrCycle = goerrors.New("cycle in profile inheritance")
+
+	// ErrTooDeep is returned when inheritance chain is longer than MaxDepth.
+	ErrTooDeep = fmt.Errorf("profile inheritance deeper than %d", MaxDepth)
+)
+
+// Resolver reads profiles and merges them with the profiles they inherit
+// through `from`.
+type Resolver struct {
+	Client client.Reader
+}
+
+// Resolve returns data of profile `name` in `namespace` merged over all its
+// parents, and the inheritance chain starting with the profile itself. Name
+// may also be url of a remote profile.
+func (r *Resolver) Resolve(ctx context.Context, name, namespace string) (map[string]interface{}, []string, error) {
+
+	data, err := r.get(ctx, name, namespace, nil)
+	if err != nil {
+		return nil, []string{profileID(name, namespace)}, err
+	}
+
+	return r.ResolveFrom(ctx, data, name, namespace)
+}
+
+// ResolveFrom is same as Resolve, for profile whose `data` is already read.
+func (r *Resolver) ResolveFrom(ctx context.Context, data map[string]interface{}, name, namespace string) (map[string]interface{}, []string, error) {
+	return r.resolve(ctx, data, name, namespace, nil)
+}
+
+// resolve() is a recurring function. If the profile has any parent then it
+// recurs. The resulting data is drived by overriding parent data values.
+func (r *Resolver) resolve(ctx context.Context, data map[string]interface{}, name, namespace string, chain []string) (map[string]interface{}, []string, error) {
+
+	id := profileID(name, namespace)
+	for _, seen := range chain {
+		if seen == id {
+			chain = append(chain, id)
+			return nil, chain, fmt.Errorf("%w: %s", ErrCycle, strings.Join(chain, " -> "))
+		}
+	}
+	chain = append(chain, id)
+	if len(chain) > MaxDepth {
+		return nil, chain, ErrTooDeep
+	}
+
+	// if no parent then return data
+	if data["from"] == nil || data["from"] == "" {
+		return data, chain, nil
+	}
+
+	// else get parent and merge into profile-data
+	parent, ok := data["from"].(string)
+	if !ok {
+		return nil, chain, fmt.Errorf("Invalid from in profile %s: must be a string", id)
+	}
+
+	// Credentials for remote parent are read from Secret in namespace of
+	// the profile inheriting it. They are honored only on profiles in the
+	// cluster, so that remote documents cannot have any Secret sent out.
+	var creds *credentials
+	if ref, ok := data["fromCredentials"].(map[string]interface{}); ok && ref["name"] != nil && !IsUrl(name) {
+		var err error
+		creds, err = readCredentials(ctx, r.Client, fmt.Sprint(ref["name"]), namespace)
+		if err != nil {
+			return nil, chain, &SourceError{Source: parent, Err: err}
+		}
+	}
+
+	if IsUrl(parent) {
+		name = parent
+		// have not changed namespace to preserve it as default namespace for next function recussion.
+	} else {
+		profileName, profileNamespace := r.predictNameNamespace(ctx, parent)
+		name = profileName
+		if profileNamespace != "" {
+			namespace = profileNamespace
+		}
+	}
+
+	parentData, err := r.get(ctx, name, namespace, creds)
+	if err != nil {
+		return nil, append(chain, profileID(name, namespace)), err
+	}
+
+	parentData, chain, err = r.resolve(ctx, parentData, name, namespace, chain)
+	if err != nil {
+		return nil, chain, err
+	}
+	data, err = utils.MergeJson(parentData, data)
+	if err != nil {
+		return nil, chain, fmt.Errorf("cannot merge %s over %s: %w", id, profileID(name, namespace), err)
+	}
+	return data, chain, nil
+}
+
+// get reads data of a single profile, without its parents.
+func (r *Resolver) get(ctx context.Context, name, namespace string, creds *credentials) (map[string]interface{}, error) {
+	if IsUrl(name) {
+		return fetchProfileData(ctx, name, creds)
+	}
+	return r.getProfileData(ctx, name, namespace)
+}
+
+func (r *Resolver) getProfileData(ctx context.Context, name, namespace string) (map[string]interface{}, error) {
+
+	occp := &unstructured.Unstructured{}
+	occp.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cluster.kupenstack.io",
+		Kind:    "OpenStackCloudConfigurationProfile",
+		Version: "v1alpha1",
+	})
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, occp)
+	if err != nil {
+		return nil, err
+	}
+	if occp.Object["spec"] == nil {
+		return nil, nil
+	}
+	data := occp.Object["spec"].(map[string]interface{})
+	return data, err
+}
+
+// Predict Name/Namespace of profile based on FQDN name provided.
+func (r *Resolver) predictNameNamespace(ctx context.Context, profile string) (string, string) {
+
+	profileName := profile
+	profileNamespace := ""
+
+	// if dot separated name, then split to get namespace.
+	profileFQDN := strings.Split(profile, ".")
+	if len(profileFQDN) > 1 {
+		profileName = strings.Join(profileFQDN[:len(profileFQDN)-1], ".")
+		profileNamespace = profileFQDN[len(profileFQDN)-1]
+	}
+
+	// check if namespace exists
+	if profileNamespace != "" {
+		err := r.Client.Get(ctx, types.NamespacedName{Name: profileNamespace}, &corev1.Namespace{})
+		if err != nil && errors.IsNotFound(err) {
+			// Maybe profile is in same namespace
+			profileNamespace = ""
+			profileName = profile
+		}
+	}
+
+	return profileName, profileNamespace
+}
+
+// profileID returns `<name>.<namespace>` of profile, or url of remote profile.
+func profileID(name, namespace string) string {
+	if IsUrl(name) {
+		return name
+	}
+	return name + "." + namespace
+}
+
+func IsUrl(testUrl string) bool {
+	_, err := url.ParseRequestURI(testUrl)
+	if err == nil {
+		return true
+	}
+	return false
+}
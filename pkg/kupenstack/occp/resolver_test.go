@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+// profiles serves OCCPs as they are written, keyed by `<name>.<namespace>`,
+// and other objects from a fake client. Fake client would round trip OCCPs
+// through their go type, dropping fields unset in tests.
+type profiles struct {
+	client.Reader
+	specs map[string]map[string]interface{}
+}
+
+func (p profiles) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	occp, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return p.Reader.Get(ctx, key, obj)
+	}
+	spec, ok := p.specs[key.Name+"."+key.Namespace]
+	if !ok {
+		return errors.NewNotFound(schema.GroupResource{Resource: "openstackcloudconfigurationprofiles"}, key.Name)
+	}
+	occp.Object["spec"] = runtime.DeepCopyJSONValue(spec)
+	return nil
+}
+
+func newResolver(specs map[string]map[string]interface{}, objs ...client.Object) *occp.Resolver {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &occp.Resolver{Client: profiles{Reader: c, specs: specs}}
+}
+
+func TestResolveChain(t *testing.T) {
+
+	r := newResolver(map[string]map[string]interface{}{
+		"child.default": {
+			"from":    "base.shared",
+			"horizon": map[string]interface{}{"disabled": true},
+		},
+		"base.shared": {
+			"from":    "root",
+			"horizon": map[string]interface{}{"disabled": false},
+			"nova":    map[string]interface{}{"replicas": map[string]interface{}{"api": int64(2)}},
+		},
+		"root.shared": {
+			"nova":   map[string]interface{}{"replicas": map[string]interface{}{"api": int64(1), "conductor": int64(1)}},
+			"glance": map[string]interface{}{"disabled": true},
+		},
+	}, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}})
+
+	data, chain, err := r.Resolve(context.Background(), "child", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedChain := []string{"child.default", "base.shared", "root.shared"}
+	if !reflect.DeepEqual(chain, expectedChain) {
+		t.Errorf("expected chain %v, got %v", expectedChain, chain)
+	}
+	expected := map[string]interface{}{
+		"from":    "base.shared",
+		"horizon": map[string]interface{}{"disabled": true},
+		"nova":    map[string]interface{}{"replicas": map[string]interface{}{"api": int64(2), "conductor": int64(1)}},
+		"glance":  map[string]interface{}{"disabled": true},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected merged profile %v, got %v", expected, data)
+	}
+}
+
+// Credentials of fromCredentials are sent for parent of a profile in the
+// cluster, but fromCredentials of remote documents is ignored.
+func TestResolveRemoteCredentials(t *testing.T) {
+
+	var mu sync.Mutex
+	authorization := make(map[string]string)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/parent.yaml":
+			w.Write([]byte("spec:\n  from: " + server.URL + "/grandparent.yaml\n  fromCredentials:\n    name: other\n"))
+		case "/grandparent.yaml":
+			w.Write([]byte("spec:\n  horizon:\n    disabled: true\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	r := newResolver(map[string]map[string]interface{}{
+		"child.default": {
+			"from":            server.URL + "/parent.yaml",
+			"fromCredentials": map[string]interface{}{"name": "creds"},
+		},
+	},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"},
+			Data: map[string][]byte{"token": []byte("child-token")}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Data: map[string][]byte{"token": []byte("other-token")}},
+	)
+
+	data, chain, err := r.Resolve(context.Background(), "child", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 3 {
+		t.Errorf("expected chain of 3 profiles, got %v", chain)
+	}
+	if horizon, _ := data["horizon"].(map[string]interface{}); horizon["disabled"] != true {
+		t.Errorf("expected values of remote grandparent, got %v", data)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := authorization["/parent.yaml"]; got != "Bearer child-token" {
+		t.Errorf("expected credentials of child sent for parent, got %q", got)
+	}
+	if got := authorization["/grandparent.yaml"]; got != "" {
+		t.Errorf("expected no credentials sent for grandparent, got %q", got)
+	}
+}
@@ -0,0 +1,645 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Timeout of a single http request to a remote source.
+	httpTimeout = 30 * time.Second
+
+	// Timeout of cloning a git repository.
+	gitTimeout = 2 * time.Minute
+
+	// Maximum size of a remote profile document.
+	maxProfileSize = 4 << 20
+)
+
+// ErrDigestMismatch is returned when a remote profile does not match the
+// sha256 digest pinned in its source.
+var ErrDigestMismatch = goerrors.New("sha256 digest mismatch")
+
+// SourceError is returned when a remote profile cannot be fetched or parsed.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("Failed to fetch profile %s: %s", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Credentials for a remote source, read from keys `username` and `password`,
+// or `token` of a Secret.
+type credentials struct {
+	username string
+	password string
+	token    string
+}
+
+// authorization returns value of http Authorization header, or empty string
+// for anonymous access.
+func (c *credentials) authorization() string {
+	switch {
+	case c == nil:
+		return ""
+	case c.token != "":
+		return "Bearer " + c.token
+	case c.username != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	}
+	return ""
+}
+
+// readCredentials reads credentials from Secret `name` in `namespace`.
+func readCredentials(ctx context.Context, c client.Reader, name, namespace string) (*credentials, error) {
+
+	var secret corev1.Secret
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials{
+		username: string(secret.Data["username"]),
+		password: string(secret.Data["password"]),
+		token:    string(secret.Data["token"]),
+	}, nil
+}
+
+// Documents fetched from remote sources, keyed by source. Version is the
+// ETag of http sources, commit of git sources and manifest digest of OCI
+// sources, and is used to avoid downloading unchanged documents again. Least
+// recently used documents are evicted once cache holds maxCacheSize bytes.
+var cache = struct {
+	sync.Mutex
+	entries map[string]cacheEntry
+	size    int
+}{entries: make(map[string]cacheEntry)}
+
+// Maximum total size of documents in cache.
+const maxCacheSize = 16 << 20
+
+type cacheEntry struct {
+	version string
+	data    []byte
+	used    time.Time
+}
+
+func cached(source string) cacheEntry {
+	cache.Lock()
+	defer cache.Unlock()
+	entry, ok := cache.entries[source]
+	if ok {
+		entry.used = time.Now()
+		cache.entries[source] = entry
+	}
+	return entry
+}
+
+func store(source, version string, data []byte) {
+	if version == "" || len(data) > maxCacheSize {
+		return
+	}
+	cache.Lock()
+	defer cache.Unlock()
+
+	if old, ok := cache.entries[source]; ok {
+		cache.size -= len(old.data)
+		delete(cache.entries, source)
+	}
+	for cache.size+len(data) > maxCacheSize {
+		evictOldest()
+	}
+	cache.entries[source] = cacheEntry{version: version, data: data, used: time.Now()}
+	cache.size += len(data)
+}
+
+// evictOldest removes least recently used document from cache. Cache must
+// be locked.
+func evictOldest() {
+	oldest := ""
+	for source, entry := range cache.entries {
+		if oldest == "" || entry.used.Before(cache.entries[oldest].used) {
+			oldest = source
+		}
+	}
+	cache.size -= len(cache.entries[oldest].data)
+	delete(cache.entries, oldest)
+}
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// fetchProfileData fetches remote profile `source` and returns its spec.
+//
+// Supported sources are:
+//  https://host/path/profile.yaml
+//  git+https://host/org/repo.git//path/profile.yaml?ref=v1
+//  oci://registry/org/profile:v1
+// Any of them may be pinned with a `#sha256=<hex>` suffix.
+func fetchProfileData(ctx context.Context, source string, creds *credentials) (map[string]interface{}, error) {
+
+	data, err := fetchSource(ctx, source, creds)
+	if err != nil {
+		return nil, &SourceError{Source: source, Err: err}
+	}
+
+	var occp map[string]interface{}
+	err = yaml.Unmarshal(data, &occp)
+	if err != nil {
+		return nil, &SourceError{Source: source, Err: err}
+	}
+
+	spec, ok := occp["spec"].(map[string]interface{})
+	if !ok {
+		return nil, &SourceError{Source: source, Err: fmt.Errorf("document has no spec")}
+	}
+	return spec, nil
+}
+
+// fetchSource returns raw document of source, verified against pinned digest.
+func fetchSource(ctx context.Context, source string, creds *credentials) ([]byte, error) {
+
+	location, digest := splitDigest(source)
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	switch u.Scheme {
+	case "http", "https":
+		data, err = fetchHTTP(ctx, location, creds)
+	case "git+https", "git+http", "git+ssh":
+		data, err = fetchGit(ctx, u, creds)
+	case "oci":
+		data, err = fetchOCI(ctx, u, creds)
+	case "builtin":
+		data, err = BuiltinProfile(u.Host)
+	default:
+		err = fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if digest != "" {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(digest) {
+			return nil, fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, digest, hex.EncodeToString(sum[:]))
+		}
+	}
+	return data, nil
+}
+
+// splitDigest splits `#sha256=<hex>` suffix from source.
+func splitDigest(source string) (string, string) {
+	i := strings.LastIndex(source, "#sha256=")
+	if i < 0 {
+		return source, ""
+	}
+	return source[:i], source[i+len("#sha256="):]
+}
+
+func fetchHTTP(ctx context.Context, location string, creds *credentials) ([]byte, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth := creds.authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	entry := cached(location)
+	if entry.version != "" {
+		req.Header.Set("If-None-Match", entry.version)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry.version != "" {
+		return entry.data, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status %s", resp.Status)
+	}
+
+	data, err := readAll(resp)
+	if err != nil {
+		return nil, err
+	}
+	store(location, resp.Header.Get("ETag"), data)
+	return data, nil
+}
+
+func readAll(resp *http.Response) ([]byte, error) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxProfileSize))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchGit reads a file from git repository. Path of file in repository
+// follows `//` in url path, and `ref` query selects branch or tag. Requires
+// `git` binary.
+func fetchGit(ctx context.Context, u *url.URL, creds *credentials) ([]byte, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	i := strings.Index(u.Path, "//")
+	if i < 0 {
+		return nil, fmt.Errorf("git source must have path of profile after //")
+	}
+	file := u.Path[i+2:]
+	ref := u.Query().Get("ref")
+
+	repo := *u
+	repo.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+	repo.Path = u.Path[:i]
+	repo.RawQuery = ""
+	repo.Fragment = ""
+
+	// Commit of ref decides whether cached document is still valid.
+	lsRef := ref
+	if lsRef == "" {
+		lsRef = "HEAD"
+	}
+	out, err := git(ctx, creds, "ls-remote", repo.String(), lsRef)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("ref %s not found in %s", lsRef, repo.Redacted())
+	}
+	commit := fields[0]
+
+	source := u.String()
+	if entry := cached(source); entry.version == commit {
+		return entry.data, nil
+	}
+
+	dir, err := ioutil.TempDir("", "kupenstack-occp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cloneArgs := []string{"--depth", "1"}
+	if ref != "" {
+		cloneArgs = append(cloneArgs, "--branch", ref)
+	}
+	_, err = git(ctx, creds, "clone", append(cloneArgs, repo.String(), dir)...)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(file))
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+		return nil, fmt.Errorf("path %s is outside of repository", file)
+	}
+
+	// Path is checked again with symlinks resolved, so that a repository
+	// cannot have files of the operator read through them.
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("path %s not found in repository", file)
+	}
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("path %s is outside of repository", file)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store(source, commit, data)
+	return data, nil
+}
+
+// Credential helper answering git with credentials from environment of git,
+// so that they never appear in arguments of processes.
+const gitCredentialHelper = `!f() { test "$1" = get && echo "username=$KUPENSTACK_GIT_USERNAME" && echo "password=$KUPENSTACK_GIT_PASSWORD"; }; f`
+
+// Username sent with token of credentials, accepted by common git hosts.
+const gitTokenUsername = "x-access-token"
+
+// git runs git `command` with args. Credentials, when set, are given to git
+// through gitCredentialHelper.
+func git(ctx context.Context, creds *credentials, command string, args ...string) ([]byte, error) {
+
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var argv []string
+	if creds != nil && (creds.username != "" || creds.token != "") {
+		username, password := creds.username, creds.password
+		if creds.token != "" {
+			password = creds.token
+			if username == "" {
+				username = gitTokenUsername
+			}
+		}
+		// empty helper first resets helpers configured on the host
+		argv = append(argv, "-c", "credential.helper=", "-c", "credential.helper="+gitCredentialHelper)
+		env = append(env, "KUPENSTACK_GIT_USERNAME="+username, "KUPENSTACK_GIT_PASSWORD="+password)
+	}
+	argv = append(append(argv, command), args...)
+
+	cmd := exec.CommandContext(ctx, "git", argv...)
+	cmd.Env = env
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %s: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Media types of OCI and docker image manifests.
+const (
+	ociManifest    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+// fetchOCI reads profile from an OCI artifact `oci://registry/repository:tag`
+// or `oci://registry/repository@sha256:...`. Profile is its only layer, or
+// its first layer with a yaml media type.
+func fetchOCI(ctx context.Context, u *url.URL, creds *credentials) ([]byte, error) {
+
+	repository := strings.TrimPrefix(u.Path, "/")
+	reference := "latest"
+	if i := strings.LastIndex(repository, "@"); i >= 0 {
+		repository, reference = repository[:i], repository[i+1:]
+	} else if i := strings.LastIndex(repository, ":"); i >= 0 && !strings.Contains(repository[i:], "/") {
+		repository, reference = repository[:i], repository[i+1:]
+	}
+
+	registry := &ociRegistry{host: u.Host, repository: repository, creds: creds}
+
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", u.Host, repository, reference)
+	resp, err := registry.get(ctx, manifestURL, ociManifest+", "+dockerManifest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := readAll(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	source := u.String()
+	version := resp.Header.Get("Docker-Content-Digest")
+	if entry := cached(source); version != "" && entry.version == version {
+		return entry.data, nil
+	}
+
+	var manifest struct {
+		Layers []ociDescriptor `json:"layers"`
+	}
+	err = json.Unmarshal(body, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var layer *ociDescriptor
+	for i := range manifest.Layers {
+		if strings.Contains(manifest.Layers[i].MediaType, "yaml") {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil && len(manifest.Layers) == 1 {
+		layer = &manifest.Layers[0]
+	}
+	if layer == nil {
+		return nil, fmt.Errorf("artifact has no yaml layer")
+	}
+
+	blobURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", u.Host, repository, layer.Digest)
+	blob, err := registry.get(ctx, blobURL, "")
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Body.Close()
+	data, err := readAll(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	if layer.Digest != "sha256:"+hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("%w: layer %s", ErrDigestMismatch, layer.Digest)
+	}
+
+	store(source, version, data)
+	return data, nil
+}
+
+// ociRegistry performs requests to registry, authenticating with the
+// token flow of docker registry when challenged.
+type ociRegistry struct {
+	host       string
+	repository string
+	creds      *credentials
+	auth       string
+}
+
+func (r *ociRegistry) get(ctx context.Context, location, accept string) (*http.Response, error) {
+
+	resp, err := r.do(ctx, location, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && r.auth == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		r.auth, err = r.authenticate(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = r.do(ctx, location, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected http status %s from registry", resp.Status)
+	}
+	return resp, nil
+}
+
+func (r *ociRegistry) do(ctx context.Context, location, accept string) (*http.Response, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if r.auth != "" {
+		req.Header.Set("Authorization", r.auth)
+	}
+	return httpClient.Do(req)
+}
+
+// authenticate returns Authorization header answering challenge of registry.
+func (r *ociRegistry) authenticate(ctx context.Context, challenge string) (string, error) {
+
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "basic"):
+		if auth := r.creds.authorization(); auth != "" {
+			return auth, nil
+		}
+		return "", fmt.Errorf("registry requires credentials")
+	case !strings.EqualFold(scheme, "bearer"):
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+
+	if r.creds != nil && r.creds.token != "" {
+		return "Bearer " + r.creds.token, nil
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + r.repository + ":pull"
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth := r.creds.authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected http status %s from registry token service", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge parses `Bearer realm="...",service="...",scope="..."`.
+func parseChallenge(challenge string) (string, map[string]string) {
+
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+
+	for _, param := range splitParams(parts[1]) {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+	return parts[0], params
+}
+
+// splitParams splits comma separated params, ignoring commas within quotes.
+func splitParams(s string) []string {
+	var params []string
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo returns path of a git repository with profile.yaml, a symlink to
+// it and a symlink to a file outside of repository.
+func gitRepo(t *testing.T) string {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	secret := filepath.Join(t.TempDir(), "token")
+	err := ioutil.WriteFile(secret, []byte("secret"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = ioutil.WriteFile(filepath.Join(dir, "profile.yaml"), []byte("spec: {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("profile.yaml", filepath.Join(dir, "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "token.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "profiles"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s: %s", args[0], err, out)
+		}
+	}
+	return dir
+}
+
+func TestFetchGitPaths(t *testing.T) {
+
+	repo := gitRepo(t)
+
+	tests := []struct {
+		file  string
+		error string
+	}{
+		{file: "profile.yaml"},
+		{file: "link.yaml"},
+		{file: "token.yaml", error: "outside of repository"},
+		{file: "../token", error: "outside of repository"},
+		{file: "missing.yaml", error: "not found in repository"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			u, err := url.Parse("git+file://" + repo + "//" + test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := fetchGit(context.Background(), u, nil)
+			if test.error == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != "spec: {}\n" {
+					t.Errorf("expected profile, got %q", data)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("expected error %q, got %v with data %q", test.error, err, data)
+			}
+		})
+	}
+}
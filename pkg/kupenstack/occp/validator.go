@@ -0,0 +1,253 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/helm"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/chartmap"
+	"github.com/kupenstack/kupenstack/pkg/utils"
+)
+
+//+kubebuilder:webhook:path=/validate-cluster-kupenstack-io-v1alpha1-openstackcloudconfigurationprofile,mutating=false,failurePolicy=fail,sideEffects=None,groups=cluster.kupenstack.io,resources=openstackcloudconfigurationprofiles,verbs=create;update;delete,versions=v1alpha1,name=vopenstackcloudconfigurationprofile.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// ValidatingWebhookPath is path on which Validator is served.
+const ValidatingWebhookPath = "/validate-cluster-kupenstack-io-v1alpha1-openstackcloudconfigurationprofile"
+
+// Timeout of validating a profile, including fetching its remote parents
+// and charts. It is below the 10 second default timeout of admission
+// webhooks in kube-apiserver, so that a slow source is reported as such.
+const admissionTimeout = 8 * time.Second
+
+// OpenStack components configured by a profile. Each is deployed with
+// openstack-helm chart of same name.
+var Components = []string{"keystone", "glance", "horizon", "nova", "neutron", "placement",
+	"ingress", "mariadb", "rabbitmq", "memcached", "libvirt"}
+
+// Validator is a validating admission webhook for OCCPs. It rejects profiles
+// whose inheritance chain cannot be resolved, or whose replicas and conf do
+// not match values of openstack-helm charts, and refuses deleting profiles
+// still used by osknodes.
+type Validator struct {
+	Client client.Client
+}
+
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstackcloudconfigurationprofiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.kupenstack.io,resources=openstacknodes,verbs=get;list;watch
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+
+	if req.Operation == admissionv1.Delete {
+		return v.validateDelete(ctx, req)
+	}
+
+	var obj map[string]interface{}
+	err := json.Unmarshal(req.Object.Raw, &obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	spec, _ := obj["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = make(map[string]interface{})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, admissionTimeout)
+	defer cancel()
+
+	resolver := Resolver{Client: v.Client}
+	data, chain, err := resolver.ResolveFrom(ctx, spec, req.Name, req.Namespace)
+	var sourceErr *SourceError
+	if err != nil {
+		if !goerrors.As(err, &sourceErr) && errors.IsNotFound(err) {
+			return admission.Denied(fmt.Sprintf("Unknown parent profile %s in %s.",
+				chain[len(chain)-1], strings.Join(chain, " -> ")))
+		}
+		return admission.Denied(err.Error())
+	}
+
+	// Unknown keys only warn, so that existing profiles having them are
+	// still accepted.
+	problems, warnings := Lint(ctx, data, false)
+	if len(problems) > 0 {
+		return admission.Denied(strings.Join(problems, "; ")).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// validateDelete refuses deleting profile used by any osknode.
+func (v *Validator) validateDelete(ctx context.Context, req admission.Request) admission.Response {
+
+	var oskNodeList clusterv1alpha1.OpenstackNodeList
+	err := v.Client.List(ctx, &oskNodeList)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var users []string
+	for _, osknode := range oskNodeList.Items {
+		if osknode.Spec.Occp.Name == req.Name && osknode.Spec.Occp.Namespace == req.Namespace {
+			users = append(users, osknode.Name)
+		}
+	}
+
+	if len(users) > 0 {
+		return admission.Denied(fmt.Sprintf("Profile is used by osknodes: %s.", strings.Join(users, ", ")))
+	}
+	return admission.Allowed("")
+}
+
+// checkComponent compares resolved configuration of component with default
+// values of its chart, and returns problems found. When chart values are not
+// available checks are skipped and a warning is returned instead.
+func checkComponent(ctx context.Context, component string, config interface{}) ([]string, []string) {
+
+	cfg, ok := config.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var problems []string
+
+	replicas, _ := cfg["replicas"].(map[string]interface{})
+	for _, key := range sortedKeys(replicas) {
+		count, ok := toInt(replicas[key])
+		if !ok || count < 0 {
+			problems = append(problems, fmt.Sprintf("%s.replicas.%s must be a non-negative integer", component, key))
+		}
+	}
+
+	resources, _ := cfg["resources"].(map[string]interface{})
+	conf, _ := cfg["conf"].(map[string]interface{})
+	overrides, _ := cfg["values"].(map[string]interface{})
+	if len(replicas) == 0 && len(resources) == 0 && len(conf) == 0 && len(overrides) == 0 {
+		return problems, nil
+	}
+
+	ch, err := getChart(ctx, "osh", component)
+	if err != nil {
+		return problems, []string{fmt.Sprintf("Values of chart %s are not available, its keys are not checked: %s", component, err)}
+	}
+	values := ch.Values
+
+	mapping, err := chartmap.Default.Lookup(component, ch.Metadata.Version)
+	if err != nil {
+		return problems, []string{fmt.Sprintf("Keys of chart %s are not checked: %s", component, err)}
+	}
+
+	for _, key := range sortedKeys(replicas) {
+		// Zero is set by schema defaults for every replica key.
+		if count, _ := toInt(replicas[key]); count == 0 {
+			continue
+		}
+		path, _ := mapping.ChartPath("replicas." + key)
+		if _, ok := chartmap.GetPath(values, path); !ok {
+			problems = append(problems, fmt.Sprintf("%s.replicas.%s is not a replica count in chart %s", component, key, component))
+		}
+	}
+
+	for _, key := range sortedKeys(resources) {
+		path, _ := mapping.ChartPath("resources." + key)
+		if _, ok := chartmap.GetPath(values, path); !ok {
+			problems = append(problems, fmt.Sprintf("%s.resources.%s is not a pod type in chart %s", component, key, component))
+		}
+	}
+
+	chartConf, _ := values["conf"].(map[string]interface{})
+	for _, key := range sortedKeys(conf) {
+		if key == utils.PatchDirective {
+			continue
+		}
+		if _, ok := chartConf[key]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.conf.%s is not a known key of chart %s", component, key, component))
+		}
+	}
+
+	for _, key := range sortedKeys(overrides) {
+		if key == utils.PatchDirective {
+			continue
+		}
+		if _, ok := values[key]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.values.%s is not a known key of chart %s", component, key, component))
+		}
+	}
+
+	return problems, nil
+}
+
+// getChart returns chart `name` from helm repository `repo`, or error once
+// ctx is done. helm may download chart, and takes no context; chart is still
+// cached when it is downloaded after ctx is done.
+func getChart(ctx context.Context, repo, name string) (*chart.Chart, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		chart *chart.Chart
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ch, err := helm.GetChart(repo, name)
+		done <- result{ch, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.chart, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	}
+	return 0, false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
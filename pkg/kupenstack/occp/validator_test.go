@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package occp_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/kupenstack/occp"
+)
+
+// profileClient serves OCCPs like profiles, and other objects from a fake
+// client.
+type profileClient struct {
+	client.Client
+	profiles profiles
+}
+
+func (c profileClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return c.profiles.Get(ctx, key, obj)
+}
+
+func newValidator(specs map[string]map[string]interface{}, objs ...client.Object) *occp.Validator {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	clusterv1alpha1.AddToScheme(scheme)
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &occp.Validator{Client: profileClient{Client: c, profiles: profiles{Reader: c, specs: specs}}}
+}
+
+func request(t *testing.T, operation admissionv1.Operation, spec map[string]interface{}) admission.Request {
+	raw, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Name:      "child",
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestValidatorProfiles(t *testing.T) {
+
+	v := newValidator(map[string]map[string]interface{}{
+		"base.default": {"horizon": map[string]interface{}{"disabled": true}},
+	})
+
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		allowed bool
+		reason  string
+		warning string
+	}{
+		{
+			name:    "valid profile",
+			spec:    map[string]interface{}{"from": "base", "glance": map[string]interface{}{"disabled": true}},
+			allowed: true,
+		},
+		{
+			name:    "unknown key",
+			spec:    map[string]interface{}{"from": "base", "swift": map[string]interface{}{}},
+			allowed: true,
+			warning: "swift is not a known component and is ignored",
+		},
+		{
+			name:   "unknown parent",
+			spec:   map[string]interface{}{"from": "missing"},
+			reason: "Unknown parent profile missing.default in child.default -> missing.default.",
+		},
+		{
+			name: "negative replicas",
+			spec: map[string]interface{}{"from": "base",
+				"nova": map[string]interface{}{"replicas": map[string]interface{}{"api": -1}}},
+			reason: "nova.replicas.api must be a non-negative integer",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := v.Handle(context.Background(), request(t, admissionv1.Create, test.spec))
+			if resp.Allowed != test.allowed {
+				t.Fatalf("expected allowed %v, got %v: %s", test.allowed, resp.Allowed, resp.Result.Reason)
+			}
+			if !test.allowed && !strings.Contains(string(resp.Result.Reason), test.reason) {
+				t.Errorf("expected reason %q, got %q", test.reason, resp.Result.Reason)
+			}
+			if test.warning != "" && (len(resp.Warnings) == 0 || resp.Warnings[0] != test.warning) {
+				t.Errorf("expected warning %q, got %v", test.warning, resp.Warnings)
+			}
+		})
+	}
+}
+
+func TestValidatorDelete(t *testing.T) {
+
+	v := newValidator(nil, &clusterv1alpha1.OpenstackNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       clusterv1alpha1.OpenstackNodeSpec{Occp: clusterv1alpha1.OccpRef{Name: "child", Namespace: "default"}},
+	})
+
+	resp := v.Handle(context.Background(), request(t, admissionv1.Delete, nil))
+	if resp.Allowed || !strings.Contains(string(resp.Result.Reason), "node-1") {
+		t.Errorf("expected deleting profile used by node-1 to be denied, got %+v", resp.Result)
+	}
+
+	req := request(t, admissionv1.Delete, nil)
+	req.Name = "unused"
+	resp = v.Handle(context.Background(), req)
+	if !resp.Allowed {
+		t.Errorf("expected deleting unused profile to be allowed, got %+v", resp.Result)
+	}
+}
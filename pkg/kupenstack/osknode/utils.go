@@ -14,6 +14,15 @@ import (
 	ksktypes "github.com/kupenstack/kupenstack/pkg/kupenstack/types/v1alpha1"
 )
 
+// DisabledAnnotation is set to "true" on OpenstackNodes disabled in
+// KupenstackConfiguration.
+const DisabledAnnotation = "kupenstack.io/disabled"
+
+// SkipDeregisterAnnotation set to "true" on an OpenstackNode lets it be
+// deleted without deregistering its node from OpenStack, e.g. when OpenStack
+// is gone or migrating its instances is stuck.
+const SkipDeregisterAnnotation = "kupenstack.io/skip-deregister"
+
 // get unstructured node list
 // get unstructured node // Get
 // convert to struct // osknode.AsStruct()
@@ -66,6 +75,37 @@ func AsStruct(in *unstructured.Unstructured) (*ksktypes.OpenstackNode, error) {
 	return out, nil
 }
 
+// Returns configuration of `component` generated for OpenstackNodes using
+// profile `occp` (`<name>.<namespace>`), and whether their configuration is
+// generated yet.
+func DesiredConfiguration(ctx context.Context, c client.Client, occp, component string) (map[string]interface{}, bool, error) {
+
+	oskNodeList, err := GetList(ctx, c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	generated := false
+	cfg := make(map[string]interface{})
+	for _, n := range oskNodeList.Items {
+		oskNode, err := AsStruct(&n)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if oskNode.Spec.Occp.Name+"."+oskNode.Spec.Occp.Namespace != occp {
+			continue
+		}
+
+		generated = oskNode.Status.Generated
+		if componentCfg, ok := oskNode.Status.DesiredNodeConfiguration[component].(map[string]interface{}); ok {
+			cfg = componentCfg
+		}
+	}
+
+	return cfg, generated, nil
+}
+
 // Todo:
 // Takes OpenstackNode as struct and returns it as correesponding map[string]interface{}
 // Usage: the reurned map[string]interface{} can be stored back in k8s-unstructured-types
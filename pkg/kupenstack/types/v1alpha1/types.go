@@ -29,6 +29,15 @@ type OpenstackNodeSpec struct {
 
 	// OpenStack Cloud Configuration Profile(OCCP) used by this node.
 	Occp OccpRef `json:"openstackCloudConfigurationProfileRef"`
+
+	// Whether node is in maintenance.
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+type MaintenanceStatus struct {
+	Phase              string `json:"phase,omitempty"`
+	RemainingInstances int32  `json:"remainingInstances,omitempty"`
+	Message            string `json:"message,omitempty"`
 }
 
 type OpenstackNodeStatus struct {
@@ -41,6 +50,9 @@ type OpenstackNodeStatus struct {
 
 	// Status of OpenStack cluster components for this osknode.
 	Status string `json:"status,omitempty"`
+
+	// Progress of maintenance of this node.
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
 }
 
 type OpenstackNode struct {
@@ -2,12 +2,14 @@ package kupenstack
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1alpha1 "github.com/kupenstack/kupenstack/apis/cluster/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/utils"
 )
 
 func OccpExists(c client.Client, profilename string) (bool, error) {
@@ -19,3 +21,40 @@ func OccpExists(c client.Client, profilename string) (bool, error) {
 	}
 	return true, nil
 }
+
+// OverrideValues merges `values` of component configuration in `vals` over
+// rest of `vals`, i.e. chart values generated by kupenstack, and returns
+// the result.
+func OverrideValues(vals map[string]interface{}) (map[string]interface{}, error) {
+
+	override, _ := vals["values"].(map[string]interface{})
+	delete(vals, "values")
+
+	vals, err := utils.MergeJson(vals, override)
+	if err != nil {
+		return nil, fmt.Errorf("cannot merge values: %w", err)
+	}
+	return vals, nil
+}
+
+// WithDefaults merges component configuration `cfg` over chart values
+// `defaults` generated by kupenstack. `values` of cfg is kept as is, to be
+// applied last by OverrideValues.
+func WithDefaults(defaults, cfg map[string]interface{}) (map[string]interface{}, error) {
+
+	rest := make(map[string]interface{}, len(cfg))
+	for key, val := range cfg {
+		if key != "values" {
+			rest[key] = val
+		}
+	}
+
+	vals, err := utils.MergeJson(defaults, rest)
+	if err != nil {
+		return nil, fmt.Errorf("cannot merge configuration over defaults: %w", err)
+	}
+	if cfg["values"] != nil {
+		vals["values"] = cfg["values"]
+	}
+	return vals, nil
+}
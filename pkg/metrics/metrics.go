@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics defines prometheus metrics of kupenstack. They are served
+// on metrics endpoint of controller manager, along with controller-runtime
+// metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "kupenstack"
+
+// Metrics are served from registry of controller-runtime.
+var registry = crmetrics.Registry
+
+var (
+	// HelmOperations counts helm operations by chart, operation
+	// (install, upgrade or uninstall) and result (success or failure).
+	HelmOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "helm",
+		Name:      "operations_total",
+		Help:      "Number of helm operations by chart, operation and result.",
+	}, []string{"chart", "operation", "result"})
+
+	// HelmOperationDuration observes duration of helm operations.
+	HelmOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "helm",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of helm operations by chart and operation.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"chart", "operation"})
+
+	// OpenstackRequests counts requests to OpenStack APIs by service,
+	// http method and response code. Code is `error` when no response
+	// was received.
+	OpenstackRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "openstack",
+		Name:      "requests_total",
+		Help:      "Number of OpenStack API requests by service, method and code.",
+	}, []string{"service", "method", "code"})
+
+	// OpenstackRequestDuration observes latency of requests to OpenStack APIs.
+	OpenstackRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "openstack",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of OpenStack API requests by service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+)
+
+func init() {
+	registry.MustRegister(
+		HelmOperations,
+		HelmOperationDuration,
+		OpenstackRequests,
+		OpenstackRequestDuration,
+	)
+}
+
+// Result returns value of `result` label for err.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
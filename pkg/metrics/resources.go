@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
+)
+
+// Timeout of listing resources on a scrape.
+const listTimeout = 10 * time.Second
+
+var resourcesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "resources"),
+	"Number of kupenstack resources by kind and state.",
+	[]string{"kind", "state"}, nil,
+)
+
+var listErrorsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "resources_list_errors"),
+	"Whether listing resources of kind failed on this scrape.",
+	[]string{"kind"}, nil,
+)
+
+// ResourceCollector reports number of VirtualMachines, Images and Networks
+// by state, counted from cache of client on every scrape.
+type ResourceCollector struct {
+	Client client.Reader
+}
+
+// NewResourceCollector returns ResourceCollector reading with `c`, and
+// registers it to controller-runtime metrics registry.
+func NewResourceCollector(c client.Reader) (*ResourceCollector, error) {
+	collector := &ResourceCollector{Client: c}
+	return collector, registry.Register(collector)
+}
+
+func (r *ResourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourcesDesc
+	ch <- listErrorsDesc
+}
+
+func (r *ResourceCollector) Collect(ch chan<- prometheus.Metric) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	var vms kupenstackiov1alpha1.VirtualMachineList
+	err := r.Client.List(ctx, &vms)
+	counts := make(map[string]float64)
+	for _, vm := range vms.Items {
+		state := vm.Status.State
+		if state == "" {
+			state = "Pending"
+		}
+		counts[state]++
+	}
+	collect(ch, "VirtualMachine", counts, err)
+
+	var images kupenstackiov1alpha1.ImageList
+	err = r.Client.List(ctx, &images)
+	counts = make(map[string]float64)
+	for _, image := range images.Items {
+		switch {
+		case image.Status.Ready:
+			counts["Ready"]++
+		default:
+			counts["NotReady"]++
+		}
+	}
+	collect(ch, "Image", counts, err)
+
+	var networks kupenstackiov1alpha1.NetworkList
+	err = r.Client.List(ctx, &networks)
+	counts = make(map[string]float64)
+	for _, network := range networks.Items {
+		switch {
+		case network.Status.ID == "":
+			counts["Pending"]++
+		case network.Status.Usage.InUse:
+			counts["InUse"]++
+		default:
+			counts["Available"]++
+		}
+	}
+	collect(ch, "Network", counts, err)
+}
+
+func collect(ch chan<- prometheus.Metric, kind string, counts map[string]float64, err error) {
+
+	failed := 0.0
+	if err != nil {
+		failed = 1
+		counts = nil
+	}
+	ch <- prometheus.MustNewConstMetric(listErrorsDesc, prometheus.GaugeValue, failed, kind)
+
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, count, kind, state)
+	}
+}
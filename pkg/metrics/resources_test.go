@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kupenstackiov1alpha1 "github.com/kupenstack/kupenstack/apis/v1alpha1"
+	"github.com/kupenstack/kupenstack/pkg/metrics"
+)
+
+func TestResourceCollector(t *testing.T) {
+
+	scheme := runtime.NewScheme()
+	kupenstackiov1alpha1.AddToScheme(scheme)
+
+	vm := func(name, state string) *kupenstackiov1alpha1.VirtualMachine {
+		return &kupenstackiov1alpha1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "demo"},
+			Status: kupenstackiov1alpha1.VirtualMachineStatus{State: state}}
+	}
+	image := &kupenstackiov1alpha1.Image{ObjectMeta: metav1.ObjectMeta{Name: "cirros"}}
+	image.Status.Ready = true
+	pending := &kupenstackiov1alpha1.Network{ObjectMeta: metav1.ObjectMeta{Name: "pending"}}
+	inUse := &kupenstackiov1alpha1.Network{ObjectMeta: metav1.ObjectMeta{Name: "public"}}
+	inUse.Status.ID = "network-id"
+	inUse.Status.Usage.InUse = true
+
+	c := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		vm("vm-1", "Running"), vm("vm-2", "Running"), vm("vm-3", "SHUTOFF"), vm("vm-4", ""),
+		image, pending, inUse,
+	).Build()
+
+	expected := `
+# HELP kupenstack_resources Number of kupenstack resources by kind and state.
+# TYPE kupenstack_resources gauge
+kupenstack_resources{kind="Image",state="Ready"} 1
+kupenstack_resources{kind="Network",state="InUse"} 1
+kupenstack_resources{kind="Network",state="Pending"} 1
+kupenstack_resources{kind="VirtualMachine",state="Pending"} 1
+kupenstack_resources{kind="VirtualMachine",state="Running"} 2
+kupenstack_resources{kind="VirtualMachine",state="SHUTOFF"} 1
+# HELP kupenstack_resources_list_errors Whether listing resources of kind failed on this scrape.
+# TYPE kupenstack_resources_list_errors gauge
+kupenstack_resources_list_errors{kind="Image"} 0
+kupenstack_resources_list_errors{kind="Network"} 0
+kupenstack_resources_list_errors{kind="VirtualMachine"} 0
+`
+	collector := &metrics.ResourceCollector{Client: c}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	// Kinds not in scheme of client cannot be listed.
+	c = clientfake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+	expected = `
+# HELP kupenstack_resources_list_errors Whether listing resources of kind failed on this scrape.
+# TYPE kupenstack_resources_list_errors gauge
+kupenstack_resources_list_errors{kind="Image"} 1
+kupenstack_resources_list_errors{kind="Network"} 1
+kupenstack_resources_list_errors{kind="VirtualMachine"} 1
+`
+	collector = &metrics.ResourceCollector{Client: c}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
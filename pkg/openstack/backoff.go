@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// Requests per second, and burst of requests, sent to each cloud by
+	// all its clients together.
+	requestsPerSecond = 20
+	requestBurst      = 40
+
+	// Rate limited requests are retried by gophercloud up to
+	// maxBackoffRetries times, when they can be retried within
+	// maxRetryDelay. Otherwise they fail and are retried by reconcilers.
+	maxBackoffRetries = 3
+	maxRetryDelay     = 10 * time.Second
+
+	// Reconciles of a resource failing again and again are retried after
+	// exponentially growing delays, from BackoffBase to BackoffMax.
+	BackoffBase = 500 * time.Millisecond
+	BackoffMax  = 5 * time.Minute
+
+	// Reconciles of all resources of a controller are retried at most
+	// reconcileRetriesPerSecond times per second, with reconcileRetryBurst.
+	reconcileRetriesPerSecond = 10
+	reconcileRetryBurst       = 100
+)
+
+// rateLimitedTransport delays requests exceeding rate limit of cloud, and
+// records them in metrics.
+type rateLimitedTransport struct {
+	limiter *rate.Limiter
+	metrics *metricsTransport
+}
+
+func newTransport() *rateLimitedTransport {
+	return &rateLimitedTransport{
+		limiter: rate.NewLimiter(requestsPerSecond, requestBurst),
+		metrics: newMetricsTransport(http.DefaultTransport),
+	}
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.metrics.RoundTrip(req)
+}
+
+// retryBackoff waits before gophercloud retries a request rejected with 429,
+// for as long as Retry-After header asks, or exponentially longer on every
+// retry.
+func retryBackoff(ctx context.Context, respErr *gophercloud.ErrUnexpectedResponseCode, err error, retries uint) error {
+
+	delay := RetryAfter(err)
+	if delay == 0 {
+		delay = BackoffBase << (retries - 1)
+	}
+	if delay > maxRetryDelay {
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return err
+	}
+}
+
+// RateLimiter returns rate limiter for work queues of reconcilers. Failing
+// resources are retried with exponential backoff per resource, and retries
+// of all resources are limited together.
+func RateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(BackoffBase, BackoffMax),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(reconcileRetriesPerSecond, reconcileRetryBurst)},
+	)
+}
+
+// Result returns result of a reconcile which ended with err, to be
+// requeued after `period` when err is nil.
+//
+// Failed reconciles are retried by work queue with exponential backoff,
+// see RateLimiter(), except when OpenStack asks to retry after a delay
+// with Retry-After, which is then used as is.
+func Result(err error, period time.Duration) (reconcile.Result, error) {
+
+	if err == nil {
+		return reconcile.Result{RequeueAfter: period}, nil
+	}
+
+	if IsRetryable(err) {
+		if delay := RetryAfter(err); delay > 0 {
+			return reconcile.Result{RequeueAfter: delay}, nil
+		}
+	}
+
+	return reconcile.Result{}, err
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func tooManyRequests(retryAfter string) error {
+	header := http.Header{}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return gophercloud.ErrDefault429{ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{
+		Actual: http.StatusTooManyRequests, ResponseHeader: header}}
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	// exponential delays: BackoffBase, 2*BackoffBase, ...
+	start := time.Now()
+	if err := retryBackoff(context.Background(), nil, tooManyRequests(""), 2); err != nil {
+		t.Fatalf("expected retry, got %v", err)
+	}
+	if waited := time.Since(start); waited < 2*BackoffBase {
+		t.Errorf("expected to wait %s, waited %s", 2*BackoffBase, waited)
+	}
+
+	// Retry-After beyond maxRetryDelay is left to reconcilers
+	if got := retryBackoff(context.Background(), nil, tooManyRequests("60"), 1); got == nil {
+		t.Error("expected error returned when Retry-After exceeds max retry delay")
+	}
+	if got := retryBackoff(context.Background(), nil, tooManyRequests(""), 6); got == nil {
+		t.Error("expected error returned when backoff exceeds max retry delay")
+	}
+
+	// waiting ends with ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	if got := retryBackoff(ctx, nil, tooManyRequests("5"), 1); got == nil {
+		t.Error("expected error when context is done")
+	}
+	if waited := time.Since(start); waited >= time.Second {
+		t.Errorf("expected no wait when context is done, waited %s", waited)
+	}
+}
+
+func TestResult(t *testing.T) {
+
+	failed := errors.New("failed")
+	tests := []struct {
+		name   string
+		err    error
+		result reconcile.Result
+		failed bool
+	}{
+		{name: "success", result: reconcile.Result{RequeueAfter: time.Minute}},
+		{name: "error", err: failed, failed: true},
+		{name: "retry after", err: tooManyRequests("30"), result: reconcile.Result{RequeueAfter: 30 * time.Second}},
+		{name: "rate limited without retry after", err: tooManyRequests(""), failed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Result(test.err, time.Minute)
+			if result != test.result {
+				t.Errorf("expected result %+v, got %+v", test.result, result)
+			}
+			if (err != nil) != test.failed {
+				t.Errorf("expected failed %v, got error %v", test.failed, err)
+			}
+		})
+	}
+}
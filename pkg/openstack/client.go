@@ -18,9 +18,12 @@ package openstack
 
 import (
 	"fmt"
+	"net/http"
+	"sync"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
 )
 
 const (
@@ -36,33 +39,133 @@ type Client struct {
 
 	provider *gophercloud.ProviderClient
 
-	// Client for each service
+	// Client for each service. Guarded by mu, as clients are shared by
+	// reconcilers.
+	mu         sync.Mutex
 	clientList map[string]*gophercloud.ServiceClient
+
+	// Transport of cloud client sends requests with.
+	transport *rateLimitedTransport
+
+	// Options client is authenticated with.
+	authOptions gophercloud.AuthOptions
+
+	// ID of project token of client is scoped to, empty for clients of
+	// project in authOptions.
+	projectID string
+
+	// Clients scoped to other projects. Shared with later admin clients
+	// of same cloud by Clouds.Set().
+	projects *projectClients
+}
+
+// projectClients caches clients scoped to projects of a cloud, keyed by
+// project ID.
+type projectClients struct {
+	mu      sync.Mutex
+	clients map[string]*Client
 }
 
 // New returns a new Client using the provided openstack authentication config.
+// It is rate limited on its own, use Clouds.Authenticate() for clients of
+// clouds used by controllers.
 func New(config *gophercloud.AuthOptions) (*Client, error) {
-	return newClient(config)
+	return newClient(config, newTransport())
 }
 
-func newClient(config *gophercloud.AuthOptions) (*Client, error) {
+// newClient returns a client sending requests with `transport`. Clients of
+// a cloud share transport of their cloud held by Clouds, and project clients
+// the transport of client they are created by, so that all are rate limited
+// together.
+func newClient(config *gophercloud.AuthOptions, transport *rateLimitedTransport) (*Client, error) {
 	if config == nil {
 		return nil, fmt.Errorf(msgInvalidAuthOptions)
 	}
 
-	providerClient, err := openstack.AuthenticatedClient(*config)
+	providerClient, err := openstack.NewClient(config.IdentityEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport.metrics.addEndpoint(providerClient.IdentityBase, "identity")
+
+	// Requests, including authentication, are rate limited and recorded
+	// in metrics.
+	providerClient.HTTPClient = http.Client{
+		Transport: transport,
+	}
+	providerClient.MaxBackoffRetries = maxBackoffRetries
+	providerClient.RetryBackoffFunc = retryBackoff
+
+	err = openstack.Authenticate(providerClient, *config)
 	if err != nil {
 		return nil, err
 	}
 
 	c := &Client{
-		provider:   providerClient,
-		clientList: make(map[string]*gophercloud.ServiceClient),
+		provider:    providerClient,
+		transport:   transport,
+		clientList:  make(map[string]*gophercloud.ServiceClient),
+		authOptions: *config,
+		projects:    &projectClients{clients: make(map[string]*Client)},
+	}
+
+	return c, nil
+}
+
+// Project returns a client authenticated with same credentials, whose token
+// is scoped to project with `id`. User of client must have a role on that
+// project. Clients are cached per cloud and re-authenticate when their token
+// expires.
+func (client *Client) Project(id string) (*Client, error) {
+
+	if client.provider == nil {
+		return nil, fmt.Errorf(MsgConnectionFailed)
 	}
 
+	client.projects.mu.Lock()
+	defer client.projects.mu.Unlock()
+
+	if c := client.projects.clients[id]; c != nil {
+		return c, nil
+	}
+
+	opts := client.authOptions
+	opts.TenantID = id
+	opts.TenantName = ""
+	opts.Scope = nil
+	opts.AllowReauth = true
+
+	c, err := newClient(&opts, client.transport)
+	if err != nil {
+		return nil, fmt.Errorf("cannot authenticate to project %s: %w", id, err)
+	}
+	c.projectID = id
+
+	client.projects.clients[id] = c
 	return c, nil
 }
 
+// ProjectID returns ID of project client is scoped to by Project(), or
+// empty string for other clients.
+func (client *Client) ProjectID() string {
+	return client.projectID
+}
+
+// UserID returns ID of user client is authenticated as.
+func (client *Client) UserID() (string, error) {
+
+	identity, err := client.GetClient("identity")
+	if err != nil {
+		return "", err
+	}
+
+	user, err := tokens.Get(identity, client.provider.Token()).ExtractUser()
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
 // client.GetClient() returns valid gophercloud.ServiceClient based on `Type` of service.
 // If ServiceClient does not exists then it lazily initializes it.
 //
@@ -71,8 +174,12 @@ func newClient(config *gophercloud.AuthOptions) (*Client, error) {
 //   * "identity"
 //   * "image"
 //   * "network"
+//   * "volume"
 func (client *Client) GetClient(Type string) (*gophercloud.ServiceClient, error) {
 
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
 	if client.clientList[Type] != nil {
 		return client.clientList[Type], nil
 	}
@@ -81,28 +188,34 @@ func (client *Client) GetClient(Type string) (*gophercloud.ServiceClient, error)
 		return nil, fmt.Errorf(MsgConnectionFailed)
 	}
 
+	var serviceClient *gophercloud.ServiceClient
 	var err error
 
 	switch Type {
 	case "compute":
-		client.clientList[Type], err = openstack.NewComputeV2(client.provider,
+		serviceClient, err = openstack.NewComputeV2(client.provider,
 			gophercloud.EndpointOpts{})
 	case "identity":
-		client.clientList[Type], err = openstack.NewIdentityV3(client.provider,
+		serviceClient, err = openstack.NewIdentityV3(client.provider,
 			gophercloud.EndpointOpts{})
 	case "image":
-		client.clientList[Type], err = openstack.NewImageServiceV2(client.provider,
+		serviceClient, err = openstack.NewImageServiceV2(client.provider,
 			gophercloud.EndpointOpts{})
 	case "network":
-		client.clientList[Type], err = openstack.NewNetworkV2(client.provider,
+		serviceClient, err = openstack.NewNetworkV2(client.provider,
+			gophercloud.EndpointOpts{})
+	case "volume":
+		serviceClient, err = openstack.NewBlockStorageV3(client.provider,
 			gophercloud.EndpointOpts{})
 	default:
 		return nil, fmt.Errorf(MsgConnectionFailed)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf(MsgConnectionFailed)
+		return nil, fmt.Errorf("%s %w", MsgConnectionFailed, err)
 	}
 
-	return client.clientList[Type], nil
+	client.transport.metrics.addEndpoint(serviceClient.Endpoint, Type)
+	client.clientList[Type] = serviceClient
+	return serviceClient, nil
 }
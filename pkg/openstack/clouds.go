@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudAnnotation is set on kupenstack resources to select the OpenStack cloud
+// they are created in. Value is name of the cloud i.e. `<occp-name>.<occp-namespace>`.
+// Resources without this annotation are created in the default cloud.
+const CloudAnnotation = "kupenstack.io/cloud"
+
+// Clouds holds one Client for each OpenStack cloud managed by kupenstack,
+// keyed by cloud name. It is safe for concurrent use.
+type Clouds struct {
+	mu sync.RWMutex
+
+	// Name of cloud used when resource does not select any.
+	defaultCloud string
+
+	clients map[string]*Client
+
+	// Rate limited transport of each cloud, shared by all its clients.
+	transports map[string]*rateLimitedTransport
+}
+
+// NewClouds returns an empty set of clouds.
+func NewClouds() *Clouds {
+	return &Clouds{
+		clients:    make(map[string]*Client),
+		transports: make(map[string]*rateLimitedTransport),
+	}
+}
+
+// SetDefault marks the cloud with `name` as default cloud.
+func (c *Clouds) SetDefault(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaultCloud = name
+}
+
+// Set stores authenticated client for cloud `name`. Project clients cached
+// by previous client of cloud are kept, unless credentials changed.
+func (c *Clouds) Set(name string, client *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old := c.clients[name]; old != nil && reflect.DeepEqual(old.authOptions, client.authOptions) {
+		client.projects = old.projects
+	}
+	c.clients[name] = client
+}
+
+// Authenticate stores a new client of cloud `name` authenticated with
+// `opts`, which sends requests through transport of cloud. When credentials
+// are rejected the current client of cloud is forgotten too, as its tokens
+// are revoked along with them.
+func (c *Clouds) Authenticate(name string, opts *gophercloud.AuthOptions) error {
+
+	c.mu.Lock()
+	transport := c.transports[name]
+	if transport == nil {
+		transport = newTransport()
+		c.transports[name] = transport
+	}
+	c.mu.Unlock()
+
+	client, err := newClient(opts, transport)
+	if IsAuthFailure(err) {
+		c.Delete(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Set(name, client)
+	return nil
+}
+
+// Delete forgets client and transport of cloud `name`.
+func (c *Clouds) Delete(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, name)
+	delete(c.transports, name)
+}
+
+// Names returns sorted names of all clouds having a client.
+func (c *Clouds) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.clients))
+	for name := range c.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Cloud returns client for cloud `name`, or for default cloud if name is empty.
+// When cloud is not yet authenticated an empty Client is returned, whose
+// GetClient() fails with MsgConnectionFailed.
+func (c *Clouds) Cloud(name string) *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if name == "" {
+		name = c.defaultCloud
+	}
+	if client := c.clients[name]; client != nil {
+		return client
+	}
+	return &Client{}
+}
+
+// For returns client for the cloud selected by CloudAnnotation on obj.
+func (c *Clouds) For(obj metav1.Object) *Client {
+	return c.Cloud(obj.GetAnnotations()[CloudAnnotation])
+}
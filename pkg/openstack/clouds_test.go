@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/users"
+
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+// memberRoleID returns ID of role member at server.
+func memberRoleID(t *testing.T, admin *openstack.Client) string {
+	identity, _ := admin.GetClient("identity")
+	allPages, err := roles.List(identity, roles.ListOpts{Name: "member"}).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allRoles, err := roles.ExtractRoles(allPages)
+	if err != nil || len(allRoles) == 0 {
+		t.Fatalf("role member not found: %v", err)
+	}
+	return allRoles[0].ID
+}
+
+// Project clients survive re-authentication of admin client of cloud, but
+// not change of its credentials.
+func TestCloudsProjectCache(t *testing.T) {
+
+	server := fake.NewServer()
+	defer server.Close()
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := admin.GetClient("identity")
+	project, err := projects.Create(identity, projects.CreateOpts{Name: "demo"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, err := users.Create(identity, users.CreateOpts{Name: "operator", Password: "secret"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID, err := admin.UserID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := memberRoleID(t, admin)
+	for _, userID := range []string{adminID, operator.ID} {
+		err = roles.Assign(identity, member, roles.AssignOpts{UserID: userID, ProjectID: project.ID}).ExtractErr()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clouds := openstack.NewClouds()
+	clouds.Set("fake", admin)
+	first, err := clouds.Cloud("fake").Project(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reauthenticated, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds.Set("fake", reauthenticated)
+	second, err := clouds.Cloud("fake").Project(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Error("expected project client kept when admin client re-authenticates")
+	}
+
+	opts := server.AdminAuthOptions()
+	opts.Username = "operator"
+	opts.Password = "secret"
+	opts.TenantName = "demo"
+	other, err := openstack.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clouds.Set("fake", other)
+	third, err := clouds.Cloud("fake").Project(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Error("expected new project client when credentials of cloud change")
+	}
+}
+
+// Project clients and their service clients are shared by reconcilers
+// running concurrently.
+func TestClientConcurrentUse(t *testing.T) {
+
+	server := fake.NewServer()
+	defer server.Close()
+	admin, err := openstack.New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := admin.GetClient("identity")
+	project, err := projects.Create(identity, projects.CreateOpts{Name: "demo"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID, err := admin.UserID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = roles.Assign(identity, memberRoleID(t, admin), roles.AssignOpts{UserID: adminID, ProjectID: project.ID}).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	clients := make(chan *openstack.Client, 20)
+	for i := 0; i < cap(clients); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := admin.Project(project.ID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for _, service := range []string{"compute", "network", "image"} {
+				if _, err := c.GetClient(service); err != nil {
+					t.Error(err)
+				}
+			}
+			clients <- c
+		}()
+	}
+	wg.Wait()
+	close(clients)
+
+	first := <-clients
+	for c := range clients {
+		if c != first {
+			t.Fatal("expected one client per project")
+		}
+	}
+}
+
+// Cloud whose credentials are rejected is left without client.
+func TestCloudsAuthenticate(t *testing.T) {
+
+	server := fake.NewServer()
+	defer server.Close()
+
+	clouds := openstack.NewClouds()
+	if err := clouds.Authenticate("fake", server.AdminAuthOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if names := clouds.Names(); len(names) != 1 || names[0] != "fake" {
+		t.Fatalf("expected client of cloud fake, got %v", names)
+	}
+	if _, err := clouds.Cloud("fake").GetClient("compute"); err != nil {
+		t.Errorf("expected authenticated client, got %v", err)
+	}
+
+	opts := server.AdminAuthOptions()
+	opts.Password = "rotated"
+	err := clouds.Authenticate("fake", opts)
+	if !openstack.IsAuthFailure(err) {
+		t.Fatalf("expected authentication failure, got %v", err)
+	}
+	if names := clouds.Names(); len(names) != 0 {
+		t.Errorf("expected client of cloud forgotten, got %v", names)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+// StatusCode returns HTTP status code of response an OpenStack request
+// failed with, or 0 when err is not a response error. Errors returned
+// after re-authentication are classified by their original error.
+func StatusCode(err error) int {
+
+	var afterReauth *gophercloud.ErrErrorAfterReauthentication
+	if errors.As(err, &afterReauth) {
+		err = afterReauth.ErrOriginal
+	}
+
+	var statusErr gophercloud.StatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.GetStatusCode()
+	}
+	return 0
+}
+
+// IsNotFound returns true when resource does not exist at OpenStack.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IgnoreNotFound returns nil on not found errors, and err otherwise. It is
+// used when deleting resources, which may be already gone.
+func IgnoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// IsConflict returns true when request conflicts with current state of
+// resource, e.g. server is in a transitional state or name is in use.
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+// IsRetryable returns true when OpenStack is rate limiting requests or is
+// temporarily unavailable, so that the same request may succeed later.
+func IsRetryable(err error) bool {
+	code := StatusCode(err)
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// IsAuthFailure returns true when credentials or token of client are not
+// accepted, including when re-authentication failed.
+func IsAuthFailure(err error) bool {
+
+	var reauth *gophercloud.ErrUnableToReauthenticate
+	if errors.As(err, &reauth) {
+		return true
+	}
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+// IsEndpointNotFound returns true when the service catalog of cloud has no
+// endpoint of a service, e.g. cloud has no block storage.
+func IsEndpointNotFound(err error) bool {
+	var notFound *gophercloud.ErrEndpointNotFound
+	return errors.As(err, &notFound)
+}
+
+// RetryAfter returns delay requested with Retry-After header by a
+// retryable error, or 0 if none.
+func RetryAfter(err error) time.Duration {
+
+	var header http.Header
+	var tooMany gophercloud.ErrDefault429
+	var unavailable gophercloud.ErrDefault503
+	switch {
+	case errors.As(err, &tooMany):
+		header = tooMany.ResponseHeader
+	case errors.As(err, &unavailable):
+		header = unavailable.ResponseHeader
+	default:
+		return 0
+	}
+
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+)
+
+// responseError returns error gophercloud fails a request with when
+// response has status `code` and `header`.
+func responseError(code int, header http.Header) error {
+	respErr := gophercloud.ErrUnexpectedResponseCode{Actual: code, ResponseHeader: header}
+	switch code {
+	case http.StatusUnauthorized:
+		return gophercloud.ErrDefault401{ErrUnexpectedResponseCode: respErr}
+	case http.StatusNotFound:
+		return gophercloud.ErrDefault404{ErrUnexpectedResponseCode: respErr}
+	case http.StatusConflict:
+		return gophercloud.ErrDefault409{ErrUnexpectedResponseCode: respErr}
+	case http.StatusTooManyRequests:
+		return gophercloud.ErrDefault429{ErrUnexpectedResponseCode: respErr}
+	case http.StatusServiceUnavailable:
+		return gophercloud.ErrDefault503{ErrUnexpectedResponseCode: respErr}
+	}
+	return respErr
+}
+
+func TestErrorClassification(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		err        error
+		code       int
+		notFound   bool
+		conflict   bool
+		retryable  bool
+		authFailed bool
+	}{
+		{name: "not a response error", err: errors.New("connection refused")},
+		{name: "not found", err: responseError(404, nil), code: 404, notFound: true},
+		{name: "wrapped not found", err: fmt.Errorf("get server: %w", responseError(404, nil)), code: 404, notFound: true},
+		{name: "conflict", err: responseError(409, nil), code: 409, conflict: true},
+		{name: "rate limited", err: responseError(429, nil), code: 429, retryable: true},
+		{name: "unavailable", err: responseError(503, nil), code: 503, retryable: true},
+		{name: "server error", err: responseError(500, nil), code: 500},
+		{name: "unauthorized", err: responseError(401, nil), code: 401, authFailed: true},
+		{
+			name:      "after re-authentication",
+			err:       &gophercloud.ErrErrorAfterReauthentication{ErrOriginal: responseError(429, nil)},
+			code:      429,
+			retryable: true,
+		},
+		{
+			name:       "re-authentication failed",
+			err:        &gophercloud.ErrUnableToReauthenticate{ErrOriginal: errors.New("connection refused")},
+			authFailed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := openstack.StatusCode(test.err); code != test.code {
+				t.Errorf("expected status code %d, got %d", test.code, code)
+			}
+			if got := openstack.IsNotFound(test.err); got != test.notFound {
+				t.Errorf("expected IsNotFound %v, got %v", test.notFound, got)
+			}
+			if got := openstack.IsConflict(test.err); got != test.conflict {
+				t.Errorf("expected IsConflict %v, got %v", test.conflict, got)
+			}
+			if got := openstack.IsRetryable(test.err); got != test.retryable {
+				t.Errorf("expected IsRetryable %v, got %v", test.retryable, got)
+			}
+			if got := openstack.IsAuthFailure(test.err); got != test.authFailed {
+				t.Errorf("expected IsAuthFailure %v, got %v", test.authFailed, got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	retryAfter := func(value string) http.Header {
+		return http.Header{"Retry-After": []string{value}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		min  time.Duration
+		max  time.Duration
+	}{
+		{name: "seconds", err: responseError(429, retryAfter("30")), min: 30 * time.Second, max: 30 * time.Second},
+		{name: "unavailable", err: responseError(503, retryAfter("5")), min: 5 * time.Second, max: 5 * time.Second},
+		{
+			name: "http date",
+			err:  responseError(429, retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))),
+			min:  50 * time.Second,
+			max:  time.Minute,
+		},
+		{name: "date in past", err: responseError(429, retryAfter("Mon, 02 Jan 2006 15:04:05 GMT"))},
+		{name: "invalid", err: responseError(429, retryAfter("soon"))},
+		{name: "no header", err: responseError(429, nil)},
+		{name: "not retryable", err: responseError(404, retryAfter("30"))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := openstack.RetryAfter(test.err)
+			if got < test.min || got > test.max {
+				t.Errorf("expected delay within [%s, %s], got %s", test.min, test.max, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// authenticate handles POST /v3/auth/tokens with password method, scoped
+// to a project by ID, or by name and domain.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) {
+
+	body, err := decode(r, "auth")
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	identity, _ := body["identity"].(object)
+	password, _ := identity["password"].(object)
+	credentials, _ := password["user"].(object)
+
+	user := s.findUser(credentials)
+	if user == nil || s.passwords[str(user, "id")] != str(credentials, "password") {
+		reply(w, http.StatusUnauthorized, object{"error": object{"code": 401, "message": "The request you have made requires authentication."}})
+		return
+	}
+	tok := token{userID: str(user, "id")}
+
+	var project object
+	if scope, ok := body["scope"].(object); ok {
+		scoped, _ := scope["project"].(object)
+		project = s.findProject(scoped)
+		if project == nil || len(s.userRoles(tok.userID, str(project, "id"))) == 0 {
+			reply(w, http.StatusUnauthorized, object{"error": object{"code": 401, "message": "User has no access to project."}})
+			return
+		}
+		tok.projectID = str(project, "id")
+	}
+
+	id := newID()
+	s.tokens[id] = tok
+
+	w.Header().Set("X-Subject-Token", id)
+	reply(w, http.StatusCreated, object{"token": s.tokenBody(tok)})
+}
+
+func (s *Server) tokenBody(tok token) object {
+
+	user := s.users[tok.userID]
+	body := object{
+		"methods":    []string{"password"},
+		"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"issued_at":  now(),
+		"user": object{
+			"id":     user["id"],
+			"name":   user["name"],
+			"domain": object{"id": DomainID, "name": DomainName},
+		},
+	}
+
+	if tok.projectID != "" {
+		project := s.projects[tok.projectID]
+		body["project"] = object{
+			"id":     project["id"],
+			"name":   project["name"],
+			"domain": object{"id": DomainID, "name": DomainName},
+		}
+		var roles []interface{}
+		for _, roleID := range s.userRoles(tok.userID, tok.projectID) {
+			roles = append(roles, s.roles[roleID])
+		}
+		body["roles"] = roles
+		body["catalog"] = s.catalog()
+	}
+
+	return body
+}
+
+func (s *Server) serveIdentity(w http.ResponseWriter, r *http.Request, tok token, path []string) {
+
+	switch {
+	case len(path) == 2 && path[0] == "auth" && path[1] == "tokens" && r.Method == http.MethodGet:
+		subject, ok := s.tokens[r.Header.Get("X-Subject-Token")]
+		if !ok {
+			notFound(w)
+			return
+		}
+		reply(w, http.StatusOK, object{"token": s.tokenBody(subject)})
+
+	case len(path) == 2 && path[0] == "auth" && path[1] == "projects" && r.Method == http.MethodGet:
+		projects := []interface{}{}
+		for id, project := range s.projects {
+			if len(s.userRoles(tok.userID, id)) > 0 {
+				projects = append(projects, project)
+			}
+		}
+		reply(w, http.StatusOK, object{"projects": projects})
+
+	case len(path) == 6 && path[0] == "projects" && path[4] == "roles":
+		s.serveRoleAssignment(w, r, path[1], path[2], path[3], path[5])
+
+	case len(path) >= 1 && path[0] == "projects":
+		s.serveIdentityCollection(w, r, path, "project", s.projects, s.deleteProject)
+
+	case len(path) >= 1 && path[0] == "users":
+		s.serveIdentityCollection(w, r, path, "user", s.users, s.deleteUser)
+
+	case len(path) >= 1 && path[0] == "groups":
+		s.serveIdentityCollection(w, r, path, "group", s.groups, s.deleteGroup)
+
+	case len(path) == 1 && path[0] == "roles" && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"roles": list(s.roles, r, "", "")})
+
+	case len(path) == 1 && path[0] == "role_assignments" && r.Method == http.MethodGet:
+		s.listRoleAssignments(w, r)
+
+	default:
+		notFound(w)
+	}
+}
+
+// serveIdentityCollection serves create, list, get and delete of projects,
+// users and groups.
+func (s *Server) serveIdentityCollection(w http.ResponseWriter, r *http.Request, path []string,
+	kind string, collection map[string]object, remove func(id string)) {
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodPost:
+		obj, err := decode(r, kind)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		for _, existing := range collection {
+			if existing["name"] == obj["name"] {
+				conflict(w, fmt.Sprintf("Duplicate entry found with name %s.", obj["name"]))
+				return
+			}
+		}
+		switch kind {
+		case "project":
+			obj = s.createProject(obj)
+		case "user":
+			obj = s.createUser(obj, str(obj, "password"))
+		default:
+			obj = s.createGroup(obj)
+		}
+		reply(w, http.StatusCreated, object{kind: obj})
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		items := list(collection, r, "domain_id", r.URL.Query().Get("domain_id"))
+		reply(w, http.StatusOK, object{kind + "s": items, "links": object{}})
+
+	case len(path) == 2 && collection[path[1]] == nil:
+		notFound(w)
+
+	case len(path) == 2 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{kind: collection[path[1]]})
+
+	case len(path) == 2 && r.Method == http.MethodDelete:
+		remove(path[1])
+		reply(w, http.StatusNoContent, nil)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveRoleAssignment(w http.ResponseWriter, r *http.Request, projectID, kind, actorID, roleID string) {
+
+	actors := s.users
+	if kind == "groups" {
+		actors = s.groups
+	}
+	if s.projects[projectID] == nil || actors[actorID] == nil || s.roles[roleID] == nil {
+		notFound(w)
+		return
+	}
+
+	a := assignment{roleID: roleID, projectID: projectID}
+	if kind == "groups" {
+		a.groupID = actorID
+	} else {
+		a.userID = actorID
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if s.indexOf(a) < 0 {
+			s.assignments = append(s.assignments, a)
+		}
+		reply(w, http.StatusNoContent, nil)
+	case http.MethodDelete:
+		i := s.indexOf(a)
+		if i < 0 {
+			notFound(w)
+			return
+		}
+		s.assignments = append(s.assignments[:i], s.assignments[i+1:]...)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) listRoleAssignments(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	items := []interface{}{}
+	for _, a := range s.assignments {
+		if v := query.Get("user.id"); v != "" && a.userID != v {
+			continue
+		}
+		if v := query.Get("group.id"); v != "" && a.groupID != v {
+			continue
+		}
+		if v := query.Get("role.id"); v != "" && a.roleID != v {
+			continue
+		}
+		if v := query.Get("scope.project.id"); v != "" && a.projectID != v {
+			continue
+		}
+
+		item := object{
+			"role":  object{"id": a.roleID},
+			"scope": object{"project": object{"id": a.projectID}},
+		}
+		if a.userID != "" {
+			item["user"] = object{"id": a.userID}
+		} else {
+			item["group"] = object{"id": a.groupID}
+		}
+		items = append(items, item)
+	}
+
+	reply(w, http.StatusOK, object{"role_assignments": items, "links": object{}})
+}
+
+func (s *Server) createProject(obj object) object {
+	id := newID()
+	project := object{
+		"id":          id,
+		"name":        obj["name"],
+		"description": str(obj, "description"),
+		"domain_id":   DomainID,
+		"enabled":     true,
+		"is_domain":   false,
+		"parent_id":   DomainID,
+	}
+	s.projects[id] = project
+	return project
+}
+
+func (s *Server) createUser(obj object, password string) object {
+	id := newID()
+	user := object{
+		"id":          id,
+		"name":        obj["name"],
+		"description": str(obj, "description"),
+		"domain_id":   DomainID,
+		"enabled":     true,
+	}
+	s.users[id] = user
+	s.passwords[id] = password
+	return user
+}
+
+func (s *Server) createGroup(obj object) object {
+	id := newID()
+	group := object{
+		"id":          id,
+		"name":        obj["name"],
+		"description": str(obj, "description"),
+		"domain_id":   DomainID,
+	}
+	s.groups[id] = group
+	return group
+}
+
+func (s *Server) deleteProject(id string) {
+	delete(s.projects, id)
+	delete(s.computeQuotas, id)
+	delete(s.networkQuotas, id)
+	s.removeAssignments(func(a assignment) bool { return a.projectID == id })
+}
+
+func (s *Server) deleteUser(id string) {
+	delete(s.users, id)
+	delete(s.passwords, id)
+	s.removeAssignments(func(a assignment) bool { return a.userID == id })
+	for t, tok := range s.tokens {
+		if tok.userID == id {
+			delete(s.tokens, t)
+		}
+	}
+}
+
+func (s *Server) deleteGroup(id string) {
+	delete(s.groups, id)
+	s.removeAssignments(func(a assignment) bool { return a.groupID == id })
+}
+
+func (s *Server) removeAssignments(match func(assignment) bool) {
+	var kept []assignment
+	for _, a := range s.assignments {
+		if !match(a) {
+			kept = append(kept, a)
+		}
+	}
+	s.assignments = kept
+}
+
+func (s *Server) indexOf(a assignment) int {
+	for i, existing := range s.assignments {
+		if existing == a {
+			return i
+		}
+	}
+	return -1
+}
+
+// userRoles returns IDs of roles user has on project.
+func (s *Server) userRoles(userID, projectID string) []string {
+	var roles []string
+	for _, a := range s.assignments {
+		if a.userID == userID && a.projectID == projectID {
+			roles = append(roles, a.roleID)
+		}
+	}
+	return roles
+}
+
+func (s *Server) roleID(name string) string {
+	for id, role := range s.roles {
+		if role["name"] == name {
+			return id
+		}
+	}
+	return ""
+}
+
+// findUser returns user identified by ID, or by name in the domain.
+func (s *Server) findUser(ref object) object {
+	if id := str(ref, "id"); id != "" {
+		return s.users[id]
+	}
+	for _, user := range s.users {
+		if user["name"] == ref["name"] && inDomain(ref) {
+			return user
+		}
+	}
+	return nil
+}
+
+// findProject returns project identified by ID, or by name in the domain.
+func (s *Server) findProject(ref object) object {
+	if id := str(ref, "id"); id != "" {
+		return s.projects[id]
+	}
+	for _, project := range s.projects {
+		if project["name"] == ref["name"] && inDomain(ref) {
+			return project
+		}
+	}
+	return nil
+}
+
+// inDomain returns false when ref names a domain other than the default.
+func inDomain(ref object) bool {
+	domain, ok := ref["domain"].(object)
+	if !ok {
+		return true
+	}
+	return str(domain, "id") == DomainID || str(domain, "name") == DomainName
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Size of images imported by web-download.
+const importedImageSize = 16338944
+
+func (s *Server) serveImage(w http.ResponseWriter, r *http.Request, tok token, path []string) {
+
+	if len(path) == 0 || path[0] != "images" {
+		notFound(w)
+		return
+	}
+	path = path[1:]
+
+	switch {
+	case len(path) == 0 && r.Method == http.MethodPost:
+		image, err := decode(r, "")
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		id := str(image, "id")
+		if id == "" {
+			id = newUUID()
+		}
+		if s.images[id] != nil {
+			conflict(w, fmt.Sprintf("Image with identifier %s already exists!", id))
+			return
+		}
+		image["id"] = id
+		image["status"] = "queued"
+		image["owner"] = tok.projectID
+		image["size"] = nil
+		image["min_disk"] = num(image, "min_disk", 0)
+		image["min_ram"] = num(image, "min_ram", 0)
+		image["protected"] = image["protected"] == true
+		if str(image, "visibility") == "" {
+			image["visibility"] = "shared"
+		}
+		if _, ok := image["tags"]; !ok {
+			image["tags"] = []interface{}{}
+		}
+		image["created_at"] = now()
+		image["updated_at"] = now()
+		image["self"] = "/v2/images/" + id
+		image["file"] = "/v2/images/" + id + "/file"
+		image["schema"] = "/v2/schemas/image"
+		s.images[id] = image
+		reply(w, http.StatusCreated, image)
+
+	case len(path) == 0 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"images": list(s.images, r, "", "")})
+
+	case len(path) >= 1 && s.images[path[0]] == nil:
+		notFound(w)
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, s.images[path[0]])
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		delete(s.images, path[0])
+		reply(w, http.StatusNoContent, nil)
+
+	case len(path) == 2 && path[1] == "import" && r.Method == http.MethodPost:
+		body, err := decode(r, "method")
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		image := s.images[path[0]]
+		if str(body, "name") != "web-download" || str(body, "uri") == "" {
+			badRequest(w, fmt.Errorf("Import method %s is not supported.", str(body, "name")))
+			return
+		}
+		if image["status"] != "queued" {
+			conflict(w, fmt.Sprintf("Image status transition from %s to importing is not allowed", image["status"]))
+			return
+		}
+		// Download completes immediately.
+		image["status"] = "active"
+		image["size"] = importedImageSize
+		image["updated_at"] = now()
+		reply(w, http.StatusAccepted, nil)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
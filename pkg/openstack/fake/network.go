@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Default network quota limits of projects.
+var networkQuotaDefaults = map[string]int{
+	"network":             100,
+	"subnet":              100,
+	"port":                500,
+	"router":              10,
+	"floatingip":          50,
+	"security_group":      10,
+	"security_group_rule": 100,
+	"rbac_policy":         10,
+	"subnetpool":          -1,
+	"trunk":               -1,
+}
+
+func (s *Server) serveNetwork(w http.ResponseWriter, r *http.Request, tok token, path []string) {
+
+	if len(path) == 0 {
+		notFound(w)
+		return
+	}
+
+	switch path[0] {
+	case "networks":
+		s.serveNetworks(w, r, tok, path[1:])
+	case "subnets":
+		s.serveSubnets(w, r, tok, path[1:])
+	case "quotas":
+		s.serveNetworkQuotas(w, r, path[1:])
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveNetworks(w http.ResponseWriter, r *http.Request, tok token, path []string) {
+
+	switch {
+	case len(path) == 0 && r.Method == http.MethodPost:
+		network, err := decode(r, "network")
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		if s.networkUsage(tok.projectID)["network"]+1 > s.networkQuota(tok.projectID)["network"] {
+			reply(w, http.StatusConflict, object{"NeutronError": object{"type": "OverQuota",
+				"message": "Quota exceeded for resources: ['network']."}})
+			return
+		}
+		id := newUUID()
+		network["id"] = id
+		network["status"] = "ACTIVE"
+		network["subnets"] = []interface{}{}
+		network["tenant_id"] = tok.projectID
+		network["project_id"] = tok.projectID
+		network["mtu"] = num(network, "mtu", 1450)
+		if _, ok := network["admin_state_up"]; !ok {
+			network["admin_state_up"] = true
+		}
+		if _, ok := network["shared"]; !ok {
+			network["shared"] = false
+		}
+		network["created_at"] = now()
+		network["updated_at"] = now()
+		s.networks[id] = network
+		reply(w, http.StatusCreated, object{"network": network})
+
+	case len(path) == 0 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"networks": list(s.networks, r, "", "")})
+
+	case len(path) == 1 && s.networks[path[0]] == nil:
+		notFound(w)
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"network": s.networks[path[0]]})
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		for _, networks := range s.serverNetworks {
+			if containsString(networks, path[0]) {
+				conflict(w, fmt.Sprintf("Unable to complete operation on network %s. There are one or more ports still in use on the network.", path[0]))
+				return
+			}
+		}
+		for _, subnetID := range s.networks[path[0]]["subnets"].([]interface{}) {
+			delete(s.subnets, subnetID.(string))
+		}
+		delete(s.networks, path[0])
+		reply(w, http.StatusNoContent, nil)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveSubnets(w http.ResponseWriter, r *http.Request, tok token, path []string) {
+
+	switch {
+	case len(path) == 0 && r.Method == http.MethodPost:
+		subnet, err := decode(r, "subnet")
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		network := s.networks[str(subnet, "network_id")]
+		if network == nil {
+			notFound(w)
+			return
+		}
+		_, cidr, err := net.ParseCIDR(str(subnet, "cidr"))
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		id := newUUID()
+		subnet["id"] = id
+		subnet["cidr"] = cidr.String()
+		subnet["tenant_id"] = tok.projectID
+		subnet["project_id"] = tok.projectID
+		if _, ok := subnet["gateway_ip"]; !ok {
+			subnet["gateway_ip"] = addIP(cidr.IP, 1).String()
+		}
+		if _, ok := subnet["allocation_pools"]; !ok {
+			subnet["allocation_pools"] = []interface{}{object{
+				"start": addIP(cidr.IP, 2).String(),
+				"end":   lastIP(cidr).String(),
+			}}
+		}
+		if _, ok := subnet["enable_dhcp"]; !ok {
+			subnet["enable_dhcp"] = true
+		}
+		s.subnets[id] = subnet
+		network["subnets"] = append(network["subnets"].([]interface{}), id)
+		reply(w, http.StatusCreated, object{"subnet": subnet})
+
+	case len(path) == 0 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"subnets": list(s.subnets, r, "network_id", r.URL.Query().Get("network_id"))})
+
+	case len(path) == 1 && s.subnets[path[0]] == nil:
+		notFound(w)
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"subnet": s.subnets[path[0]]})
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		network := s.networks[str(s.subnets[path[0]], "network_id")]
+		var kept []interface{}
+		for _, id := range network["subnets"].([]interface{}) {
+			if id != path[0] {
+				kept = append(kept, id)
+			}
+		}
+		network["subnets"] = kept
+		delete(s.subnets, path[0])
+		reply(w, http.StatusNoContent, nil)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveNetworkQuotas(w http.ResponseWriter, r *http.Request, path []string) {
+
+	if len(path) == 0 || s.projects[path[0]] == nil {
+		notFound(w)
+		return
+	}
+	projectID := path[0]
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodPut:
+		update, err := decode(r, "quota")
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		limits := s.networkQuota(projectID)
+		for k := range networkQuotaDefaults {
+			if _, ok := update[k]; ok {
+				limits[k] = num(update, k, limits[k])
+			}
+		}
+		s.networkQuotas[projectID] = limitsObject(limits)
+		reply(w, http.StatusOK, object{"quota": s.networkQuotas[projectID]})
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, object{"quota": limitsObject(s.networkQuota(projectID))})
+
+	case len(path) == 2 && path[1] == "details.json" && r.Method == http.MethodGet:
+		usage := s.networkUsage(projectID)
+		detail := object{}
+		for k, limit := range s.networkQuota(projectID) {
+			detail[k] = object{"limit": limit, "used": usage[k], "reserved": 0}
+		}
+		reply(w, http.StatusOK, object{"quota": detail})
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		delete(s.networkQuotas, projectID)
+		reply(w, http.StatusNoContent, nil)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// networkQuota returns network limits of project.
+func (s *Server) networkQuota(projectID string) map[string]int {
+	limits := make(map[string]int)
+	for k, v := range networkQuotaDefaults {
+		limits[k] = num(s.networkQuotas[projectID], k, v)
+	}
+	return limits
+}
+
+// networkUsage returns networks, subnets and ports of project.
+func (s *Server) networkUsage(projectID string) map[string]int {
+	usage := make(map[string]int)
+	for _, network := range s.networks {
+		if network["tenant_id"] == projectID {
+			usage["network"]++
+		}
+	}
+	for _, subnet := range s.subnets {
+		if subnet["tenant_id"] == projectID {
+			usage["subnet"]++
+		}
+	}
+	for _, server := range s.servers {
+		if server["tenant_id"] != projectID {
+			continue
+		}
+		for _, ips := range server["addresses"].(object) {
+			usage["port"] += len(ips.([]interface{}))
+		}
+	}
+	return usage
+}
+
+// allocateIP returns next unused address from first allocation pool of
+// subnet. Addresses are not reused.
+func (s *Server) allocateIP(subnetID string) string {
+
+	subnet := s.subnets[subnetID]
+	pools, _ := subnet["allocation_pools"].([]interface{})
+	if len(pools) == 0 {
+		return ""
+	}
+	pool, _ := pools[0].(object)
+
+	ip := addIP(net.ParseIP(str(pool, "start")), s.allocated[subnetID])
+	s.allocated[subnetID]++
+
+	if strings.Contains(str(subnet, "cidr"), ".") {
+		return ip.To4().String()
+	}
+	return ip.String()
+}
+
+// addIP returns ip incremented by n.
+func addIP(ip net.IP, n int) net.IP {
+	v := new(big.Int).SetBytes(ip.To16())
+	v.Add(v, big.NewInt(int64(n)))
+	b := v.Bytes()
+	out := make(net.IP, net.IPv6len)
+	copy(out[net.IPv6len-len(b):], b)
+	if ip.To4() != nil {
+		return out.To4()
+	}
+	return out
+}
+
+// lastIP returns last usable address of cidr.
+func lastIP(cidr *net.IPNet) net.IP {
+	ones, bits := cidr.Mask.Size()
+	hostBits := bits - ones
+	if hostBits > 30 {
+		hostBits = 30
+	}
+	return addIP(cidr.IP, 1<<hostBits-2)
+}
@@ -0,0 +1,355 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake implements an in-memory fake of the subset of Keystone,
+// Nova, Neutron and Glance apis used by kupenstack controllers: projects,
+// users, groups and role assignments, servers with their migration,
+// nova-compute services and their hypervisors, flavors, keypairs, networks, subnets, images with
+// web-download import, and compute and network quotas.
+//
+// Server is a real http server on localhost, so gophercloud clients and
+// openstack.New work against it unchanged. It is used by tests, and by
+// the manager in simulated-cloud mode.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// Credentials of admin user, who has admin role on admin project.
+	AdminUser     = "admin"
+	AdminPassword = "password"
+	AdminProject  = "admin"
+
+	// Name and ID of the only domain.
+	DomainName = "Default"
+	DomainID   = "default"
+
+	// Host all servers run on.
+	ComputeHost = "fake-compute-0"
+
+	// Host servers are moved to by migration and evacuation.
+	OtherComputeHost = "fake-compute-1"
+)
+
+type object = map[string]interface{}
+
+type token struct {
+	userID    string
+	projectID string
+}
+
+type assignment struct {
+	roleID    string
+	userID    string
+	groupID   string
+	projectID string
+}
+
+// Server is an in-memory OpenStack cloud served over http. It is safe for
+// concurrent use.
+type Server struct {
+	// Base url of server.
+	URL string
+
+	srv *httptest.Server
+
+	mu sync.Mutex
+
+	tokens      map[string]token
+	projects    map[string]object
+	users       map[string]object
+	passwords   map[string]string
+	groups      map[string]object
+	roles       map[string]object
+	assignments []assignment
+
+	servers  map[string]object
+	services map[string]object
+	flavors  map[string]object
+	keypairs map[string]object
+	networks map[string]object
+	subnets  map[string]object
+	images   map[string]object
+
+	computeQuotas map[string]object
+	networkQuotas map[string]object
+
+	// IDs of networks each server is attached to.
+	serverNetworks map[string][]string
+
+	// Status of servers before their migration awaiting confirmation.
+	resizedFrom map[string]interface{}
+
+	// Number of addresses allocated in each subnet.
+	allocated map[string]int
+}
+
+// NewServer starts a fake cloud with admin user, admin project and roles
+// admin, member and reader. Close it when done.
+func NewServer() *Server {
+
+	s := &Server{
+		tokens:         make(map[string]token),
+		projects:       make(map[string]object),
+		users:          make(map[string]object),
+		passwords:      make(map[string]string),
+		groups:         make(map[string]object),
+		roles:          make(map[string]object),
+		servers:        make(map[string]object),
+		services:       make(map[string]object),
+		flavors:        make(map[string]object),
+		keypairs:       make(map[string]object),
+		networks:       make(map[string]object),
+		subnets:        make(map[string]object),
+		images:         make(map[string]object),
+		computeQuotas:  make(map[string]object),
+		networkQuotas:  make(map[string]object),
+		serverNetworks: make(map[string][]string),
+		resizedFrom:    make(map[string]interface{}),
+		allocated:      make(map[string]int),
+	}
+
+	for _, host := range []string{ComputeHost, OtherComputeHost} {
+		id := newUUID()
+		s.services[id] = object{
+			"id":              id,
+			"binary":          "nova-compute",
+			"host":            host,
+			"zone":            "nova",
+			"status":          "enabled",
+			"state":           "up",
+			"disabled_reason": nil,
+			"forced_down":     false,
+			"updated_at":      time.Now().UTC().Format("2006-01-02T15:04:05.000000"),
+		}
+	}
+
+	for _, name := range []string{"admin", "member", "reader"} {
+		id := newID()
+		s.roles[id] = object{"id": id, "name": name}
+	}
+	project := s.createProject(object{"name": AdminProject})
+	user := s.createUser(object{"name": AdminUser}, AdminPassword)
+	s.assignments = append(s.assignments, assignment{
+		roleID:    s.roleID("admin"),
+		userID:    user["id"].(string),
+		projectID: project["id"].(string),
+	})
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetComputeState sets state of nova-compute service on host, `up` or
+// `down`.
+func (s *Server) SetComputeState(host, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, service := range s.services {
+		if service["host"] == host {
+			service["state"] = state
+		}
+	}
+}
+
+// IdentityEndpoint returns keystone url of cloud.
+func (s *Server) IdentityEndpoint() string {
+	return s.URL + "/identity/v3"
+}
+
+// AdminAuthOptions returns options authenticating as admin on admin project.
+func (s *Server) AdminAuthOptions() *gophercloud.AuthOptions {
+	return &gophercloud.AuthOptions{
+		IdentityEndpoint: s.IdentityEndpoint(),
+		Username:         AdminUser,
+		Password:         AdminPassword,
+		DomainName:       DomainName,
+		TenantName:       AdminProject,
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) < 2 {
+		notFound(w)
+		return
+	}
+
+	if path[0] == "identity" && path[1] == "v3" && len(path) == 4 && path[2] == "auth" && path[3] == "tokens" &&
+		r.Method == http.MethodPost {
+		s.authenticate(w, r)
+		return
+	}
+
+	tok, ok := s.tokens[r.Header.Get("X-Auth-Token")]
+	if !ok {
+		reply(w, http.StatusUnauthorized, object{"error": object{"code": 401, "message": "The request you have made requires authentication."}})
+		return
+	}
+
+	switch {
+	case path[0] == "identity" && path[1] == "v3":
+		s.serveIdentity(w, r, tok, path[2:])
+	case path[0] == "compute" && path[1] == "v2.1":
+		s.serveCompute(w, r, tok, path[2:])
+	case path[0] == "network" && path[1] == "v2.0":
+		s.serveNetwork(w, r, tok, path[2:])
+	case path[0] == "image" && path[1] == "v2":
+		s.serveImage(w, r, tok, path[2:])
+	default:
+		notFound(w)
+	}
+}
+
+// catalog returns service catalog of tokens.
+func (s *Server) catalog() []interface{} {
+
+	endpoints := map[string]string{
+		"identity": s.URL + "/identity/v3",
+		"compute":  s.URL + "/compute/v2.1",
+		"network":  s.URL + "/network",
+		"image":    s.URL + "/image",
+	}
+
+	var catalog []interface{}
+	for _, service := range []string{"identity", "compute", "network", "image"} {
+		catalog = append(catalog, object{
+			"type": service,
+			"name": service,
+			"id":   service,
+			"endpoints": []interface{}{
+				object{"id": service, "interface": "public", "region": "RegionOne", "region_id": "RegionOne",
+					"url": endpoints[service]},
+			},
+		})
+	}
+	return catalog
+}
+
+// newUUID returns ID for nova, neutron and glance resources.
+func newUUID() string {
+	return string(uuid.NewUUID())
+}
+
+// newID returns ID for keystone resources and tokens.
+func newID() string {
+	return strings.ReplaceAll(newUUID(), "-", "")
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// decode reads json request body, and returns its `key` object, or whole
+// body when key is empty.
+func decode(r *http.Request, key string) (object, error) {
+
+	var body object
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return body, nil
+	}
+	obj, ok := body[key].(object)
+	if !ok {
+		return nil, fmt.Errorf("request body has no %s", key)
+	}
+	return obj, nil
+}
+
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func notFound(w http.ResponseWriter) {
+	reply(w, http.StatusNotFound, object{"itemNotFound": object{"code": 404, "message": "Resource not found"}})
+}
+
+func badRequest(w http.ResponseWriter, err error) {
+	reply(w, http.StatusBadRequest, object{"badRequest": object{"code": 400, "message": err.Error()}})
+}
+
+func conflict(w http.ResponseWriter, message string) {
+	reply(w, http.StatusConflict, object{"conflictingRequest": object{"code": 409, "message": message}})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	reply(w, http.StatusMethodNotAllowed, nil)
+}
+
+// list returns objects of `collection` matching `name` query parameter of
+// request when it is set, and `owner` when it is not empty.
+func list(collection map[string]object, r *http.Request, ownerKey, owner string) []interface{} {
+
+	name := r.URL.Query().Get("name")
+	items := []interface{}{}
+	for _, obj := range collection {
+		if name != "" && obj["name"] != name {
+			continue
+		}
+		if owner != "" && obj[ownerKey] != owner {
+			continue
+		}
+		items = append(items, obj)
+	}
+	return items
+}
+
+// str returns string value of `key` of obj, or empty string.
+func str(obj object, key string) string {
+	v, _ := obj[key].(string)
+	return v
+}
+
+// num returns numeric value of `key` of obj, or def when it is not set.
+func num(obj object, key string, def int) int {
+	v, ok := obj[key].(float64)
+	if !ok {
+		if i, ok := obj[key].(int); ok {
+			return i
+		}
+		return def
+	}
+	return int(v)
+}
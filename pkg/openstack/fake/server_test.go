@@ -0,0 +1,304 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake_test
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/roles"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/users"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/imageimport"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+
+	"github.com/kupenstack/kupenstack/pkg/openstack"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func newClient(t *testing.T) (*fake.Server, *openstack.Client) {
+	t.Helper()
+	s := fake.NewServer()
+	t.Cleanup(s.Close)
+
+	c, err := openstack.New(s.AdminAuthOptions())
+	if err != nil {
+		t.Fatalf("cannot authenticate: %s", err)
+	}
+	return s, c
+}
+
+func service(t *testing.T, c *openstack.Client, Type string) *gophercloud.ServiceClient {
+	t.Helper()
+	sc, err := c.GetClient(Type)
+	if err != nil {
+		t.Fatalf("no %s client: %s", Type, err)
+	}
+	return sc
+}
+
+func TestAuthenticateRejectsWrongPassword(t *testing.T) {
+	s := fake.NewServer()
+	defer s.Close()
+
+	opts := s.AdminAuthOptions()
+	opts.Password = "wrong"
+	if _, err := openstack.New(opts); err == nil {
+		t.Fatal("expected authentication to fail")
+	}
+}
+
+func TestProjectScopedClient(t *testing.T) {
+	_, c := newClient(t)
+	identity := service(t, c, "identity")
+
+	project, err := projects.Create(identity, projects.CreateOpts{Name: "demo"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// admin has no role on new project yet
+	if _, err := c.Project(project.ID); err == nil {
+		t.Fatal("expected scoping to project without role to fail")
+	}
+
+	userID, err := c.UserID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allPages, err := roles.List(identity, roles.ListOpts{Name: "member"}).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memberRoles, err := roles.ExtractRoles(allPages)
+	if err != nil || len(memberRoles) != 1 {
+		t.Fatalf("expected member role, got %v, %v", memberRoles, err)
+	}
+	err = roles.Assign(identity, memberRoles[0].ID, roles.AssignOpts{UserID: userID, ProjectID: project.ID}).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped, err := c.Project(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allPages, err = projects.ListAvailable(service(t, scoped, "identity")).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	available, _ := projects.ExtractProjects(allPages)
+	if len(available) != 2 {
+		t.Errorf("expected admin and demo projects to be available, got %d", len(available))
+	}
+
+	allPages, err = roles.ListAssignments(identity, roles.ListAssignmentsOpts{ScopeProjectID: project.ID}).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignments, _ := roles.ExtractRoleAssignments(allPages)
+	if len(assignments) != 1 || assignments[0].User.ID != userID {
+		t.Errorf("unexpected assignments %v", assignments)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	s, c := newClient(t)
+	identity := service(t, c, "identity")
+
+	user, err := users.Create(identity, users.CreateOpts{Name: "alice", DomainID: fake.DomainID, Password: "secret"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(identity, users.CreateOpts{Name: "alice"}).Err; err == nil {
+		t.Error("expected duplicate user to conflict")
+	}
+
+	allPages, err := users.List(identity, users.ListOpts{Name: "alice", DomainID: fake.DomainID}).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, _ := users.ExtractUsers(allPages)
+	if len(found) != 1 || found[0].ID != user.ID {
+		t.Errorf("expected to find alice, got %v", found)
+	}
+
+	opts := s.AdminAuthOptions()
+	opts.Username, opts.Password, opts.TenantName = "alice", "secret", ""
+	if _, err := openstack.New(opts); err != nil {
+		t.Errorf("created user cannot authenticate: %s", err)
+	}
+
+	if err := users.Delete(identity, user.ID).ExtractErr(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openstack.New(opts); err == nil {
+		t.Error("deleted user can authenticate")
+	}
+}
+
+func TestServerLifecycle(t *testing.T) {
+	_, c := newClient(t)
+	compute := service(t, c, "compute")
+	network := service(t, c, "network")
+	image := service(t, c, "image")
+
+	img, err := images.Create(image, images.CreateOpts{Name: "cirros", ContainerFormat: "bare", DiskFormat: "qcow2"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Status != images.ImageStatusQueued {
+		t.Errorf("expected queued image, got %s", img.Status)
+	}
+	err = imageimport.Create(image, img.ID, imageimport.CreateOpts{Name: imageimport.WebDownloadMethod, URI: "http://example.com/cirros.img"}).ExtractErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = images.Get(image, img.ID).Extract()
+	if err != nil || img.Status != images.ImageStatusActive {
+		t.Fatalf("expected active image, got %v, %v", img, err)
+	}
+
+	disk := 1
+	flavor, err := flavors.Create(compute, flavors.CreateOpts{Name: "small", RAM: 512, VCPUs: 1, Disk: &disk}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := networks.Create(network, networks.CreateOpts{Name: "private"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = subnets.Create(network, subnets.CreateOpts{NetworkID: net.ID, CIDR: "10.0.0.0/24", IPVersion: gophercloud.IPv4}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err = networks.Get(network, net.ID).Extract()
+	if err != nil || len(net.Subnets) != 1 {
+		t.Fatalf("expected network with one subnet, got %v, %v", net, err)
+	}
+	subnet, err := subnets.Get(network, net.Subnets[0]).Extract()
+	if err != nil || subnet.GatewayIP != "10.0.0.1" {
+		t.Fatalf("expected default gateway, got %v, %v", subnet, err)
+	}
+
+	kp, err := keypairs.Create(compute, keypairs.CreateOpts{Name: "key"}).Extract()
+	if err != nil || kp.PrivateKey == "" || kp.UserID == "" {
+		t.Fatalf("expected generated keypair, got %v, %v", kp, err)
+	}
+
+	created, err := servers.Create(compute, keypairs.CreateOptsExt{
+		CreateOptsBuilder: servers.CreateOpts{
+			Name:      "vm",
+			ImageRef:  img.ID,
+			FlavorRef: flavor.ID,
+			Networks:  []servers.Network{{UUID: net.ID}},
+		},
+		KeyName: "key",
+	}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := servers.Get(compute, created.ID).Extract()
+	if err != nil || server.Status != "ACTIVE" {
+		t.Fatalf("expected active server, got %v, %v", server, err)
+	}
+	allPages, err := servers.ListAddresses(compute, created.ID).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, _ := servers.ExtractAddresses(allPages)
+	if got := addresses["private"]; len(got) != 1 || got[0].Address != "10.0.0.2" {
+		t.Errorf("unexpected addresses %v", addresses)
+	}
+
+	if err := networks.Delete(network, net.ID).ExtractErr(); err == nil {
+		t.Error("expected deleting network in use to fail")
+	}
+
+	if err := startstop.Stop(compute, created.ID).ExtractErr(); err != nil {
+		t.Fatal(err)
+	}
+	server, _ = servers.Get(compute, created.ID).Extract()
+	if server.Status != "SHUTOFF" {
+		t.Errorf("expected stopped server, got %s", server.Status)
+	}
+
+	if err := servers.Delete(compute, created.ID).ExtractErr(); err != nil {
+		t.Fatal(err)
+	}
+	err = servers.Get(compute, created.ID).Err
+	if _, ok := err.(gophercloud.ErrDefault404); !ok {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if err := networks.Delete(network, net.ID).ExtractErr(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestComputeQuota(t *testing.T) {
+	_, c := newClient(t)
+	compute := service(t, c, "compute")
+	image := service(t, c, "image")
+
+	img, err := images.Create(image, images.CreateOpts{Name: "cirros"}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk := 1
+	flavor, err := flavors.Create(compute, flavors.CreateOpts{Name: "small", RAM: 512, VCPUs: 2, Disk: &disk}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allPages, err := projects.ListAvailable(service(t, c, "identity")).AllPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	available, _ := projects.ExtractProjects(allPages)
+	projectID := available[0].ID
+
+	one := 1
+	_, err = quotasets.Update(compute, projectID, quotasets.UpdateOpts{Instances: &one}).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := servers.CreateOpts{Name: "vm", ImageRef: img.ID, FlavorRef: flavor.ID}
+	if err := servers.Create(compute, opts).Err; err != nil {
+		t.Fatal(err)
+	}
+	if err := servers.Create(compute, opts).Err; err == nil {
+		t.Error("expected instances quota to be enforced")
+	}
+
+	detail, err := quotasets.GetDetail(compute, projectID).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Instances.Limit != 1 || detail.Instances.InUse != 1 || detail.Cores.InUse != 2 {
+		t.Errorf("unexpected quota usage %+v", detail)
+	}
+}
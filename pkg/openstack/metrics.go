@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kupenstack/kupenstack/pkg/metrics"
+)
+
+// metricsTransport records latency and response codes of OpenStack API
+// requests.
+type metricsTransport struct {
+	next http.RoundTripper
+
+	mu sync.RWMutex
+
+	// Service type in catalog of each endpoint, keyed by endpoint url.
+	endpoints map[string]string
+}
+
+func newMetricsTransport(next http.RoundTripper) *metricsTransport {
+	return &metricsTransport{
+		next:      next,
+		endpoints: make(map[string]string),
+	}
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	service := t.serviceOf(req)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	metrics.OpenstackRequestDuration.WithLabelValues(service, req.Method).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.OpenstackRequests.WithLabelValues(service, req.Method, code).Inc()
+
+	return resp, err
+}
+
+// addEndpoint records that requests to urls under `endpoint` are sent to
+// `service`.
+func (t *metricsTransport) addEndpoint(endpoint, service string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endpoints[endpoint] = service
+}
+
+// serviceOf returns service type of endpoint a request is sent to, as in
+// catalog of cloud, e.g. `compute`. Endpoints are recorded when clients of
+// their service are created, and identity endpoint when clients
+// authenticate. Requests to other urls are of service `unknown`.
+func (t *metricsTransport) serviceOf(req *http.Request) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	url := req.URL.String()
+	service, longest := "unknown", 0
+	for endpoint, s := range t.endpoints {
+		if len(endpoint) > longest && strings.HasPrefix(url, endpoint) {
+			service, longest = s, len(endpoint)
+		}
+	}
+	return service
+}
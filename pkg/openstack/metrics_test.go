@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/kupenstack/kupenstack/pkg/metrics"
+	"github.com/kupenstack/kupenstack/pkg/openstack/fake"
+)
+
+func TestServiceOf(t *testing.T) {
+
+	transport := newMetricsTransport(http.DefaultTransport)
+	transport.addEndpoint("http://10.0.0.5:5000/", "identity")
+	transport.addEndpoint("http://10.0.0.5:8774/v2.1/", "compute")
+	transport.addEndpoint("http://openstack.example.com/", "identity")
+	transport.addEndpoint("http://openstack.example.com/image/", "image")
+
+	tests := []struct {
+		url     string
+		service string
+	}{
+		{url: "http://10.0.0.5:5000/v3/auth/tokens", service: "identity"},
+		{url: "http://10.0.0.5:8774/v2.1/servers/detail", service: "compute"},
+		{url: "http://openstack.example.com/v3/projects", service: "identity"},
+		{url: "http://openstack.example.com/image/v2/images", service: "image"},
+		{url: "http://10.0.0.5:9696/v2.0/networks", service: "unknown"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service := transport.serviceOf(req); service != test.service {
+			t.Errorf("%s: expected service %s, got %s", test.url, test.service, service)
+		}
+	}
+}
+
+// Requests of clients are counted by service type of their endpoint.
+func TestRequestMetrics(t *testing.T) {
+
+	server := fake.NewServer()
+	defer server.Close()
+
+	authentications := testutil.ToFloat64(metrics.OpenstackRequests.WithLabelValues("identity", http.MethodPost, "201"))
+	listings := testutil.ToFloat64(metrics.OpenstackRequests.WithLabelValues("compute", http.MethodGet, "200"))
+
+	client, err := New(server.AdminAuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	compute, err := client.GetClient("compute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flavors.ListDetail(compute, nil).AllPages(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(metrics.OpenstackRequests.WithLabelValues("identity", http.MethodPost, "201")); got != authentications+1 {
+		t.Errorf("expected authentication counted for identity, got %v more", got-authentications)
+	}
+	if got := testutil.ToFloat64(metrics.OpenstackRequests.WithLabelValues("compute", http.MethodGet, "200")); got != listings+1 {
+		t.Errorf("expected listing flavors counted for compute, got %v more", got-listings)
+	}
+}
@@ -20,7 +20,9 @@ type json = map[string]interface{}
 
 // Default json package does not support merging two json data
 // This function assumes json data is stored as map[string]interface{} and
-// patches `changes` to `original`
+// patches `changes` to `original`. Lists are replaced wholesale and a map in
+// `original` overridden by a non-map value is replaced. See MergeJson for
+// merging profiles.
 func PatchJson(original, changes json) json {
 
 	if original == nil {
@@ -32,12 +34,12 @@ func PatchJson(original, changes json) json {
 		if defaultVal, ok := original[key]; ok {
 			// key already exists.
 
-			switch defaultVal.(type) {
-			case map[string]interface{}:
+			defaultMap, isMap := defaultVal.(json)
+			valMap, valIsMap := val.(json)
+			if isMap && valIsMap {
 				// When it has more nested values then we don't overwrite
-				original[key] = PatchJson(defaultVal.(json), val.(json))
-				break
-			default:
+				original[key] = PatchJson(defaultMap, valMap)
+			} else {
 				original[key] = val
 			}
 
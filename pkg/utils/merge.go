@@ -0,0 +1,274 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const (
+	// PatchDirective is the key holding a merge directive in a map, or in a
+	// list element of its own.
+	PatchDirective = "$patch"
+
+	// PatchDelete removes the map holding it from the original. In a list
+	// element it removes the element with same merge key.
+	PatchDelete = "delete"
+
+	// PatchReplace replaces the original map, or the whole original list,
+	// instead of merging into it.
+	PatchReplace = "replace"
+
+	// MergeKey identifies elements of a list of maps. Two such lists are
+	// merged element by element when every element has this key.
+	MergeKey = "name"
+)
+
+// MergeError is returned when changes cannot be merged into original.
+type MergeError struct {
+	// Path of the conflicting value, e.g. `conf.nova.DEFAULT`.
+	Path string
+	Msg  string
+}
+
+func (e *MergeError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// MergeJson merges `changes` over `original` with strategic merge semantics
+// and returns the result. Neither argument is modified.
+//
+//  - Maps are merged recursively. A map value `{$patch: delete}` removes
+//    the key, and a map holding `$patch: replace` replaces the original map.
+//  - Lists of maps that all have MergeKey are merged by that key, appending
+//    new elements. An element `{name: x, $patch: delete}` removes element x
+//    and an element `{$patch: replace}` replaces the original list.
+//  - Other lists and scalars in `changes` replace the original value.
+//
+// Overriding a map or a list with a value of different type is an error.
+func MergeJson(original, changes json) (json, error) {
+	return mergeMap("", original, changes)
+}
+
+func mergeMap(path string, original, changes json) (json, error) {
+
+	result := make(json, len(original)+len(changes))
+	for key, val := range original {
+		result[key] = val
+	}
+
+	if directive, ok := changes[PatchDirective]; ok {
+		switch directive {
+		case PatchReplace:
+			return clean(changes).(json), nil
+		case PatchDelete:
+			return nil, &MergeError{Path: path, Msg: "delete directive is only allowed in a map value or a list element"}
+		default:
+			return nil, &MergeError{Path: path, Msg: fmt.Sprintf("unknown %s directive %v", PatchDirective, directive)}
+		}
+	}
+
+	for key, val := range changes {
+		keyPath := joinPath(path, key)
+
+		if m, ok := val.(json); ok && isDelete(m) {
+			delete(result, key)
+			continue
+		}
+
+		defaultVal, ok := result[key]
+		if !ok || defaultVal == nil {
+			// key was not in `original` so we simply store it, dropping
+			// directives meaningless without anything to merge into.
+			result[key] = clean(val)
+			continue
+		}
+
+		merged, err := mergeValue(keyPath, defaultVal, val)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = merged
+	}
+	return result, nil
+}
+
+func mergeValue(path string, original, changes interface{}) (interface{}, error) {
+
+	switch orig := original.(type) {
+	case json:
+		ch, ok := changes.(json)
+		if !ok {
+			return nil, typeMismatch(path, original, changes)
+		}
+		return mergeMap(path, orig, ch)
+
+	case []interface{}:
+		ch, ok := changes.([]interface{})
+		if !ok {
+			return nil, typeMismatch(path, original, changes)
+		}
+		return mergeList(path, orig, ch)
+
+	default:
+		switch changes.(type) {
+		case json, []interface{}:
+			return nil, typeMismatch(path, original, changes)
+		}
+		return changes, nil
+	}
+}
+
+func mergeList(path string, original, changes []interface{}) ([]interface{}, error) {
+
+	for _, elem := range changes {
+		if m, ok := elem.(json); ok && len(m) == 1 && m[PatchDirective] == PatchReplace {
+			return cleanList(changes), nil
+		}
+	}
+
+	if !keyedList(original) || !keyedList(changes) {
+		return cleanList(changes), nil
+	}
+
+	result := make([]interface{}, 0, len(original)+len(changes))
+	result = append(result, original...)
+
+	for _, elem := range changes {
+		ch := elem.(json)
+		elemPath := fmt.Sprintf("%s[%s=%v]", path, MergeKey, ch[MergeKey])
+
+		index := -1
+		for j, o := range result {
+			if reflect.DeepEqual(o.(json)[MergeKey], ch[MergeKey]) {
+				index = j
+				break
+			}
+		}
+
+		if isDelete(ch) {
+			if index >= 0 {
+				result = append(result[:index], result[index+1:]...)
+			}
+			continue
+		}
+
+		if index < 0 {
+			result = append(result, clean(ch))
+			continue
+		}
+
+		merged, err := mergeMap(elemPath, result[index].(json), ch)
+		if err != nil {
+			return nil, err
+		}
+		result[index] = merged
+	}
+	return result, nil
+}
+
+// keyedList tells if every element of list is a map having MergeKey.
+// Directive-only elements are not considered.
+func keyedList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, elem := range list {
+		m, ok := elem.(json)
+		if !ok {
+			return false
+		}
+		if _, ok := m[MergeKey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isDelete(m json) bool {
+	return m[PatchDirective] == PatchDelete
+}
+
+// clean removes directives from a value not merged into anything.
+func clean(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json:
+		result := withoutDirective(v)
+		for key, elem := range result {
+			result[key] = clean(elem)
+		}
+		return result
+	case []interface{}:
+		return cleanList(v)
+	default:
+		return val
+	}
+}
+
+func cleanList(list []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(list))
+	for _, elem := range list {
+		if m, ok := elem.(json); ok && m[PatchDirective] != nil {
+			if isDelete(m) || len(m) == 1 {
+				continue
+			}
+		}
+		result = append(result, clean(elem))
+	}
+	return result
+}
+
+func withoutDirective(m json) json {
+	result := make(json, len(m))
+	for key, val := range m {
+		if key != PatchDirective {
+			result[key] = val
+		}
+	}
+	return result
+}
+
+func typeMismatch(path string, original, changes interface{}) error {
+	return &MergeError{
+		Path: path,
+		Msg:  fmt.Sprintf("cannot override %s with %s", kind(original), kind(changes)),
+	}
+}
+
+func kind(val interface{}) string {
+	switch val.(type) {
+	case json:
+		return "map"
+	case []interface{}:
+		return "list"
+	case nil:
+		return "null"
+	default:
+		return reflect.TypeOf(val).Kind().String()
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2021 The Kupenstack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func parse(t *testing.T, data string) json {
+	t.Helper()
+	var out json
+	if err := yaml.Unmarshal([]byte(data), &out); err != nil {
+		t.Fatalf("invalid test data: %s", err)
+	}
+	return out
+}
+
+func TestMergeJson(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		parent  string
+		child   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "child overrides scalars and adds keys",
+			parent: `
+keystone:
+  replicas: {api: 1}
+  conf: {keystone: {DEFAULT: {debug: false, use_syslog: true}}}`,
+			child: `
+keystone:
+  replicas: {api: 3}
+  conf: {keystone: {DEFAULT: {debug: true}}}
+glance: {disable: true}`,
+			want: `
+keystone:
+  replicas: {api: 3}
+  conf: {keystone: {DEFAULT: {debug: true, use_syslog: true}}}
+glance: {disable: true}`,
+		},
+		{
+			name:   "delete directive removes inherited key",
+			parent: `conf: {nova: {libvirt: {virt_type: kvm}, DEFAULT: {debug: true}}}`,
+			child:  `conf: {nova: {libvirt: {$patch: delete}}}`,
+			want:   `conf: {nova: {DEFAULT: {debug: true}}}`,
+		},
+		{
+			name:   "delete directive of missing key is a no-op",
+			parent: `conf: {a: 1}`,
+			child:  `conf: {b: {$patch: delete}}`,
+			want:   `conf: {a: 1}`,
+		},
+		{
+			name:   "replace directive replaces inherited map",
+			parent: `conf: {neutron: {DEFAULT: {debug: true, l3_ha: true}}}`,
+			child:  `conf: {neutron: {$patch: replace, DEFAULT: {l3_ha: false}}}`,
+			want:   `conf: {neutron: {DEFAULT: {l3_ha: false}}}`,
+		},
+		{
+			name:   "scalar lists are replaced",
+			parent: `hosts: [a, b]`,
+			child:  `hosts: [c]`,
+			want:   `hosts: [c]`,
+		},
+		{
+			name: "keyed lists are merged by name",
+			parent: `
+pools:
+- {name: fast, size: 3, ssd: true}
+- {name: slow, size: 10}`,
+			child: `
+pools:
+- {name: slow, size: 20}
+- {name: archive, size: 100}`,
+			want: `
+pools:
+- {name: fast, size: 3, ssd: true}
+- {name: slow, size: 20}
+- {name: archive, size: 100}`,
+		},
+		{
+			name: "delete directive removes list element",
+			parent: `
+pools:
+- {name: fast, size: 3}
+- {name: slow, size: 10}`,
+			child: `
+pools:
+- {name: fast, $patch: delete}`,
+			want: `
+pools:
+- {name: slow, size: 10}`,
+		},
+		{
+			name: "replace directive replaces keyed list",
+			parent: `
+pools:
+- {name: fast, size: 3}
+- {name: slow, size: 10}`,
+			child: `
+pools:
+- $patch: replace
+- {name: only, size: 1}`,
+			want: `
+pools:
+- {name: only, size: 1}`,
+		},
+		{
+			name:   "directives are dropped from new keys",
+			parent: `conf: {}`,
+			child:  `conf: {nova: {$patch: replace, DEFAULT: {debug: true}}, pools: [{name: a, $patch: delete}]}`,
+			want:   `conf: {nova: {DEFAULT: {debug: true}}, pools: []}`,
+		},
+		{
+			name: "delete directive in nested maps",
+			parent: `
+conf: {horizon: {local_settings: {config: {debug: "False", secure_cookies: "True"}}}}
+replicas: {server: 1}`,
+			child: `
+conf: {horizon: {local_settings: {config: {secure_cookies: {$patch: delete}}}}}
+replicas: {server: 2}`,
+			want: `
+conf: {horizon: {local_settings: {config: {debug: "False"}}}}
+replicas: {server: 2}`,
+		},
+		{
+			name:    "map overridden by scalar",
+			parent:  `conf: {nova: {DEFAULT: {debug: true}}}`,
+			child:   `conf: {nova: disabled}`,
+			wantErr: "conf.nova: cannot override map with string",
+		},
+		{
+			name:    "scalar overridden by map",
+			parent:  `replicas: {api: 1}`,
+			child:   `replicas: {api: {count: 2}}`,
+			wantErr: "replicas.api: cannot override float64 with map",
+		},
+		{
+			name:    "list overridden by map",
+			parent:  `hosts: [a]`,
+			child:   `hosts: {a: true}`,
+			wantErr: "hosts: cannot override list with map",
+		},
+		{
+			name:    "mismatch inside keyed list element",
+			parent:  `pools: [{name: fast, opts: {ssd: true}}]`,
+			child:   `pools: [{name: fast, opts: [ssd]}]`,
+			wantErr: "pools[name=fast].opts: cannot override map with list",
+		},
+		{
+			name:    "unknown directive",
+			parent:  `conf: {a: {b: 1}}`,
+			child:   `conf: {a: {$patch: merge-all}}`,
+			wantErr: "conf.a: unknown $patch directive merge-all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := parse(t, tt.parent)
+			child := parse(t, tt.child)
+
+			got, err := MergeJson(parent, child)
+			if tt.wantErr != "" {
+				var mergeErr *MergeError
+				if !errors.As(err, &mergeErr) {
+					t.Fatalf("expected MergeError %q, got %v", tt.wantErr, err)
+				}
+				if err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %q", tt.wantErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			want := parse(t, tt.want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("merge result mismatch\n got: %v\nwant: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeJsonChainedInheritance(t *testing.T) {
+
+	base := parse(t, `
+conf:
+  nova:
+    DEFAULT: {debug: false, cpu_allocation_ratio: 16}
+    libvirt: {virt_type: kvm}
+pools:
+- {name: default, size: 3}`)
+	prod := parse(t, `
+conf:
+  nova:
+    DEFAULT: {cpu_allocation_ratio: 4}
+pools:
+- {name: ssd, size: 3}`)
+	dev := parse(t, `
+conf:
+  nova:
+    DEFAULT: {debug: true}
+    libvirt: {virt_type: qemu}
+pools:
+- {name: default, $patch: delete}`)
+
+	// Profiles are resolved from the root of chain, like Resolver does.
+	merged, err := MergeJson(base, prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err = MergeJson(merged, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := parse(t, `
+conf:
+  nova:
+    DEFAULT: {debug: true, cpu_allocation_ratio: 4}
+    libvirt: {virt_type: qemu}
+pools:
+- {name: ssd, size: 3}`)
+	if !reflect.DeepEqual(merged, want) {
+		t.Fatalf("merge result mismatch\n got: %v\nwant: %v", merged, want)
+	}
+}
+
+func TestMergeJsonDoesNotModifyArguments(t *testing.T) {
+
+	parent := parse(t, `{conf: {a: {b: 1}}, pools: [{name: x, size: 1}]}`)
+	child := parse(t, `{conf: {a: {b: 2}, c: {$patch: delete}}, pools: [{name: x, size: 2}]}`)
+	parentCopy := parse(t, `{conf: {a: {b: 1}}, pools: [{name: x, size: 1}]}`)
+	childCopy := parse(t, `{conf: {a: {b: 2}, c: {$patch: delete}}, pools: [{name: x, size: 2}]}`)
+
+	_, err := MergeJson(parent, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parent, parentCopy) {
+		t.Fatalf("original was modified: %v", parent)
+	}
+	if !reflect.DeepEqual(child, childCopy) {
+		t.Fatalf("changes were modified: %v", child)
+	}
+}
+
+func TestPatchJsonTypeMismatch(t *testing.T) {
+
+	original := parse(t, `conf: {nova: {DEFAULT: {debug: true}}}`)
+	changes := parse(t, `conf: {nova: disabled}`)
+
+	got := PatchJson(original, changes)
+	want := parse(t, `conf: {nova: disabled}`)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("patch result mismatch\n got: %v\nwant: %v", got, want)
+	}
+}